@@ -0,0 +1,864 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AlertRule fires when spend in a category or bucket crosses a threshold:
+//
+//	kind "percent" — spent ≥ Threshold% of the cycle budget
+//	kind "pace"    — spent share of budget runs more than Threshold% ahead of
+//	                 the share of the cycle elapsed (only while the cycle runs)
+//
+// Scope "category" targets a category by name; scope "bucket" targets one of
+// the dashboard buckets: "fixed", "wants" or "goals". Each rule fires at most
+// once per cycle.
+type AlertRule struct {
+	ID        int64   `json:"id"`
+	Scope     string  `json:"scope"`
+	Target    string  `json:"target"`
+	Kind      string  `json:"kind"`
+	Threshold float64 `json:"threshold"`
+	Enabled   bool    `json:"enabled"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// Webhook is an outbound alert destination. Format "ntfy" posts the message
+// as plain text with ntfy Title/Tags headers; "json" posts an AlertPayload.
+type Webhook struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Format    string `json:"format"`
+	Enabled   bool   `json:"enabled"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// AlertEvent is one threshold crossing, recorded once per rule per cycle.
+type AlertEvent struct {
+	ID         int64           `json:"id"`
+	RuleID     int64           `json:"ruleId"`
+	Cycle      string          `json:"cycle"`
+	Message    string          `json:"message"`
+	Spent      float64         `json:"spent"`
+	Budget     float64         `json:"budget"`
+	FiredAt    string          `json:"firedAt"`
+	Deliveries []AlertDelivery `json:"deliveries"`
+}
+
+// AlertDelivery is one attempt to post an event (or a test) to a webhook.
+// EventID is nil for test fires.
+type AlertDelivery struct {
+	ID          int64  `json:"id"`
+	EventID     *int64 `json:"eventId,omitempty"`
+	WebhookID   int64  `json:"webhookId"`
+	StatusCode  int    `json:"statusCode"`
+	Error       string `json:"error,omitempty"`
+	DeliveredAt string `json:"deliveredAt"`
+}
+
+// AlertPayload is the body of a "json" webhook.
+type AlertPayload struct {
+	Event     string  `json:"event"`
+	Cycle     string  `json:"cycle"`
+	Scope     string  `json:"scope"`
+	Target    string  `json:"target"`
+	Kind      string  `json:"kind"`
+	Threshold float64 `json:"threshold"`
+	Spent     float64 `json:"spent"`
+	Budget    float64 `json:"budget"`
+	Message   string  `json:"message"`
+}
+
+var webhookClient = &http.Client{Timeout: 5 * time.Second}
+
+func (c *DatabaseClient) migrateAlerts() error {
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS alert_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			scope TEXT NOT NULL,
+			target TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			threshold REAL NOT NULL,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL,
+			format TEXT NOT NULL DEFAULT 'json',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS alert_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			rule_id INTEGER NOT NULL,
+			cycle TEXT NOT NULL,
+			message TEXT NOT NULL,
+			spent REAL NOT NULL,
+			budget REAL NOT NULL,
+			fired_at TEXT NOT NULL,
+			UNIQUE(rule_id, cycle)
+		)`,
+		`CREATE TABLE IF NOT EXISTS alert_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id INTEGER,
+			webhook_id INTEGER NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			delivered_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_alert_events_cycle ON alert_events(cycle)`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("alerts migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateAlertRule(r AlertRule) error {
+	switch r.Scope {
+	case "category":
+		if r.Target == "" {
+			return fmt.Errorf("invalid alert target")
+		}
+	case "bucket":
+		if r.Target != "fixed" && r.Target != "wants" && r.Target != "goals" {
+			return fmt.Errorf("invalid alert target")
+		}
+	default:
+		return fmt.Errorf("invalid alert scope")
+	}
+	if r.Kind != "percent" && r.Kind != "pace" {
+		return fmt.Errorf("invalid alert kind")
+	}
+	if r.Threshold <= 0 {
+		return fmt.Errorf("invalid alert threshold")
+	}
+	return nil
+}
+
+const alertRuleColumns = "id, scope, target, kind, threshold, enabled, created_at"
+
+func scanAlertRules(rows *sql.Rows) ([]AlertRule, error) {
+	defer rows.Close()
+	rules := []AlertRule{}
+	for rows.Next() {
+		var r AlertRule
+		var enabled int
+		if err := rows.Scan(&r.ID, &r.Scope, &r.Target, &r.Kind, &r.Threshold, &enabled, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		r.Enabled = enabled == 1
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (c *DatabaseClient) GetAlertRules() ([]AlertRule, error) {
+	rows, err := c.db.Query("SELECT " + alertRuleColumns + " FROM alert_rules ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	return scanAlertRules(rows)
+}
+
+func (c *DatabaseClient) CreateAlertRule(r AlertRule) (*AlertRule, error) {
+	if err := validateAlertRule(r); err != nil {
+		return nil, err
+	}
+	r.Enabled = true
+	r.CreatedAt = time.Now().Format(time.RFC3339)
+	result, err := c.db.Exec(
+		"INSERT INTO alert_rules (scope, target, kind, threshold, enabled, created_at) VALUES (?, ?, ?, ?, 1, ?)",
+		r.Scope, r.Target, r.Kind, r.Threshold, r.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	r.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &r, nil
+}
+
+func (c *DatabaseClient) UpdateAlertRule(r AlertRule) error {
+	if err := validateAlertRule(r); err != nil {
+		return err
+	}
+	enabled := 0
+	if r.Enabled {
+		enabled = 1
+	}
+	result, err := c.db.Exec(
+		"UPDATE alert_rules SET scope = ?, target = ?, kind = ?, threshold = ?, enabled = ? WHERE id = ?",
+		r.Scope, r.Target, r.Kind, r.Threshold, enabled, r.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("alert rule not found")
+	}
+	return nil
+}
+
+func (c *DatabaseClient) DeleteAlertRule(id int64) error {
+	result, err := c.db.Exec("DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("alert rule not found")
+	}
+	return nil
+}
+
+func (c *DatabaseClient) GetWebhooks() ([]Webhook, error) {
+	rows, err := c.db.Query("SELECT id, name, url, format, enabled, created_at FROM webhooks ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		var h Webhook
+		var enabled int
+		if err := rows.Scan(&h.ID, &h.Name, &h.URL, &h.Format, &enabled, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		h.Enabled = enabled == 1
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+func (c *DatabaseClient) getWebhook(id int64) (*Webhook, error) {
+	hooks, err := c.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hooks {
+		if h.ID == id {
+			return &h, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook not found")
+}
+
+func (c *DatabaseClient) CreateWebhook(name, url, format string) (*Webhook, error) {
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "ntfy" {
+		return nil, fmt.Errorf("invalid webhook format")
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("invalid webhook url")
+	}
+	now := time.Now().Format(time.RFC3339)
+	result, err := c.db.Exec(
+		"INSERT INTO webhooks (name, url, format, enabled, created_at) VALUES (?, ?, ?, 1, ?)",
+		name, url, format, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &Webhook{ID: id, Name: name, URL: url, Format: format, Enabled: true, CreatedAt: now}, nil
+}
+
+func (c *DatabaseClient) DeleteWebhook(id int64) error {
+	result, err := c.db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+	return nil
+}
+
+// alertSpend returns what a rule measures in a cycle's stats: spent and the
+// budget it is compared against (budget plus carried balance for envelope
+// categories). ok is false when the target has no positive budget.
+func alertSpend(rule AlertRule, stats *StatsResponse) (spent, budget float64, ok bool) {
+	if rule.Scope == "bucket" {
+		switch rule.Target {
+		case "fixed":
+			spent, budget = stats.FixedTotal, stats.FixedBudget
+		case "wants":
+			spent, budget = stats.WantsTotal, stats.WantsBudget
+		case "goals":
+			spent, budget = stats.GoalsFunded, stats.GoalsBudget
+		}
+		return spent, budget, budget > 0
+	}
+	for _, def := range stats.CategoryDefinitions {
+		if def.Name == rule.Target && def.BudgetAmount != nil {
+			budget = *def.BudgetAmount
+		}
+	}
+	for _, cat := range flatCategoryStats(stats.Categories) {
+		if cat.Category == rule.Target {
+			spent = cat.Total
+			budget += cat.Carried
+		}
+	}
+	return spent, budget, budget > 0
+}
+
+// alertMessage renders the human-readable text sent to webhooks.
+func alertMessage(rule AlertRule, cycle string, spent, budget float64) string {
+	name := rule.Target
+	if rule.Scope == "bucket" {
+		name = strings.ToUpper(name[:1]) + name[1:] + " bucket"
+	}
+	if rule.Kind == "pace" {
+		return fmt.Sprintf("%s is spending more than %.0f%% ahead of pace in %s: %.2f of %.2f AED used",
+			name, rule.Threshold, cycleDisplayLabel(cycle), spent, budget)
+	}
+	return fmt.Sprintf("%s reached %.0f%% of budget in %s: %.2f of %.2f AED",
+		name, rule.Threshold, cycleDisplayLabel(cycle), spent, budget)
+}
+
+// CheckAlerts evaluates every enabled rule against a cycle and delivers the
+// ones crossing their threshold for the first time this cycle. It returns the
+// newly fired events.
+func (c *DatabaseClient) CheckAlerts(cycle string) ([]AlertEvent, error) {
+	rows, err := c.db.Query("SELECT "+alertRuleColumns+" FROM alert_rules WHERE enabled = 1 AND id NOT IN (SELECT rule_id FROM alert_events WHERE cycle = ?)", cycle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	rules, err := scanAlertRules(rows)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+
+	stats, err := c.GetStats(cycle)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := cycleElapsed(cycle, time.Now())
+
+	var fired []AlertEvent
+	for _, rule := range rules {
+		spent, budget, ok := alertSpend(rule, stats)
+		if !ok {
+			continue
+		}
+		used := spent / budget
+		switch rule.Kind {
+		case "percent":
+			if used*100 < rule.Threshold {
+				continue
+			}
+		case "pace":
+			if elapsed <= 0 || elapsed >= 1 || used <= elapsed*(1+rule.Threshold/100) {
+				continue
+			}
+		}
+
+		event := AlertEvent{
+			RuleID:     rule.ID,
+			Cycle:      cycle,
+			Message:    alertMessage(rule, cycle, spent, budget),
+			Spent:      spent,
+			Budget:     budget,
+			FiredAt:    time.Now().Format(time.RFC3339),
+			Deliveries: []AlertDelivery{},
+		}
+		result, err := c.db.Exec(
+			"INSERT OR IGNORE INTO alert_events (rule_id, cycle, message, spent, budget, fired_at) VALUES (?, ?, ?, ?, ?, ?)",
+			event.RuleID, event.Cycle, event.Message, event.Spent, event.Budget, event.FiredAt,
+		)
+		if err != nil {
+			return fired, fmt.Errorf("failed to record alert: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		event.ID, _ = result.LastInsertId()
+		log.Printf("[Alerts] %s", event.Message)
+
+		payload := AlertPayload{
+			Event:     "alert",
+			Cycle:     cycle,
+			Scope:     rule.Scope,
+			Target:    rule.Target,
+			Kind:      rule.Kind,
+			Threshold: rule.Threshold,
+			Spent:     spent,
+			Budget:    budget,
+			Message:   event.Message,
+		}
+		event.Deliveries, err = c.deliverAlert(&event.ID, payload)
+		if err != nil {
+			return fired, err
+		}
+		fired = append(fired, event)
+	}
+	return fired, nil
+}
+
+// alertQueueSize bounds the checks waiting for the alert worker. A check
+// dropped when it is full is made again by the cycle's next write.
+const alertQueueSize = 64
+
+// startAlertWorker starts the goroutine that checks alerts and delivers
+// webhooks after transaction writes, so neither GetStats nor a slow webhook
+// holds up the request that saved.
+func (c *DatabaseClient) startAlertWorker() {
+	c.alertQueue = make(chan string, alertQueueSize)
+	c.alertDone = make(chan struct{})
+	go func() {
+		defer close(c.alertDone)
+		for cycle := range c.alertQueue {
+			if _, err := c.CheckAlerts(cycle); err != nil {
+				log.Printf("[Alerts] Failed to check alerts for %s: %v", cycle, err)
+			}
+			c.alertPending.Done()
+		}
+	}()
+}
+
+// stopAlertWorker lets queued checks finish and stops the worker.
+func (c *DatabaseClient) stopAlertWorker() {
+	if c.alertQueue == nil {
+		return
+	}
+	close(c.alertQueue)
+	<-c.alertDone
+	c.alertQueue = nil
+}
+
+// waitForAlerts blocks until every queued alert check has finished.
+func (c *DatabaseClient) waitForAlerts() {
+	c.alertPending.Wait()
+}
+
+// checkAlertsAfterWrite queues an alert check for the cycles a transaction
+// write touched. Inline it only asks whether any enabled rule has yet to fire
+// in the cycle; the stats and deliveries run on the alert worker. Failures
+// are logged, never surfaced: alerts must not block saves.
+func (c *DatabaseClient) checkAlertsAfterWrite(cycles ...string) {
+	seen := make(map[string]bool)
+	for _, cycle := range cycles {
+		if cycle == "" || seen[cycle] {
+			continue
+		}
+		seen[cycle] = true
+
+		var armed bool
+		if err := c.db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM alert_rules r WHERE r.enabled = 1
+				AND NOT EXISTS (SELECT 1 FROM alert_events e WHERE e.rule_id = r.id AND e.cycle = ?))`, cycle,
+		).Scan(&armed); err != nil {
+			log.Printf("[Alerts] Failed to check alerts for %s: %v", cycle, err)
+			continue
+		}
+		if !armed {
+			continue
+		}
+
+		c.alertPending.Add(1)
+		select {
+		case c.alertQueue <- cycle:
+		default:
+			c.alertPending.Done()
+			log.Printf("[Alerts] Queue full, skipping alert check for %s", cycle)
+		}
+	}
+}
+
+// deliverAlert posts a payload to every enabled webhook and records each
+// attempt. eventID is nil for test fires.
+func (c *DatabaseClient) deliverAlert(eventID *int64, payload AlertPayload) ([]AlertDelivery, error) {
+	hooks, err := c.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []AlertDelivery{}
+	for _, h := range hooks {
+		if !h.Enabled {
+			continue
+		}
+		d, err := c.deliverWebhook(h, eventID, payload)
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+// deliverWebhook posts one payload and records the attempt. A failed POST is
+// stored on the delivery, not returned; only a failed insert is an error.
+func (c *DatabaseClient) deliverWebhook(h Webhook, eventID *int64, payload AlertPayload) (*AlertDelivery, error) {
+	d := AlertDelivery{EventID: eventID, WebhookID: h.ID}
+	status, err := postWebhook(h, payload)
+	d.StatusCode = status
+	if err != nil {
+		d.Error = err.Error()
+		log.Printf("[Alerts] Delivery to webhook %s failed: %v", h.Name, err)
+	}
+	d.DeliveredAt = time.Now().Format(time.RFC3339)
+
+	result, err := c.db.Exec(
+		"INSERT INTO alert_deliveries (event_id, webhook_id, status_code, error, delivered_at) VALUES (?, ?, ?, ?, ?)",
+		d.EventID, d.WebhookID, d.StatusCode, d.Error, d.DeliveredAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record delivery: %w", err)
+	}
+	d.ID, _ = result.LastInsertId()
+	return &d, nil
+}
+
+func postWebhook(h Webhook, payload AlertPayload) (int, error) {
+	var req *http.Request
+	var err error
+	if h.Format == "ntfy" {
+		req, err = http.NewRequest("POST", h.URL, strings.NewReader(payload.Message))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Title", "Budget alert")
+		req.Header.Set("Tags", "moneybag")
+	} else {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		req, err = http.NewRequest("POST", h.URL, bytes.NewBuffer(body))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// TestWebhook sends a test message to one webhook and records the delivery.
+func (c *DatabaseClient) TestWebhook(id int64) (*AlertDelivery, error) {
+	h, err := c.getWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.deliverWebhook(*h, nil, AlertPayload{
+		Event:   "test",
+		Message: "Test alert from Transaction Tracker",
+	})
+}
+
+// GetAlertHistory lists fired alerts newest first, optionally for one cycle,
+// each with its delivery attempts.
+func (c *DatabaseClient) GetAlertHistory(cycle string) ([]AlertEvent, error) {
+	query := "SELECT id, rule_id, cycle, message, spent, budget, fired_at FROM alert_events"
+	var args []interface{}
+	if cycle != "" {
+		query += " WHERE cycle = ?"
+		args = append(args, cycle)
+	}
+	rows, err := c.db.Query(query+" ORDER BY id DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert history: %w", err)
+	}
+	events := []AlertEvent{}
+	index := make(map[int64]int)
+	for rows.Next() {
+		var e AlertEvent
+		if err := rows.Scan(&e.ID, &e.RuleID, &e.Cycle, &e.Message, &e.Spent, &e.Budget, &e.FiredAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan alert event: %w", err)
+		}
+		e.Deliveries = []AlertDelivery{}
+		index[e.ID] = len(events)
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dRows, err := c.db.Query("SELECT id, event_id, webhook_id, status_code, error, delivered_at FROM alert_deliveries WHERE event_id IS NOT NULL ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert deliveries: %w", err)
+	}
+	defer dRows.Close()
+	for dRows.Next() {
+		var d AlertDelivery
+		var eventID int64
+		if err := dRows.Scan(&d.ID, &eventID, &d.WebhookID, &d.StatusCode, &d.Error, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert delivery: %w", err)
+		}
+		i, ok := index[eventID]
+		if !ok {
+			continue
+		}
+		d.EventID = &eventID
+		events[i].Deliveries = append(events[i].Deliveries, d)
+	}
+	return events, dRows.Err()
+}
+
+// alertsHandler serves alert configuration and history:
+//
+//	GET    /alerts/rules              — list rules
+//	POST   /alerts/rules              — create a rule
+//	PUT    /alerts/rules/:id          — update a rule
+//	DELETE /alerts/rules/:id          — delete a rule
+//	GET    /alerts/webhooks           — list webhooks
+//	POST   /alerts/webhooks           — add a webhook
+//	DELETE /alerts/webhooks/:id       — remove a webhook
+//	POST   /alerts/webhooks/:id/test  — send a test message
+//	GET    /alerts/history?cycle=     — fired alerts with delivery attempts
+func alertsHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, "/")
+
+		switch {
+		case path == "/alerts/rules":
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /alerts/rules - Request from %s", r.RemoteAddr)
+				rules, err := db.GetAlertRules()
+				if err != nil {
+					log.Printf("[API] Failed to get alert rules: %v", err)
+					http.Error(w, "Failed to retrieve alert rules", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"rules":   rules,
+				})
+
+			case http.MethodPost:
+				log.Printf("[API] POST /alerts/rules - Create rule from %s", r.RemoteAddr)
+				var req AlertRule
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				rule, err := db.CreateAlertRule(req)
+				if err != nil {
+					log.Printf("[API] Failed to create alert rule: %v", err)
+					if strings.HasPrefix(err.Error(), "invalid alert") {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					} else {
+						http.Error(w, "Failed to create alert rule", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"rule":    rule,
+				})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+
+		case strings.HasPrefix(path, "/alerts/rules/"):
+			var id int64
+			if _, err := fmt.Sscanf(path[len("/alerts/rules/"):], "%d", &id); err != nil {
+				http.Error(w, "Invalid alert rule ID", http.StatusBadRequest)
+				return
+			}
+			switch r.Method {
+			case http.MethodPut:
+				log.Printf("[API] PUT /alerts/rules/%d - Update rule from %s", id, r.RemoteAddr)
+				var req AlertRule
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				req.ID = id
+				if err := db.UpdateAlertRule(req); err != nil {
+					log.Printf("[API] Failed to update alert rule: %v", err)
+					if err.Error() == "alert rule not found" {
+						http.Error(w, "Alert rule not found", http.StatusNotFound)
+					} else if strings.HasPrefix(err.Error(), "invalid alert") {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					} else {
+						http.Error(w, "Failed to update alert rule", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+			case http.MethodDelete:
+				log.Printf("[API] DELETE /alerts/rules/%d - Delete rule from %s", id, r.RemoteAddr)
+				if err := db.DeleteAlertRule(id); err != nil {
+					log.Printf("[API] Failed to delete alert rule: %v", err)
+					if err.Error() == "alert rule not found" {
+						http.Error(w, "Alert rule not found", http.StatusNotFound)
+					} else {
+						http.Error(w, "Failed to delete alert rule", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+
+		case path == "/alerts/webhooks":
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /alerts/webhooks - Request from %s", r.RemoteAddr)
+				hooks, err := db.GetWebhooks()
+				if err != nil {
+					log.Printf("[API] Failed to get webhooks: %v", err)
+					http.Error(w, "Failed to retrieve webhooks", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success":  true,
+					"webhooks": hooks,
+				})
+
+			case http.MethodPost:
+				log.Printf("[API] POST /alerts/webhooks - Add webhook from %s", r.RemoteAddr)
+				var req struct {
+					Name   string `json:"name"`
+					URL    string `json:"url"`
+					Format string `json:"format"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				if req.Name == "" {
+					http.Error(w, "Name is required", http.StatusBadRequest)
+					return
+				}
+				hook, err := db.CreateWebhook(req.Name, req.URL, req.Format)
+				if err != nil {
+					log.Printf("[API] Failed to create webhook: %v", err)
+					if strings.HasPrefix(err.Error(), "invalid webhook") {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					} else {
+						http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"webhook": hook,
+				})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+
+		case strings.HasPrefix(path, "/alerts/webhooks/"):
+			rest := path[len("/alerts/webhooks/"):]
+			test := strings.HasSuffix(rest, "/test")
+			rest = strings.TrimSuffix(rest, "/test")
+			var id int64
+			if _, err := fmt.Sscanf(rest, "%d", &id); err != nil {
+				http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+				return
+			}
+
+			if test {
+				if r.Method != http.MethodPost {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+				log.Printf("[API] POST /alerts/webhooks/%d/test - Test fire from %s", id, r.RemoteAddr)
+				delivery, err := db.TestWebhook(id)
+				if err != nil {
+					log.Printf("[API] Failed to test webhook: %v", err)
+					if err.Error() == "webhook not found" {
+						http.Error(w, "Webhook not found", http.StatusNotFound)
+					} else {
+						http.Error(w, "Failed to test webhook", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success":  delivery.Error == "",
+					"delivery": delivery,
+				})
+				return
+			}
+
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] DELETE /alerts/webhooks/%d - Remove webhook from %s", id, r.RemoteAddr)
+			if err := db.DeleteWebhook(id); err != nil {
+				log.Printf("[API] Failed to delete webhook: %v", err)
+				if err.Error() == "webhook not found" {
+					http.Error(w, "Webhook not found", http.StatusNotFound)
+				} else {
+					http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		case path == "/alerts/history":
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] GET /alerts/history - Request from %s", r.RemoteAddr)
+			events, err := db.GetAlertHistory(r.URL.Query().Get("cycle"))
+			if err != nil {
+				log.Printf("[API] Failed to get alert history: %v", err)
+				http.Error(w, "Failed to retrieve alert history", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"events":  events,
+			})
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAlerts_FireOncePerThresholdPerCycle(t *testing.T) {
+	db := setupTestDB(t)
+
+	var received []AlertPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p AlertPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		received = append(received, p)
+	}))
+	defer srv.Close()
+
+	if _, err := db.CreateWebhook("test", srv.URL, "json"); err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	for _, threshold := range []float64{80, 100} {
+		if _, err := db.CreateAlertRule(AlertRule{Scope: "category", Target: "Groceries", Kind: "percent", Threshold: threshold}); err != nil {
+			t.Fatalf("CreateAlertRule failed: %v", err)
+		}
+	}
+
+	// Groceries budget 2000: 1700 crosses 80%, another 100 stays under 100%.
+	for _, tx := range []Transaction{
+		{Description: "Carrefour 1", Amount: 1700, Date: "2026-02-01", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"},
+		{Description: "Carrefour 2", Amount: 100, Date: "2026-02-02", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-02T10:00:00Z", Source: "openai"},
+	} {
+		if _, err := db.SaveTransaction(tx); err != nil {
+			t.Fatalf("SaveTransaction failed: %v", err)
+		}
+		db.waitForAlerts()
+	}
+	if len(received) != 1 || received[0].Threshold != 80 || received[0].Spent != 1700 {
+		t.Fatalf("expected one 80%% delivery at 1700, got %+v", received)
+	}
+
+	if _, err := db.SaveTransaction(Transaction{Description: "Carrefour 3", Amount: 300, Date: "2026-02-03", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-03T10:00:00Z", Source: "openai"}); err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+	db.waitForAlerts()
+	if len(received) != 2 || received[1].Threshold != 100 {
+		t.Fatalf("expected 100%% delivery, got %+v", received)
+	}
+
+	history, err := db.GetAlertHistory("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetAlertHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(history))
+	}
+	if len(history[0].Deliveries) != 1 || history[0].Deliveries[0].StatusCode != 200 {
+		t.Errorf("expected one successful delivery, got %+v", history[0].Deliveries)
+	}
+
+	// A different cycle fires independently.
+	if _, err := db.SaveTransaction(Transaction{Description: "Carrefour Feb", Amount: 1900, Date: "2026-03-01", Category: "Groceries", BillingCycle: "Feb 2026", Timestamp: "2026-03-01T10:00:00Z", Source: "openai"}); err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+	db.waitForAlerts()
+	if len(received) != 3 || received[2].Cycle != "Feb 2026" {
+		t.Errorf("expected Feb 2026 alert, got %+v", received)
+	}
+}
+
+func TestAlerts_SlowWebhookDoesNotBlockSave(t *testing.T) {
+	db := setupTestDB(t)
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	db.CreateWebhook("slow", srv.URL, "json")
+	db.CreateAlertRule(AlertRule{Scope: "category", Target: "Groceries", Kind: "percent", Threshold: 50})
+
+	saved := make(chan error, 1)
+	go func() {
+		_, err := db.SaveTransaction(Transaction{Description: "Carrefour", Amount: 1500, Date: "2026-02-01", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"})
+		saved <- err
+	}()
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Fatalf("SaveTransaction failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SaveTransaction waited on the webhook")
+	}
+
+	close(release)
+	db.waitForAlerts()
+	history, _ := db.GetAlertHistory("Jan 2026")
+	if len(history) != 1 || len(history[0].Deliveries) != 1 || history[0].Deliveries[0].StatusCode != 200 {
+		t.Errorf("expected the alert delivered once released, got %+v", history)
+	}
+}
+
+func TestAlerts_NtfyFormatAndTestFire(t *testing.T) {
+	db := setupTestDB(t)
+
+	var title string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		title = r.Header.Get("Title")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	hook, err := db.CreateWebhook("ntfy", srv.URL, "ntfy")
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	delivery, err := db.TestWebhook(hook.ID)
+	if err != nil {
+		t.Fatalf("TestWebhook failed: %v", err)
+	}
+	if title != "Budget alert" {
+		t.Errorf("expected ntfy Title header, got %q", title)
+	}
+	if delivery.StatusCode != 500 || delivery.Error == "" {
+		t.Errorf("expected failed delivery to be recorded, got %+v", delivery)
+	}
+
+	if _, err := db.CreateWebhook("bad", "ftp://example.com", "json"); err == nil {
+		t.Error("expected error for non-http webhook url")
+	}
+	if _, err := db.CreateAlertRule(AlertRule{Scope: "bucket", Target: "savings", Kind: "percent", Threshold: 80}); err == nil {
+		t.Error("expected error for unknown bucket")
+	}
+}
+
+func TestCycleElapsed(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	for _, tc := range []struct {
+		now  string
+		want float64
+	}{
+		{"2026-01-20", 0},
+		{"2026-01-23", 1.0 / 31},
+		{"2026-02-07", 16.0 / 31},
+		{"2026-02-22", 1},
+	} {
+		if got := cycleElapsed("Jan 2026", day(tc.now)); got != tc.want {
+			t.Errorf("%s: expected %.4f, got %.4f", tc.now, tc.want, got)
+		}
+	}
+}
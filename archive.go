@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// An archived category is retired rather than deleted: it drops out of
+// pickers, the category list and the LLM prompt, and its budget stops from
+// the cycle it was archived in, but transactions and past budgets keep
+// resolving against it so earlier cycles read the same. Restoring brings
+// the budget back from the current cycle.
+
+func (c *DatabaseClient) migrateCategoryArchive() error {
+	if err := c.addColumnIfNotExists("categories", "archived INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("failed to add archived column: %w", err)
+	}
+	return nil
+}
+
+// categoryUsage counts one kind of record that points at a category.
+type categoryUsage struct {
+	count int
+	what  string
+}
+
+func (u categoryUsage) String() string {
+	return fmt.Sprintf("%d %s%s", u.count, u.what, pluralize(u.count))
+}
+
+// categoryUsages lists what still points at a category, by name or ID,
+// skipping kinds with no rows.
+func (c *DatabaseClient) categoryUsages(id int64, name string) ([]categoryUsage, error) {
+	checks := []struct {
+		what  string
+		query string
+		arg   interface{}
+	}{
+		{"transaction", "SELECT COUNT(*) FROM transactions WHERE category = ?", name},
+		{"split line", "SELECT COUNT(*) FROM transaction_splits WHERE category = ?", name},
+		{"merchant rule", "SELECT COUNT(*) FROM merchant_rules WHERE category = ?", name},
+		{"alert rule", "SELECT COUNT(*) FROM alert_rules WHERE scope = 'category' AND target = ?", name},
+		{"funding record", "SELECT COUNT(*) FROM cycle_funding WHERE category_id = ?", id},
+		{"auto-fund matcher", "SELECT COUNT(*) FROM funding_matchers WHERE category_id = ?", id},
+		{"savings goal", "SELECT COUNT(*) FROM savings_goals WHERE category_id = ?", id},
+		{"sinking fund", "SELECT COUNT(*) FROM sinking_funds WHERE category_id = ?", id},
+	}
+	var usages []categoryUsage
+	for _, check := range checks {
+		var count int
+		if err := c.db.QueryRow(check.query, check.arg).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %ss: %w", check.what, err)
+		}
+		if count > 0 {
+			usages = append(usages, categoryUsage{count, check.what})
+		}
+	}
+	return usages, nil
+}
+
+func joinUsages(usages []categoryUsage) string {
+	parts := make([]string, len(usages))
+	for i, u := range usages {
+		parts[i] = u.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ArchiveCategory hides a category and ends its budget from the current
+// cycle. A category that merchant rules or auto-fund matchers still assign
+// to, or with active sub-categories, can't be archived: those would keep
+// filing new spend under it.
+func (c *DatabaseClient) ArchiveCategory(id int64) error {
+	var name string
+	var archived int
+	err := c.db.QueryRow("SELECT name, archived FROM categories WHERE id = ?", id).Scan(&name, &archived)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch category: %w", err)
+	}
+	if archived == 1 {
+		return nil
+	}
+
+	var children int
+	if err := c.db.QueryRow(
+		"SELECT COUNT(*) FROM categories WHERE parent_id = ? AND archived = 0", id,
+	).Scan(&children); err != nil {
+		return fmt.Errorf("failed to count sub-categories: %w", err)
+	}
+	if children > 0 {
+		return fmt.Errorf("cannot archive: category has %d active sub-categories", children)
+	}
+	usages, err := c.categoryUsages(id, name)
+	if err != nil {
+		return err
+	}
+	var assigning []categoryUsage
+	for _, u := range usages {
+		if u.what == "merchant rule" || u.what == "auto-fund matcher" {
+			assigning = append(assigning, u)
+		}
+	}
+	if len(assigning) > 0 {
+		return fmt.Errorf("cannot archive: category is used by %s", joinUsages(assigning))
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE categories SET archived = 1 WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to archive category: %w", err)
+	}
+	// budget_amount is left as it was so a restore can bring it back.
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	if err := setBudgetVersion(tx, id, current, nil, false); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RestoreCategory un-archives a category, reinstating the budget it had
+// when it was archived from the current cycle forward.
+func (c *DatabaseClient) RestoreCategory(id int64) error {
+	var archived int
+	var budget sql.NullFloat64
+	var parentArchived sql.NullInt64
+	err := c.db.QueryRow(`
+		SELECT c.archived, c.budget_amount, p.archived
+		FROM categories c LEFT JOIN categories p ON p.id = c.parent_id
+		WHERE c.id = ?`, id,
+	).Scan(&archived, &budget, &parentArchived)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch category: %w", err)
+	}
+	if archived == 0 {
+		return nil
+	}
+	if parentArchived.Valid && parentArchived.Int64 == 1 {
+		return fmt.Errorf("cannot restore: parent category is archived")
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE categories SET archived = 0 WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to restore category: %w", err)
+	}
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	if err := setBudgetVersion(tx, id, current, nullFloatPtr(budget), false); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
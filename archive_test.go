@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveCategory_KeepsHistory(t *testing.T) {
+	db := setupTestDB(t)
+	beauty := categoryByName(t, db, "Beauty")
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	insertTestTransaction(t, db, Transaction{Description: "Tips & Toes", Amount: 250, Date: "2026-01-25", Category: "Beauty", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual"})
+	before, _ := db.GetStats("Jan 2026")
+
+	if err := db.ArchiveCategory(beauty.ID); err != nil {
+		t.Fatalf("ArchiveCategory failed: %v", err)
+	}
+	if got := categoryByName(t, db, "Beauty"); !got.Archived || got.Emoji != beauty.Emoji || got.Type != "wants" {
+		t.Errorf("expected Beauty archived with its emoji and type, got %+v", got)
+	}
+
+	// Earlier cycles read the same; the budget ends from the current cycle.
+	jan, _ := db.GetStats("Jan 2026")
+	if row := statsRow(t, jan.Categories, "Beauty"); row.Emoji != beauty.Emoji || row.Budget == nil || *row.Budget != 400 {
+		t.Errorf("expected Jan's Beauty row unchanged, got %+v", row)
+	}
+	if jan.WantsBudget != before.WantsBudget {
+		t.Errorf("expected Jan's wants budget unchanged, got %v → %v", before.WantsBudget, jan.WantsBudget)
+	}
+	if b, _ := db.BudgetFor(beauty.ID, current); b != nil {
+		t.Errorf("expected no budget in %s, got %v", current, *b)
+	}
+
+	cats, _ := db.GetAllCategories()
+	if strings.Contains(BuildSystemPrompt(cats), `"Beauty"`) {
+		t.Error("expected Beauty left out of the prompt")
+	}
+	w := httptest.NewRecorder()
+	categoriesHandler(db)(w, httptest.NewRequest(http.MethodGet, "/categories", nil))
+	if strings.Contains(w.Body.String(), `"Beauty"`) {
+		t.Error("expected Beauty hidden from the category list")
+	}
+	w = httptest.NewRecorder()
+	categoriesHandler(db)(w, httptest.NewRequest(http.MethodGet, "/categories?archived=true", nil))
+	if !strings.Contains(w.Body.String(), `"Beauty"`) {
+		t.Error("expected ?archived=true to list Beauty")
+	}
+
+	if err := db.RestoreCategory(beauty.ID); err != nil {
+		t.Fatalf("RestoreCategory failed: %v", err)
+	}
+	if got := categoryByName(t, db, "Beauty"); got.Archived {
+		t.Error("expected Beauty restored")
+	}
+	if b, _ := db.BudgetFor(beauty.ID, current); b == nil || *b != 400 {
+		t.Errorf("expected the 400 budget back in %s, got %v", current, budgetValue(b))
+	}
+}
+
+func TestArchiveCategory_Guards(t *testing.T) {
+	db := setupTestDB(t)
+	pets, _ := db.CreateCategory("Pets", "🐾", false, "wants", nil, "actual")
+	vet, _ := db.CreateCategory("Vet", "🩺", false, "wants", nil, "actual")
+	db.SetCategoryParent(vet.ID, &pets.ID)
+
+	if err := db.ArchiveCategory(pets.ID); err == nil || !strings.HasPrefix(err.Error(), "cannot archive:") {
+		t.Errorf("expected a parent with active children to be refused, got %v", err)
+	}
+	if _, _, _, err := db.CreateRule("blue cross", MatchContains, "Vet", 10); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if err := db.ArchiveCategory(vet.ID); err == nil || !strings.Contains(err.Error(), "1 merchant rule") {
+		t.Errorf("expected the rule to block archiving, got %v", err)
+	}
+	if err := db.ArchiveCategory(9999); err == nil || err.Error() != "category not found" {
+		t.Errorf("expected category not found, got %v", err)
+	}
+
+	// With the child archived too, restoring it under an archived parent is
+	// refused.
+	rules, _ := db.GetAllRules()
+	for _, rule := range rules {
+		if rule.Category == "Vet" {
+			db.DeleteRule(rule.ID)
+		}
+	}
+	if err := db.ArchiveCategory(vet.ID); err != nil {
+		t.Fatalf("ArchiveCategory failed: %v", err)
+	}
+	if err := db.ArchiveCategory(pets.ID); err != nil {
+		t.Fatalf("ArchiveCategory failed: %v", err)
+	}
+	if err := db.RestoreCategory(vet.ID); err == nil || !strings.HasPrefix(err.Error(), "cannot restore:") {
+		t.Errorf("expected restoring under an archived parent to be refused, got %v", err)
+	}
+
+	// An unreferenced category can still be deleted outright.
+	if err := db.DeleteCategory(vet.ID); err != nil {
+		t.Errorf("expected an unreferenced category to delete, got %v", err)
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FundingMatcher ticks an allocated category off automatically when a saved
+// transaction looks like its payment (the rent transfer SMS, the car loan
+// debit). A transaction matches when its description contains Keyword, its
+// amount is within Tolerance AED of Amount (the category's budget for the
+// cycle when Amount is nil), and its day of month falls in DayFrom–DayTo
+// (either 0 means any day; DayFrom > DayTo wraps across month end).
+type FundingMatcher struct {
+	ID         int64    `json:"id"`
+	CategoryID int64    `json:"categoryId"`
+	Category   string   `json:"category"`
+	Keyword    string   `json:"keyword"`
+	Amount     *float64 `json:"amount"`
+	Tolerance  float64  `json:"tolerance"`
+	DayFrom    int      `json:"dayFrom"`
+	DayTo      int      `json:"dayTo"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+// FundingAudit records every automatic funding and every undo of one.
+type FundingAudit struct {
+	ID            int64   `json:"id"`
+	Cycle         string  `json:"cycle"`
+	CategoryID    int64   `json:"categoryId"`
+	Action        string  `json:"action"` // "auto_fund" | "undo"
+	Amount        float64 `json:"amount"`
+	TransactionID *int64  `json:"transactionId,omitempty"`
+	MatcherID     *int64  `json:"matcherId,omitempty"`
+	Undone        bool    `json:"undone"`
+	CreatedAt     string  `json:"createdAt"`
+}
+
+func (c *DatabaseClient) migrateAutoFund() error {
+	// transaction_id links a funding entry to the payment that triggered it;
+	// NULL for manual ticks.
+	if err := c.addColumnIfNotExists("cycle_funding", "transaction_id INTEGER"); err != nil {
+		return fmt.Errorf("failed to add cycle_funding.transaction_id column: %w", err)
+	}
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS funding_matchers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			category_id INTEGER NOT NULL,
+			keyword TEXT NOT NULL,
+			amount REAL,
+			tolerance REAL NOT NULL DEFAULT 0,
+			day_from INTEGER NOT NULL DEFAULT 0,
+			day_to INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS funding_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cycle TEXT NOT NULL,
+			category_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			amount REAL NOT NULL,
+			transaction_id INTEGER,
+			matcher_id INTEGER,
+			undone INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL
+		)`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("auto-fund migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *DatabaseClient) GetFundingMatchers() ([]FundingMatcher, error) {
+	rows, err := c.db.Query(
+		`SELECT m.id, m.category_id, c.name, m.keyword, m.amount, m.tolerance, m.day_from, m.day_to, m.created_at
+		 FROM funding_matchers m JOIN categories c ON c.id = m.category_id ORDER BY m.id ASC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding matchers: %w", err)
+	}
+	defer rows.Close()
+
+	matchers := []FundingMatcher{}
+	for rows.Next() {
+		var m FundingMatcher
+		var amount sql.NullFloat64
+		if err := rows.Scan(&m.ID, &m.CategoryID, &m.Category, &m.Keyword, &amount, &m.Tolerance, &m.DayFrom, &m.DayTo, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan funding matcher: %w", err)
+		}
+		if amount.Valid {
+			m.Amount = floatPtr(amount.Float64)
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, rows.Err()
+}
+
+func (c *DatabaseClient) CreateFundingMatcher(m FundingMatcher) (*FundingMatcher, error) {
+	cat, err := c.GetCategory(m.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if cat.Tracking != "allocated" {
+		return nil, fmt.Errorf("auto-fund requires allocated tracking")
+	}
+	if strings.TrimSpace(m.Keyword) == "" {
+		return nil, fmt.Errorf("keyword is required")
+	}
+	if m.Tolerance < 0 || m.DayFrom < 0 || m.DayFrom > 31 || m.DayTo < 0 || m.DayTo > 31 {
+		return nil, fmt.Errorf("invalid matcher window")
+	}
+	m.Category = cat.Name
+	m.CreatedAt = time.Now().Format(time.RFC3339)
+	result, err := c.db.Exec(
+		"INSERT INTO funding_matchers (category_id, keyword, amount, tolerance, day_from, day_to, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		m.CategoryID, m.Keyword, m.Amount, m.Tolerance, m.DayFrom, m.DayTo, m.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create funding matcher: %w", err)
+	}
+	m.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &m, nil
+}
+
+func (c *DatabaseClient) DeleteFundingMatcher(id int64) error {
+	result, err := c.db.Exec("DELETE FROM funding_matchers WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete funding matcher: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("funding matcher not found")
+	}
+	return nil
+}
+
+// inDayWindow reports whether day falls in from–to, wrapping past month end
+// when from > to (e.g. 28–3).
+func inDayWindow(day, from, to int) bool {
+	if from == 0 || to == 0 {
+		return true
+	}
+	if from <= to {
+		return day >= from && day <= to
+	}
+	return day >= from || day <= to
+}
+
+// autoFundTransaction runs the funding matchers against a newly saved
+// transaction. The first matcher whose category is not yet fully funded for
+// the transaction's cycle adds an entry for the rest of the budget, linked
+// to the transaction, and writes an audit entry.
+func (c *DatabaseClient) autoFundTransaction(txID int64) error {
+	var desc, date, cycle string
+	var amount float64
+	err := c.db.QueryRow(
+		"SELECT description, amount, transaction_date, billing_cycle FROM transactions WHERE id = ?", txID,
+	).Scan(&desc, &amount, &date, &cycle)
+	if err != nil {
+		return fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	day := 0
+	if d, err := time.Parse("2006-01-02", date); err == nil {
+		day = d.Day()
+	}
+
+	matchers, err := c.GetFundingMatchers()
+	if err != nil || len(matchers) == 0 {
+		return err
+	}
+	funded, err := c.GetFunding(cycle)
+	if err != nil {
+		return err
+	}
+	budgets, err := c.BudgetsForCycle(cycle)
+	if err != nil {
+		return err
+	}
+
+	lower := strings.ToLower(desc)
+	for _, m := range matchers {
+		if !strings.Contains(lower, strings.ToLower(m.Keyword)) || !inDayWindow(day, m.DayFrom, m.DayTo) {
+			continue
+		}
+		expected := m.Amount
+		if expected == nil {
+			expected = budgets[m.CategoryID]
+		}
+		if expected == nil || math.Abs(math.Abs(amount)-*expected) > m.Tolerance {
+			continue
+		}
+
+		budget := budgets[m.CategoryID]
+		if budget == nil {
+			budget = expected
+		}
+		rest := *budget - funded[m.CategoryID]
+		if rest <= 0 {
+			continue
+		}
+		if err := c.fundFromTransaction(cycle, m.CategoryID, rest, txID, m.ID); err != nil {
+			return err
+		}
+		log.Printf("[Database] Auto-funded %s for %s from transaction %d", m.Category, cycle, txID)
+		return nil
+	}
+	return nil
+}
+
+// fundFromTransaction adds a funding entry linked to a transaction and audits it.
+func (c *DatabaseClient) fundFromTransaction(cycle string, categoryID int64, amount float64, txID, matcherID int64) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+	if _, err := tx.Exec(
+		"INSERT INTO cycle_funding (cycle, category_id, amount, funded_at, transaction_id, note) VALUES (?, ?, ?, ?, ?, 'auto-fund')",
+		cycle, categoryID, amount, now, txID,
+	); err != nil {
+		return fmt.Errorf("failed to set funding: %w", err)
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO funding_audit (cycle, category_id, action, amount, transaction_id, matcher_id, created_at) VALUES (?, ?, 'auto_fund', ?, ?, ?, ?)",
+		cycle, categoryID, amount, txID, matcherID, now,
+	); err != nil {
+		return fmt.Errorf("failed to write funding audit: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UndoAutoFund reverses an automatic funding: the entry linked to the
+// transaction is removed (if still present) and an "undo" entry is recorded.
+func (c *DatabaseClient) UndoAutoFund(auditID int64) error {
+	var a FundingAudit
+	var txID sql.NullInt64
+	var undone int
+	err := c.db.QueryRow(
+		"SELECT cycle, category_id, action, amount, transaction_id, undone FROM funding_audit WHERE id = ?", auditID,
+	).Scan(&a.Cycle, &a.CategoryID, &a.Action, &a.Amount, &txID, &undone)
+	if err == sql.ErrNoRows || (err == nil && a.Action != "auto_fund") {
+		return fmt.Errorf("auto-fund entry not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch funding audit: %w", err)
+	}
+	if undone == 1 {
+		return fmt.Errorf("auto-fund already undone")
+	}
+	if err := c.ensureCycleOpen(a.Cycle); err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM cycle_funding WHERE cycle = ? AND category_id = ? AND transaction_id = ?",
+		a.Cycle, a.CategoryID, txID,
+	); err != nil {
+		return fmt.Errorf("failed to delete funding: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE funding_audit SET undone = 1 WHERE id = ?", auditID); err != nil {
+		return fmt.Errorf("failed to update funding audit: %w", err)
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO funding_audit (cycle, category_id, action, amount, transaction_id, created_at) VALUES (?, ?, 'undo', ?, ?, ?)",
+		a.Cycle, a.CategoryID, a.Amount, txID, time.Now().Format(time.RFC3339),
+	); err != nil {
+		return fmt.Errorf("failed to write funding audit: %w", err)
+	}
+	return tx.Commit()
+}
+
+// unfundTransaction removes the funding entries linked to a deleted
+// transaction and records an "undo" audit entry for each, as UndoAutoFund
+// would.
+func (c *DatabaseClient) unfundTransaction(txID int64) error {
+	rows, err := c.db.Query("SELECT cycle, category_id, amount FROM cycle_funding WHERE transaction_id = ?", txID)
+	if err != nil {
+		return fmt.Errorf("failed to query funding: %w", err)
+	}
+	var linked []FundingEntry
+	for rows.Next() {
+		var e FundingEntry
+		if err := rows.Scan(&e.Cycle, &e.CategoryID, &e.Amount); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan funding: %w", err)
+		}
+		linked = append(linked, e)
+	}
+	rows.Close()
+	if len(linked) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM cycle_funding WHERE transaction_id = ?", txID); err != nil {
+		return fmt.Errorf("failed to delete funding: %w", err)
+	}
+	if _, err := tx.Exec(
+		"UPDATE funding_audit SET undone = 1 WHERE transaction_id = ? AND action = 'auto_fund'", txID,
+	); err != nil {
+		return fmt.Errorf("failed to update funding audit: %w", err)
+	}
+	now := time.Now().Format(time.RFC3339)
+	for _, e := range linked {
+		if _, err := tx.Exec(
+			"INSERT INTO funding_audit (cycle, category_id, action, amount, transaction_id, created_at) VALUES (?, ?, 'undo', ?, ?, ?)",
+			e.Cycle, e.CategoryID, e.Amount, txID, now,
+		); err != nil {
+			return fmt.Errorf("failed to write funding audit: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetFundingAudit lists audit entries newest first, optionally for one cycle.
+func (c *DatabaseClient) GetFundingAudit(cycle string) ([]FundingAudit, error) {
+	query := "SELECT id, cycle, category_id, action, amount, transaction_id, matcher_id, undone, created_at FROM funding_audit"
+	var args []interface{}
+	if cycle != "" {
+		query += " WHERE cycle = ?"
+		args = append(args, cycle)
+	}
+	rows, err := c.db.Query(query+" ORDER BY id DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []FundingAudit{}
+	for rows.Next() {
+		var a FundingAudit
+		var txID, matcherID sql.NullInt64
+		var undone int
+		if err := rows.Scan(&a.ID, &a.Cycle, &a.CategoryID, &a.Action, &a.Amount, &txID, &matcherID, &undone, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan funding audit: %w", err)
+		}
+		if txID.Valid {
+			a.TransactionID = &txID.Int64
+		}
+		if matcherID.Valid {
+			a.MatcherID = &matcherID.Int64
+		}
+		a.Undone = undone == 1
+		entries = append(entries, a)
+	}
+	return entries, rows.Err()
+}
+
+// fundingDetailHandler serves the endpoints under /funding/:
+//
+//	GET    /funding/matchers            — list matchers
+//	POST   /funding/matchers            — create a matcher
+//	DELETE /funding/matchers/:id        — delete a matcher
+//	GET    /funding/audit?cycle=        — automatic fundings and undos
+//	POST   /funding/audit/:id/undo      — undo an automatic funding
+//	DELETE /funding/entries/:id         — remove one funding entry
+func fundingDetailHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, "/")
+
+		switch {
+		case path == "/funding/matchers":
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /funding/matchers - Request from %s", r.RemoteAddr)
+				matchers, err := db.GetFundingMatchers()
+				if err != nil {
+					log.Printf("[API] Failed to get funding matchers: %v", err)
+					http.Error(w, "Failed to retrieve funding matchers", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success":  true,
+					"matchers": matchers,
+				})
+
+			case http.MethodPost:
+				log.Printf("[API] POST /funding/matchers - Create matcher from %s", r.RemoteAddr)
+				var req FundingMatcher
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				matcher, err := db.CreateFundingMatcher(req)
+				if err != nil {
+					log.Printf("[API] Failed to create funding matcher: %v", err)
+					switch err.Error() {
+					case "category not found":
+						http.Error(w, "Category not found", http.StatusNotFound)
+					case "auto-fund requires allocated tracking", "keyword is required", "invalid matcher window":
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					default:
+						http.Error(w, "Failed to create funding matcher", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"matcher": matcher,
+				})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+
+		case strings.HasPrefix(path, "/funding/matchers/"):
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var id int64
+			if _, err := fmt.Sscanf(path[len("/funding/matchers/"):], "%d", &id); err != nil {
+				http.Error(w, "Invalid matcher ID", http.StatusBadRequest)
+				return
+			}
+			log.Printf("[API] DELETE /funding/matchers/%d - Delete matcher from %s", id, r.RemoteAddr)
+			if err := db.DeleteFundingMatcher(id); err != nil {
+				log.Printf("[API] Failed to delete funding matcher: %v", err)
+				if err.Error() == "funding matcher not found" {
+					http.Error(w, "Funding matcher not found", http.StatusNotFound)
+				} else {
+					http.Error(w, "Failed to delete funding matcher", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		case path == "/funding/audit":
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] GET /funding/audit - Request from %s", r.RemoteAddr)
+			entries, err := db.GetFundingAudit(r.URL.Query().Get("cycle"))
+			if err != nil {
+				log.Printf("[API] Failed to get funding audit: %v", err)
+				http.Error(w, "Failed to retrieve funding audit", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"audit":   entries,
+			})
+
+		case strings.HasPrefix(path, "/funding/audit/") && strings.HasSuffix(path, "/undo"):
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var id int64
+			if _, err := fmt.Sscanf(path[len("/funding/audit/"):], "%d", &id); err != nil {
+				http.Error(w, "Invalid audit ID", http.StatusBadRequest)
+				return
+			}
+			log.Printf("[API] POST /funding/audit/%d/undo - Undo auto-fund from %s", id, r.RemoteAddr)
+			if err := db.UndoAutoFund(id); err != nil {
+				log.Printf("[API] Failed to undo auto-fund: %v", err)
+				switch {
+				case err.Error() == "auto-fund entry not found":
+					http.Error(w, "Auto-fund entry not found", http.StatusNotFound)
+				case err.Error() == "auto-fund already undone":
+					http.Error(w, "Auto-fund already undone", http.StatusConflict)
+				case strings.HasPrefix(err.Error(), "cycle closed:"):
+					writeCycleClosed(w, err)
+				default:
+					http.Error(w, "Failed to undo auto-fund", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		case strings.HasPrefix(path, "/funding/entries/"):
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var id int64
+			if _, err := fmt.Sscanf(path[len("/funding/entries/"):], "%d", &id); err != nil {
+				http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+				return
+			}
+			log.Printf("[API] DELETE /funding/entries/%d - Delete funding entry from %s", id, r.RemoteAddr)
+			if err := db.DeleteFundingEntry(id); err != nil {
+				log.Printf("[API] Failed to delete funding entry: %v", err)
+				switch {
+				case err.Error() == "funding entry not found":
+					http.Error(w, "Funding entry not found", http.StatusNotFound)
+				case strings.HasPrefix(err.Error(), "cycle closed:"):
+					writeCycleClosed(w, err)
+				default:
+					http.Error(w, "Failed to delete funding entry", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAutoFund_MatchingTransactionFundsCategory(t *testing.T) {
+	db := setupTestDB(t)
+
+	rent := categoryByName(t, db, "Rent") // allocated, budget 9000
+	if _, err := db.CreateFundingMatcher(FundingMatcher{CategoryID: rent.ID, Keyword: "landlord", Tolerance: 50, DayFrom: 25, DayTo: 2}); err != nil {
+		t.Fatalf("CreateFundingMatcher failed: %v", err)
+	}
+
+	// Wrong day and wrong amount don't match.
+	for _, tx := range []Transaction{
+		{Description: "Transfer to LANDLORD", Amount: 9000, Date: "2026-02-10", Category: "Income/Transfer", BillingCycle: "Jan 2026", Timestamp: "2026-02-10T10:00:00Z", Source: "openai"},
+		{Description: "Transfer to LANDLORD deposit", Amount: 2000, Date: "2026-01-28", Category: "Income/Transfer", BillingCycle: "Jan 2026", Timestamp: "2026-01-28T10:00:00Z", Source: "openai"},
+	} {
+		if _, err := db.SaveTransaction(tx); err != nil {
+			t.Fatalf("SaveTransaction failed: %v", err)
+		}
+	}
+	funding, _ := db.GetFunding("Jan 2026")
+	if _, ok := funding[rent.ID]; ok {
+		t.Fatal("expected no funding from non-matching transactions")
+	}
+
+	id, err := db.SaveTransaction(Transaction{Description: "Transfer to LANDLORD", Amount: 8990, Date: "2026-02-01", Category: "Income/Transfer", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"})
+	if err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+	funding, _ = db.GetFunding("Jan 2026")
+	if funding[rent.ID] != 9000 {
+		t.Fatalf("expected Rent funded at budget 9000, got %v", funding[rent.ID])
+	}
+	var linked int64
+	db.db.QueryRow("SELECT transaction_id FROM cycle_funding WHERE cycle = 'Jan 2026' AND category_id = ?", rent.ID).Scan(&linked)
+	if linked != id {
+		t.Errorf("expected funding linked to transaction %d, got %d", id, linked)
+	}
+
+	audit, err := db.GetFundingAudit("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetFundingAudit failed: %v", err)
+	}
+	if len(audit) != 1 || audit[0].Action != "auto_fund" || *audit[0].TransactionID != id {
+		t.Fatalf("expected one auto_fund audit entry, got %+v", audit)
+	}
+
+	// A manual tick on top of the auto-fund keeps the linked entry.
+	if err := db.SetFunding("Jan 2026", rent.ID, 9000); err != nil {
+		t.Fatalf("SetFunding failed: %v", err)
+	}
+	if entries, _ := db.GetFundingEntries("Jan 2026"); len(entries) != 1 || entries[0].TransactionID == nil {
+		t.Fatalf("expected the auto-fund entry to survive a tick, got %+v", entries)
+	}
+
+	if err := db.UndoAutoFund(audit[0].ID); err != nil {
+		t.Fatalf("UndoAutoFund failed: %v", err)
+	}
+	funding, _ = db.GetFunding("Jan 2026")
+	if _, ok := funding[rent.ID]; ok {
+		t.Error("expected undo to clear the funding")
+	}
+	if err := db.UndoAutoFund(audit[0].ID); err == nil {
+		t.Error("expected second undo to fail")
+	}
+	audit, _ = db.GetFundingAudit("Jan 2026")
+	if len(audit) != 2 || audit[0].Action != "undo" || !audit[1].Undone {
+		t.Errorf("expected undo entry recorded, got %+v", audit)
+	}
+}
+
+func TestAutoFund_DeletingTransactionUndoesFunding(t *testing.T) {
+	db := setupTestDB(t)
+
+	rent := categoryByName(t, db, "Rent") // allocated, budget 9000
+	if _, err := db.CreateFundingMatcher(FundingMatcher{CategoryID: rent.ID, Keyword: "landlord", Tolerance: 50}); err != nil {
+		t.Fatalf("CreateFundingMatcher failed: %v", err)
+	}
+	id, err := db.SaveTransaction(Transaction{Description: "Transfer to LANDLORD", Amount: 9000, Date: "2026-02-01", Category: "Income/Transfer", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"})
+	if err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+	if _, err := db.AddFundingEntry("Jan 2026", rent.ID, 500, "extra", ""); err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+
+	if err := db.DeleteTransaction(id); err != nil {
+		t.Fatalf("DeleteTransaction failed: %v", err)
+	}
+	entries, _ := db.GetFundingEntries("Jan 2026")
+	if len(entries) != 1 || entries[0].TransactionID != nil || entries[0].Amount != 500 {
+		t.Fatalf("expected only the manual entry to remain, got %+v", entries)
+	}
+	audit, _ := db.GetFundingAudit("Jan 2026")
+	if len(audit) != 2 || audit[0].Action != "undo" || audit[0].Amount != 9000 || !audit[1].Undone {
+		t.Errorf("expected the auto-fund to be marked undone with an undo entry, got %+v", audit)
+	}
+}
+
+func TestAutoFund_RequiresAllocatedCategory(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries")
+	if _, err := db.CreateFundingMatcher(FundingMatcher{CategoryID: groc.ID, Keyword: "carrefour"}); err == nil {
+		t.Error("expected actual-tracked category to be rejected")
+	}
+	if !inDayWindow(30, 28, 3) || !inDayWindow(2, 28, 3) || inDayWindow(10, 28, 3) {
+		t.Error("expected wrapping day window 28–3")
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BudgetVersion is a category budget effective from a billing cycle. A
+// forward version applies from EffectiveCycle until the next forward version;
+// a one-off version overrides just its own cycle. The base version seeded
+// from categories.budget_amount has an empty EffectiveCycle ("since always").
+type BudgetVersion struct {
+	ID             int64    `json:"id"`
+	CategoryID     int64    `json:"categoryId"`
+	EffectiveCycle string   `json:"effectiveCycle"`
+	Amount         *float64 `json:"amount"`
+	OneOff         bool     `json:"oneOff"`
+	CreatedAt      string   `json:"createdAt"`
+}
+
+// cycleSortKey turns a "Jan 2006" cycle key into a sortable "2006-01" string.
+// The empty cycle (base version) maps to "", which sorts first.
+func cycleSortKey(cycle string) string {
+	t, err := time.Parse("Jan 2006", cycle)
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006-01")
+}
+
+func (c *DatabaseClient) migrateBudgetVersions() error {
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS budget_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			category_id INTEGER NOT NULL,
+			effective_cycle TEXT NOT NULL,
+			effective_key TEXT NOT NULL,
+			amount REAL,
+			one_off INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL,
+			UNIQUE(category_id, effective_key, one_off)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_budget_versions_key ON budget_versions(effective_key)`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("budget_versions migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// seedBudgetVersions gives every category without history a base version
+// holding its current budget_amount, so existing cycles keep today's numbers.
+// Runs after runBudgetingSetup so seeded budgets are captured.
+func (c *DatabaseClient) seedBudgetVersions() error {
+	_, err := c.db.Exec(
+		`INSERT INTO budget_versions (category_id, effective_cycle, effective_key, amount, one_off, created_at)
+		 SELECT id, '', '', budget_amount, 0, ? FROM categories
+		 WHERE id NOT IN (SELECT category_id FROM budget_versions)`,
+		time.Now().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to seed budget versions: %w", err)
+	}
+	return nil
+}
+
+// setBudgetVersion upserts a version inside an existing SQL transaction.
+func setBudgetVersion(tx *sql.Tx, categoryID int64, cycle string, amount *float64, oneOff bool) error {
+	flag := 0
+	if oneOff {
+		flag = 1
+	}
+	_, err := tx.Exec(
+		`INSERT INTO budget_versions (category_id, effective_cycle, effective_key, amount, one_off, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(category_id, effective_key, one_off) DO UPDATE SET amount = excluded.amount, created_at = excluded.created_at`,
+		categoryID, cycle, cycleSortKey(cycle), amount, flag, time.Now().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set budget version: %w", err)
+	}
+	return nil
+}
+
+// SetCategoryBudget records a budget for a category starting at cycle
+// ("from this cycle forward"), or for that cycle alone when oneOff is set.
+// categories.budget_amount tracks the forward budget in effect today.
+func (c *DatabaseClient) SetCategoryBudget(id int64, cycle string, amount *float64, oneOff bool) error {
+	if cycle == "" {
+		cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+	}
+	if cycleSortKey(cycle) == "" {
+		return fmt.Errorf("invalid cycle")
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ?", id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to fetch category: %w", err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	if err := setBudgetVersion(tx, id, cycle, amount, oneOff); err != nil {
+		return err
+	}
+
+	// Recompute from the versions rather than copying amount: a later
+	// forward version already in effect still wins over a back-dated one.
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	if !oneOff && cycleSortKey(cycle) <= cycleSortKey(current) {
+		if _, err := tx.Exec(
+			`UPDATE categories SET budget_amount = (
+				SELECT amount FROM budget_versions
+				WHERE category_id = ? AND one_off = 0 AND effective_key <= ?
+				ORDER BY effective_key DESC LIMIT 1)
+			 WHERE id = ?`,
+			id, cycleSortKey(current), id,
+		); err != nil {
+			return fmt.Errorf("failed to set category target: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetBudgetVersions lists a category's budget history, oldest first.
+func (c *DatabaseClient) GetBudgetVersions(id int64) ([]BudgetVersion, error) {
+	rows, err := c.db.Query(
+		`SELECT id, category_id, effective_cycle, amount, one_off, created_at FROM budget_versions
+		 WHERE category_id = ? ORDER BY effective_key ASC, one_off ASC`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []BudgetVersion
+	for rows.Next() {
+		var v BudgetVersion
+		var amount sql.NullFloat64
+		var oneOff int
+		if err := rows.Scan(&v.ID, &v.CategoryID, &v.EffectiveCycle, &amount, &oneOff, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan budget version: %w", err)
+		}
+		if amount.Valid {
+			v.Amount = floatPtr(amount.Float64)
+		}
+		v.OneOff = oneOff == 1
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// BudgetsForCycle resolves every category's budget as it stood in a cycle:
+// a one-off for that exact cycle wins, otherwise the latest forward version
+// at or before it. Categories with no history fall back to budget_amount.
+func (c *DatabaseClient) BudgetsForCycle(cycle string) (map[int64]*float64, error) {
+	key := cycleSortKey(cycle)
+	rows, err := c.db.Query(
+		`SELECT category_id, effective_key, amount, one_off FROM budget_versions
+		 WHERE effective_key <= ? ORDER BY effective_key ASC`,
+		key,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget versions: %w", err)
+	}
+	defer rows.Close()
+
+	forward := make(map[int64]*float64)
+	override := make(map[int64]*float64)
+	for rows.Next() {
+		var id int64
+		var effKey string
+		var amount sql.NullFloat64
+		var oneOff int
+		if err := rows.Scan(&id, &effKey, &amount, &oneOff); err != nil {
+			return nil, fmt.Errorf("failed to scan budget version: %w", err)
+		}
+		var v *float64
+		if amount.Valid {
+			v = floatPtr(amount.Float64)
+		}
+		if oneOff == 1 {
+			if effKey == key {
+				override[id] = v
+			}
+			continue
+		}
+		forward[id] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	budgets := make(map[int64]*float64, len(forward))
+	for id, v := range forward {
+		budgets[id] = v
+	}
+	for id, v := range override {
+		budgets[id] = v
+	}
+
+	// Fallback for categories that predate versioning and were never seeded.
+	// Categories whose history starts after this cycle have no budget yet.
+	// (Collected up front: the pool has a single connection, so no nested queries.)
+	hasHistory := make(map[int64]bool)
+	histRows, err := c.db.Query("SELECT DISTINCT category_id FROM budget_versions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget versions: %w", err)
+	}
+	for histRows.Next() {
+		var id int64
+		if err := histRows.Scan(&id); err != nil {
+			histRows.Close()
+			return nil, fmt.Errorf("failed to scan budget version: %w", err)
+		}
+		hasHistory[id] = true
+	}
+	histRows.Close()
+
+	catRows, err := c.db.Query("SELECT id, budget_amount FROM categories")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer catRows.Close()
+	for catRows.Next() {
+		var id int64
+		var amount sql.NullFloat64
+		if err := catRows.Scan(&id, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		if _, ok := budgets[id]; ok {
+			continue
+		}
+		if amount.Valid && !hasHistory[id] {
+			budgets[id] = floatPtr(amount.Float64)
+		} else {
+			budgets[id] = nil
+		}
+	}
+	return budgets, catRows.Err()
+}
+
+// BudgetFor resolves one category's budget in a cycle.
+func (c *DatabaseClient) BudgetFor(id int64, cycle string) (*float64, error) {
+	budgets, err := c.BudgetsForCycle(cycle)
+	if err != nil {
+		return nil, err
+	}
+	return budgets[id], nil
+}
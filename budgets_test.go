@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBudgetVersions_PastCyclesKeepOldBudget(t *testing.T) {
+	db := setupTestDB(t)
+
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	groc := categoryByName(t, db, "Groceries") // seeded base version: 2000
+
+	if err := db.SetCategoryTarget(groc.ID, floatPtr(2500)); err != nil {
+		t.Fatalf("SetCategoryTarget failed: %v", err)
+	}
+
+	past, err := db.BudgetFor(groc.ID, "Jan 2026")
+	if err != nil {
+		t.Fatalf("BudgetFor failed: %v", err)
+	}
+	if past == nil || *past != 2000 {
+		t.Errorf("expected Jan 2026 budget to stay 2000, got %v", past)
+	}
+	now, _ := db.BudgetFor(groc.ID, current)
+	if now == nil || *now != 2500 {
+		t.Errorf("expected current budget 2500, got %v", now)
+	}
+
+	stats, err := db.GetStats("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	for _, cat := range stats.CategoryDefinitions {
+		if cat.ID == groc.ID && (cat.BudgetAmount == nil || *cat.BudgetAmount != 2000) {
+			t.Errorf("expected Jan 2026 category definition budget 2000, got %v", cat.BudgetAmount)
+		}
+	}
+}
+
+func TestBudgetVersions_OneOffOverridesSingleCycle(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries")
+	if err := db.SetCategoryBudget(groc.ID, "Mar 2026", floatPtr(3000), true); err != nil {
+		t.Fatalf("SetCategoryBudget failed: %v", err)
+	}
+
+	for cycle, want := range map[string]float64{"Feb 2026": 2000, "Mar 2026": 3000, "Apr 2026": 2000} {
+		got, err := db.BudgetFor(groc.ID, cycle)
+		if err != nil {
+			t.Fatalf("BudgetFor failed: %v", err)
+		}
+		if got == nil || *got != want {
+			t.Errorf("%s: expected %.0f, got %v", cycle, want, got)
+		}
+	}
+
+	// A one-off never rewrites the current forward budget.
+	if got := targetOf(t, db, groc.ID); got == nil || *got != 2000 {
+		t.Errorf("expected budget_amount to stay 2000, got %v", got)
+	}
+}
+
+func TestBudgetVersions_ForwardChangeFromCycle(t *testing.T) {
+	db := setupTestDB(t)
+
+	rent := categoryByName(t, db, "Rent")
+	if err := db.SetCategoryBudget(rent.ID, "Mar 2026", floatPtr(9500), false); err != nil {
+		t.Fatalf("SetCategoryBudget failed: %v", err)
+	}
+
+	stats, err := db.GetStats("Feb 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	febFixed := stats.FixedBudget
+
+	stats, err = db.GetStats("May 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.FixedBudget-febFixed != 500 {
+		t.Errorf("expected fixed budget to rise by 500 from Mar 2026, got %.2f → %.2f", febFixed, stats.FixedBudget)
+	}
+
+	versions, err := db.GetBudgetVersions(rent.ID)
+	if err != nil {
+		t.Fatalf("GetBudgetVersions failed: %v", err)
+	}
+	if len(versions) != 2 || versions[1].EffectiveCycle != "Mar 2026" {
+		t.Errorf("expected base + Mar 2026 versions, got %+v", versions)
+	}
+}
+
+func TestBudgetVersions_BackdatedChangeKeepsLaterTarget(t *testing.T) {
+	db := setupTestDB(t)
+
+	rent := categoryByName(t, db, "Rent")
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	if err := db.SetCategoryBudget(rent.ID, shiftCycle(current, -1), floatPtr(9500), false); err != nil {
+		t.Fatalf("SetCategoryBudget failed: %v", err)
+	}
+	if err := db.SetCategoryBudget(rent.ID, shiftCycle(current, -3), floatPtr(8000), false); err != nil {
+		t.Fatalf("SetCategoryBudget failed: %v", err)
+	}
+	if got := targetOf(t, db, rent.ID); got == nil || *got != 9500 {
+		t.Errorf("expected budget_amount to stay at the later 9500, got %v", got)
+	}
+}
+
+func TestBudgetVersions_NewCategoryStartsAtCurrentCycle(t *testing.T) {
+	db := setupTestDB(t)
+
+	cat, err := db.CreateCategory("Pets", "🐶", false, "wants", floatPtr(300), "actual")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	past, _ := db.BudgetFor(cat.ID, "Jan 2026")
+	if past != nil {
+		t.Errorf("expected no budget before the category existed, got %v", *past)
+	}
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	now, _ := db.BudgetFor(cat.ID, current)
+	if now == nil || *now != 300 {
+		t.Errorf("expected current budget 300, got %v", now)
+	}
+}
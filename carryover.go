@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CarryoverEntry is one row of the envelope ledger: what a carry-over
+// category had available in a cycle and what it passed on to the next one.
+// Available = Budget + CarriedIn − Spent, and becomes the next CarriedIn
+// (negative when the envelope was overspent).
+type CarryoverEntry struct {
+	Cycle      string  `json:"cycle"`
+	CategoryID int64   `json:"categoryId"`
+	Category   string  `json:"category"`
+	Budget     float64 `json:"budget"`
+	CarriedIn  float64 `json:"carriedIn"`
+	Spent      float64 `json:"spent"`
+	Available  float64 `json:"available"`
+}
+
+func (c *DatabaseClient) migrateCarryover() error {
+	if err := c.addColumnIfNotExists("categories", "carryover INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("failed to add carryover column: %w", err)
+	}
+	// carryover_start is the first cycle of the current carry-over chain; it
+	// starts from zero there. Resetting a category moves it forward.
+	if err := c.addColumnIfNotExists("categories", "carryover_start TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("failed to add carryover_start column: %w", err)
+	}
+	if _, err := c.db.Exec(`CREATE TABLE IF NOT EXISTS carryover_ledger (
+		cycle TEXT NOT NULL,
+		category_id INTEGER NOT NULL,
+		budget REAL NOT NULL,
+		carried_in REAL NOT NULL,
+		spent REAL NOT NULL,
+		available REAL NOT NULL,
+		computed_at TEXT NOT NULL,
+		PRIMARY KEY (cycle, category_id)
+	)`); err != nil {
+		return fmt.Errorf("carryover_ledger migration failed: %w", err)
+	}
+	return nil
+}
+
+// SetCarryover turns envelope carry-over on or off for a category. Enabling
+// it starts a fresh chain at the current cycle unless one already exists.
+func (c *DatabaseClient) SetCarryover(id int64, enabled bool) error {
+	cat, err := c.GetCategory(id)
+	if err != nil {
+		return err
+	}
+	if enabled && cat.Tracking == "allocated" {
+		return fmt.Errorf("carry-over requires actual tracking")
+	}
+
+	start := cat.CarryoverStart
+	if enabled && start == "" {
+		start = calculateBillingCycle(time.Now().Format("2006-01-02"))
+	}
+	flag := 0
+	if enabled {
+		flag = 1
+	}
+	if _, err := c.db.Exec("UPDATE categories SET carryover=?, carryover_start=? WHERE id=?", flag, start, id); err != nil {
+		return fmt.Errorf("failed to set carryover: %w", err)
+	}
+	return nil
+}
+
+// ResetCarryover zeroes a category's carried balance: the chain restarts at
+// cycle (the current cycle when empty) and ledger rows from that cycle on are
+// dropped. Rows for closed cycles stay as recorded.
+func (c *DatabaseClient) ResetCarryover(id int64, cycle string) error {
+	if _, err := c.GetCategory(id); err != nil {
+		return err
+	}
+	if cycle == "" {
+		cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+	}
+	if cycleSortKey(cycle) == "" {
+		return fmt.Errorf("invalid cycle")
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE categories SET carryover_start=? WHERE id=?", cycle, id); err != nil {
+		return fmt.Errorf("failed to reset carryover: %w", err)
+	}
+	rows, err := tx.Query(
+		"SELECT cycle FROM carryover_ledger WHERE category_id=? AND cycle NOT IN (SELECT cycle FROM cycle_closures WHERE reopened_at IS NULL)", id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query carryover ledger: %w", err)
+	}
+	var stale []string
+	for rows.Next() {
+		var ledgerCycle string
+		if err := rows.Scan(&ledgerCycle); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan carryover ledger: %w", err)
+		}
+		if cycleSortKey(ledgerCycle) >= cycleSortKey(cycle) {
+			stale = append(stale, ledgerCycle)
+		}
+	}
+	rows.Close()
+	for _, ledgerCycle := range stale {
+		if _, err := tx.Exec("DELETE FROM carryover_ledger WHERE category_id=? AND cycle=?", id, ledgerCycle); err != nil {
+			return fmt.Errorf("failed to clear carryover ledger: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCarryoverLedger walks a carry-over category's chain from its start cycle
+// up to `upto` and returns the entries oldest first. Cycles recorded in
+// carryover_ledger when they were closed keep their recorded figures; the
+// rest are computed on read, and nothing is written. Categories without
+// carry-over return nil.
+func (c *DatabaseClient) GetCarryoverLedger(cat Category, upto string) ([]CarryoverEntry, error) {
+	if !cat.Carryover || cat.CarryoverStart == "" {
+		return nil, nil
+	}
+
+	rows, err := c.db.Query(
+		"SELECT cycle, budget, carried_in, spent, available FROM carryover_ledger WHERE category_id = ?", cat.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query carryover ledger: %w", err)
+	}
+	recorded := make(map[string]CarryoverEntry)
+	for rows.Next() {
+		e := CarryoverEntry{CategoryID: cat.ID, Category: cat.Name}
+		if err := rows.Scan(&e.Cycle, &e.Budget, &e.CarriedIn, &e.Spent, &e.Available); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan carryover ledger: %w", err)
+		}
+		recorded[e.Cycle] = e
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []CarryoverEntry
+	var carried float64
+	for _, cycle := range cyclesBetween(cat.CarryoverStart, upto) {
+		if entry, ok := recorded[cycle]; ok {
+			entries = append(entries, entry)
+			carried = entry.Available
+			continue
+		}
+
+		var spent float64
+		if err := c.db.QueryRow(
+			"SELECT COALESCE(SUM(amount), 0) FROM spend_lines WHERE billing_cycle = ? AND category = ?",
+			cycle, cat.Name,
+		).Scan(&spent); err != nil {
+			return nil, fmt.Errorf("failed to sum spend for %s: %w", cat.Name, err)
+		}
+
+		var budget float64
+		cycleBudget, err := c.BudgetFor(cat.ID, cycle)
+		if err != nil {
+			return nil, err
+		}
+		if cycleBudget != nil {
+			budget = *cycleBudget
+		}
+		entry := CarryoverEntry{
+			Cycle:      cycle,
+			CategoryID: cat.ID,
+			Category:   cat.Name,
+			Budget:     budget,
+			CarriedIn:  carried,
+			Spent:      spent,
+			Available:  budget + carried - spent,
+		}
+		entries = append(entries, entry)
+		carried = entry.Available
+	}
+	return entries, nil
+}
+
+// closingCarryover computes every carry-over category's entry for a cycle
+// about to be closed, for recordCarryover to store with the closure.
+func (c *DatabaseClient) closingCarryover(cycle string) ([]CarryoverEntry, error) {
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	var entries []CarryoverEntry
+	for _, cat := range cats {
+		entry, err := c.carryoverFor(cat, cycle)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, nil
+}
+
+// recordCarryover stores ledger entries inside an existing SQL transaction.
+func recordCarryover(tx *sql.Tx, entries []CarryoverEntry) error {
+	now := time.Now().Format(time.RFC3339)
+	for _, e := range entries {
+		if _, err := tx.Exec(
+			`INSERT INTO carryover_ledger (cycle, category_id, budget, carried_in, spent, available, computed_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(cycle, category_id) DO UPDATE SET budget = excluded.budget, carried_in = excluded.carried_in,
+			   spent = excluded.spent, available = excluded.available, computed_at = excluded.computed_at`,
+			e.Cycle, e.CategoryID, e.Budget, e.CarriedIn, e.Spent, e.Available, now,
+		); err != nil {
+			return fmt.Errorf("failed to record carryover: %w", err)
+		}
+	}
+	return nil
+}
+
+// carryoverFor returns the ledger entry for one cycle, or nil when the
+// category has no carry-over or the cycle predates its chain.
+func (c *DatabaseClient) carryoverFor(cat Category, cycle string) (*CarryoverEntry, error) {
+	entries, err := c.GetCarryoverLedger(cat, cycle)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 || entries[len(entries)-1].Cycle != cycle {
+		return nil, nil
+	}
+	return &entries[len(entries)-1], nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"testing"
+)
+
+func categoryByName(t *testing.T, db *DatabaseClient, name string) Category {
+	t.Helper()
+	cats, err := db.GetAllCategories()
+	if err != nil {
+		t.Fatalf("GetAllCategories failed: %v", err)
+	}
+	for _, c := range cats {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("category %s not found", name)
+	return Category{}
+}
+
+func TestCarryover_RollsUnspentAndOverspentBudget(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries") // budget 2000
+	if err := db.SetCarryover(groc.ID, true); err != nil {
+		t.Fatalf("SetCarryover failed: %v", err)
+	}
+	if err := db.ResetCarryover(groc.ID, "Jan 2026"); err != nil {
+		t.Fatalf("ResetCarryover failed: %v", err)
+	}
+
+	for _, tx := range []Transaction{
+		{Description: "Carrefour Jan", Amount: 1500, Date: "2026-02-01", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"},
+		{Description: "Carrefour Feb", Amount: 2800, Date: "2026-03-01", Category: "Groceries", BillingCycle: "Feb 2026", Timestamp: "2026-03-01T10:00:00Z", Source: "openai"},
+	} {
+		insertTestTransaction(t, db, tx)
+	}
+
+	ledger, err := db.GetCarryoverLedger(categoryByName(t, db, "Groceries"), "Mar 2026")
+	if err != nil {
+		t.Fatalf("GetCarryoverLedger failed: %v", err)
+	}
+	if len(ledger) != 3 {
+		t.Fatalf("expected 3 ledger entries, got %d", len(ledger))
+	}
+	// Jan: 2000 − 1500 = 500 left → Feb: 2000 + 500 − 2800 = −300 → Mar: 2000 − 300 = 1700.
+	if ledger[0].Available != 500 {
+		t.Errorf("Jan available: expected 500, got %.2f", ledger[0].Available)
+	}
+	if ledger[1].CarriedIn != 500 || ledger[1].Available != -300 {
+		t.Errorf("Feb: expected carriedIn 500 / available -300, got %+v", ledger[1])
+	}
+	if ledger[2].CarriedIn != -300 || ledger[2].Available != 1700 {
+		t.Errorf("Mar: expected carriedIn -300 / available 1700, got %+v", ledger[2])
+	}
+
+	var rows int
+	db.db.QueryRow("SELECT COUNT(*) FROM carryover_ledger WHERE category_id = ?", groc.ID).Scan(&rows)
+	if rows != 0 {
+		t.Errorf("expected reads to store nothing, got %d ledger rows", rows)
+	}
+}
+
+func TestCarryover_RecordedOnCloseOnly(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries") // budget 2000
+	db.SetCarryover(groc.ID, true)
+	db.ResetCarryover(groc.ID, "Jan 2026")
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour Jan", Amount: 1500, Date: "2026-02-01", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"})
+
+	if _, err := db.CloseCycle("Jan 2026"); err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+	var rows int
+	db.db.QueryRow("SELECT COUNT(*) FROM carryover_ledger WHERE category_id = ? AND cycle = 'Jan 2026'", groc.ID).Scan(&rows)
+	if rows != 1 {
+		t.Fatalf("expected the closed cycle recorded, got %d rows", rows)
+	}
+
+	// A reset drops open rows from its cycle on and keeps closed ones.
+	for _, cycle := range []string{"Dec 2025", "Mar 2026"} {
+		db.db.Exec("INSERT INTO carryover_ledger (cycle, category_id, budget, carried_in, spent, available, computed_at) VALUES (?, ?, 2000, 0, 0, 2000, '')", cycle, groc.ID)
+	}
+	if err := db.ResetCarryover(groc.ID, "Jan 2026"); err != nil {
+		t.Fatalf("ResetCarryover failed: %v", err)
+	}
+	var kept []string
+	ledgerRows, _ := db.db.Query("SELECT cycle FROM carryover_ledger WHERE category_id = ? ORDER BY cycle", groc.ID)
+	for ledgerRows.Next() {
+		var cycle string
+		ledgerRows.Scan(&cycle)
+		kept = append(kept, cycle)
+	}
+	ledgerRows.Close()
+	if len(kept) != 2 || kept[0] != "Dec 2025" || kept[1] != "Jan 2026" {
+		t.Fatalf("expected Dec 2025 and the closed Jan 2026 to be kept, got %v", kept)
+	}
+
+	// A budget change after the close leaves the closed cycle's figures alone.
+	if err := db.SetCategoryBudget(groc.ID, "Jan 2026", floatPtr(3000), true); err != nil {
+		t.Fatalf("SetCategoryBudget failed: %v", err)
+	}
+	entry, err := db.carryoverFor(categoryByName(t, db, "Groceries"), "Feb 2026")
+	if err != nil {
+		t.Fatalf("carryoverFor failed: %v", err)
+	}
+	if entry == nil || entry.CarriedIn != 500 {
+		t.Errorf("expected Feb to carry the closed 500, got %+v", entry)
+	}
+
+	if err := db.ReopenCycle("Jan 2026", "budget fix"); err != nil {
+		t.Fatalf("ReopenCycle failed: %v", err)
+	}
+	entry, _ = db.carryoverFor(categoryByName(t, db, "Groceries"), "Feb 2026")
+	if entry == nil || entry.CarriedIn != 1500 {
+		t.Errorf("expected the reopened cycle to be live again, got %+v", entry)
+	}
+
+	if err := db.ResetCarryover(groc.ID, "Smarch 2026"); err == nil || err.Error() != "invalid cycle" {
+		t.Errorf("expected invalid cycle, got %v", err)
+	}
+}
+
+func TestCarryover_GetStatsShowsAvailable(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries")
+	db.SetCarryover(groc.ID, true)
+	db.ResetCarryover(groc.ID, "Jan 2026")
+
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour Jan", Amount: 1500, Date: "2026-02-01", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"})
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour Feb", Amount: 400, Date: "2026-03-01", Category: "Groceries", BillingCycle: "Feb 2026", Timestamp: "2026-03-01T10:00:00Z", Source: "openai"})
+
+	stats, err := db.GetStats("Feb 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	var found bool
+	for _, cat := range stats.Categories {
+		if cat.Category != "Groceries" {
+			continue
+		}
+		found = true
+		if cat.Carried != 500 {
+			t.Errorf("expected carried 500, got %.2f", cat.Carried)
+		}
+		if cat.Available == nil || *cat.Available != 2100 {
+			t.Errorf("expected available 2100, got %v", cat.Available)
+		}
+	}
+	if !found {
+		t.Fatal("Groceries missing from stats categories")
+	}
+	if len(stats.Envelopes) != 1 {
+		t.Errorf("expected 1 envelope, got %d", len(stats.Envelopes))
+	}
+}
+
+func TestCarryover_ResetAndAllocatedRejected(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries")
+	db.SetCarryover(groc.ID, true)
+	db.ResetCarryover(groc.ID, "Jan 2026")
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour Jan", Amount: 500, Date: "2026-02-01", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"})
+
+	if err := db.ResetCarryover(groc.ID, "Feb 2026"); err != nil {
+		t.Fatalf("ResetCarryover failed: %v", err)
+	}
+	entry, err := db.carryoverFor(categoryByName(t, db, "Groceries"), "Feb 2026")
+	if err != nil {
+		t.Fatalf("carryoverFor failed: %v", err)
+	}
+	if entry == nil || entry.CarriedIn != 0 {
+		t.Errorf("expected reset chain to start at zero, got %+v", entry)
+	}
+
+	rent := categoryByName(t, db, "Rent")
+	if err := db.SetCarryover(rent.ID, true); err == nil {
+		t.Error("expected error enabling carry-over on an allocated category")
+	}
+}
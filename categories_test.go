@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -59,10 +60,13 @@ func TestUpdateCategory_CascadesRename(t *testing.T) {
 	if err != nil {
 		t.Fatalf("SaveTransaction failed: %v", err)
 	}
-	_, _, _, err = db.CreateRule("carrefour", "Groceries", 10)
+	_, _, _, err = db.CreateRule("carrefour", MatchContains, "Groceries", 10)
 	if err != nil {
 		t.Fatalf("CreateRule failed: %v", err)
 	}
+	if _, err := db.CreateAlertRule(AlertRule{Scope: "category", Target: "Groceries", Kind: "percent", Threshold: 80}); err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
 
 	// Find Groceries category ID
 	cats, _ := db.GetAllCategories()
@@ -87,9 +91,16 @@ func TestUpdateCategory_CascadesRename(t *testing.T) {
 	if rule == nil || rule.Category != "Food" {
 		t.Errorf("expected merchant rule category to be Food, got %v", rule)
 	}
+	alertRules, err := db.GetAlertRules()
+	if err != nil {
+		t.Fatalf("GetAlertRules failed: %v", err)
+	}
+	if len(alertRules) != 1 || alertRules[0].Target != "Food" {
+		t.Errorf("expected alert rule target to be Food, got %+v", alertRules)
+	}
 }
 
-func TestDeleteCategory_AllowedWithTransactions(t *testing.T) {
+func TestDeleteCategory_BlockedWhenReferenced(t *testing.T) {
 	db := setupTestDB(t)
 
 	_, err := db.SaveTransaction(Transaction{
@@ -115,8 +126,9 @@ func TestDeleteCategory_AllowedWithTransactions(t *testing.T) {
 		}
 	}
 
-	if err = db.DeleteCategory(shopID); err != nil {
-		t.Errorf("expected deletion to succeed even with transactions, got: %v", err)
+	err = db.DeleteCategory(shopID)
+	if err == nil || !strings.HasPrefix(err.Error(), "cannot delete:") || !strings.Contains(err.Error(), "1 transaction") {
+		t.Errorf("expected deletion to be blocked by the transaction, got: %v", err)
 	}
 }
 
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Categories form a two-level tree: a top-level category can have
+// sub-categories (parent_id), but a sub-category can't have its own.
+// Transactions and merchant rules still name exactly one category, so a
+// child keeps its own spend; GetStats rolls that spend up into the parent's
+// row. A child always sits in its parent's bucket — type, tracking and
+// exclude_from_totals follow the parent — and its budget counts toward the
+// bucket only while the parent has none of its own.
+
+func (c *DatabaseClient) migrateCategoryTree() error {
+	if err := c.addColumnIfNotExists("categories", "parent_id INTEGER"); err != nil {
+		return fmt.Errorf("failed to add parent_id column: %w", err)
+	}
+	return nil
+}
+
+// SetCategoryParent moves a category under parentID, or back to the top
+// level when parentID is nil. The child takes on the parent's type,
+// tracking and exclusion (dropping carry-over if it becomes allocated).
+func (c *DatabaseClient) SetCategoryParent(id int64, parentID *int64) error {
+	return setCategoryParent(c.db, id, parentID)
+}
+
+func setCategoryParent(q querier, id int64, parentID *int64) error {
+	var hasChildren bool
+	err := q.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = ?) FROM categories WHERE id = ?", id, id,
+	).Scan(&hasChildren)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch category: %w", err)
+	}
+
+	if parentID == nil {
+		if _, err := q.Exec("UPDATE categories SET parent_id = NULL WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to clear parent: %w", err)
+		}
+		return nil
+	}
+
+	if *parentID == id {
+		return fmt.Errorf("invalid parent: a category can't be its own parent")
+	}
+	if hasChildren {
+		return fmt.Errorf("invalid parent: category has sub-categories")
+	}
+	catType, tracking, excl, err := parentCategory(q, *parentID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.Exec(`
+		UPDATE categories
+		SET parent_id = ?, type = ?, tracking = ?, exclude_from_totals = ?,
+			carryover = CASE WHEN ? = 'allocated' THEN 0 ELSE carryover END
+		WHERE id = ?`,
+		*parentID, catType, tracking, excl, tracking, id,
+	); err != nil {
+		return fmt.Errorf("failed to set parent: %w", err)
+	}
+	return nil
+}
+
+// CheckCategoryParent checks that a category can take sub-categories.
+func (c *DatabaseClient) CheckCategoryParent(id int64) error {
+	_, _, _, err := parentCategory(c.db, id)
+	return err
+}
+
+// parentCategory checks that a category can take sub-categories, returning
+// the type, tracking and exclusion they inherit from it.
+func parentCategory(q querier, id int64) (string, string, int, error) {
+	var grandparent sql.NullInt64
+	var catType, tracking string
+	var excl int
+	err := q.QueryRow(
+		"SELECT parent_id, type, tracking, exclude_from_totals FROM categories WHERE id = ?", id,
+	).Scan(&grandparent, &catType, &tracking, &excl)
+	if err == sql.ErrNoRows {
+		return "", "", 0, fmt.Errorf("invalid parent: parent category not found")
+	}
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to fetch parent category: %w", err)
+	}
+	if grandparent.Valid {
+		return "", "", 0, fmt.Errorf("invalid parent: sub-categories can't have sub-categories")
+	}
+	return catType, tracking, excl, nil
+}
+
+// inheritFromParent returns the type, tracking and exclusion a category
+// must use: its parent's when it has one, otherwise the values given.
+func inheritFromParent(q querier, id int64, catType, tracking string, excl int) (string, string, int, error) {
+	err := q.QueryRow(`
+		SELECT p.type, p.tracking, p.exclude_from_totals
+		FROM categories c JOIN categories p ON p.id = c.parent_id
+		WHERE c.id = ?`, id,
+	).Scan(&catType, &tracking, &excl)
+	if err != nil && err != sql.ErrNoRows {
+		return "", "", 0, fmt.Errorf("failed to fetch parent category: %w", err)
+	}
+	return catType, tracking, excl, nil
+}
+
+// shadowedBudgets returns the sub-categories whose budget doesn't count
+// toward bucket totals because their parent has a budget of its own in the
+// cycle budgets describes.
+func shadowedBudgets(cats []Category, budgets map[int64]*float64) map[int64]bool {
+	shadowed := make(map[int64]bool)
+	for _, cat := range cats {
+		if cat.ParentID != nil && budgets[*cat.ParentID] != nil {
+			shadowed[cat.ID] = true
+		}
+	}
+	return shadowed
+}
+
+// lineBudget is what a top-level category is measured against: its own
+// budget, or the sum of its children's when it has none. cats carry the
+// cycle's budgets.
+func lineBudget(parent Category, cats []Category) *float64 {
+	if parent.BudgetAmount != nil {
+		return parent.BudgetAmount
+	}
+	var sum *float64
+	for _, cat := range cats {
+		if cat.ParentID != nil && *cat.ParentID == parent.ID && cat.BudgetAmount != nil {
+			if sum == nil {
+				sum = floatPtr(0)
+			}
+			*sum += *cat.BudgetAmount
+		}
+	}
+	return sum
+}
+
+// rollupCategoryStats nests sub-category rows under their parent's row,
+// adding their spend to the parent's total. A parent with no transactions
+// of its own still gets a row when a child has some.
+func rollupCategoryStats(rows []CategoryStats, cats []Category, envelopes map[string]CarryoverEntry) []CategoryStats {
+	byID := make(map[int64]Category, len(cats))
+	for _, cat := range cats {
+		byID[cat.ID] = cat
+	}
+	parentOf := make(map[string]Category)
+	isParent := make(map[string]bool)
+	for _, cat := range cats {
+		if cat.ParentID != nil {
+			if parent, ok := byID[*cat.ParentID]; ok {
+				parentOf[cat.Name] = parent
+				isParent[parent.Name] = true
+			}
+		}
+	}
+	if len(parentOf) == 0 {
+		return rows
+	}
+
+	var top []CategoryStats
+	index := make(map[string]int)
+	for _, row := range rows {
+		if _, ok := parentOf[row.Category]; !ok {
+			index[row.Category] = len(top)
+			top = append(top, row)
+		}
+	}
+	for _, row := range rows {
+		parent, ok := parentOf[row.Category]
+		if !ok {
+			continue
+		}
+		i, ok := index[parent.Name]
+		if !ok {
+			emoji := parent.Emoji
+			if emoji == "" {
+				emoji = "📌"
+			}
+			i = len(top)
+			index[parent.Name] = i
+			top = append(top, CategoryStats{Category: parent.Name, Emoji: emoji})
+		}
+		top[i].Total += row.Total
+		top[i].Count += row.Count
+		top[i].Children = append(top[i].Children, row)
+	}
+
+	// Re-measure parents against the whole line: their own budget or their
+	// children's combined.
+	byName := make(map[string]Category, len(cats))
+	for _, cat := range cats {
+		byName[cat.Name] = cat
+	}
+	for i := range top {
+		if !isParent[top[i].Category] {
+			continue
+		}
+		top[i].Budget, top[i].Carried, top[i].Available = nil, 0, nil
+		if budget := lineBudget(byName[top[i].Category], cats); budget != nil {
+			top[i].Budget = budget
+			top[i].Carried = envelopes[top[i].Category].CarriedIn
+			top[i].Available = floatPtr(*budget + top[i].Carried - top[i].Total)
+		}
+	}
+	return top
+}
+
+// flatCategoryStats lists every row of a rolled-up breakdown, parents
+// (with their rolled-up totals) and children alike, for lookups by name.
+func flatCategoryStats(rows []CategoryStats) []CategoryStats {
+	flat := make([]CategoryStats, 0, len(rows))
+	for _, row := range rows {
+		flat = append(flat, row)
+		flat = append(flat, row.Children...)
+	}
+	return flat
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func statsRow(t *testing.T, rows []CategoryStats, name string) CategoryStats {
+	t.Helper()
+	for _, row := range rows {
+		if row.Category == name {
+			return row
+		}
+	}
+	t.Fatalf("row %s not in stats", name)
+	return CategoryStats{}
+}
+
+func TestCategoryTree_SetParent(t *testing.T) {
+	db := setupTestDB(t)
+	groceries := categoryByName(t, db, "Groceries")
+	rent := categoryByName(t, db, "Rent")
+	butcher, err := db.CreateCategory("Butcher", "🥩", false, "fixed", nil, "allocated")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+
+	if err := db.SetCategoryParent(butcher.ID, &groceries.ID); err != nil {
+		t.Fatalf("SetCategoryParent failed: %v", err)
+	}
+	got := categoryByName(t, db, "Butcher")
+	if got.ParentID == nil || *got.ParentID != groceries.ID {
+		t.Fatalf("expected Butcher under Groceries, got %v", got.ParentID)
+	}
+	if got.Type != "wants" || got.Tracking != "actual" {
+		t.Errorf("expected Butcher to take Groceries' bucket, got %s/%s", got.Type, got.Tracking)
+	}
+
+	for name, tc := range map[string]struct{ id, parent int64 }{
+		"own parent":       {butcher.ID, butcher.ID},
+		"nested":           {rent.ID, butcher.ID},
+		"unknown parent":   {rent.ID, 9999},
+		"parent has child": {groceries.ID, rent.ID},
+	} {
+		if err := db.SetCategoryParent(tc.id, &tc.parent); err == nil || !strings.HasPrefix(err.Error(), "invalid parent") {
+			t.Errorf("%s: expected an invalid parent error, got %v", name, err)
+		}
+	}
+
+	// The child follows later bucket moves of its parent, and can't leave it
+	// on its own.
+	if err := db.UpdateCategory(groceries.ID, "Food shopping", groceries.Emoji, false, "fixed", groceries.BudgetAmount, "actual"); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+	if got := categoryByName(t, db, "Butcher"); got.Type != "fixed" {
+		t.Errorf("expected Butcher to follow its parent to fixed, got %s", got.Type)
+	}
+	if err := db.UpdateCategory(butcher.ID, "Butcher", "🥩", false, "wants", nil, "actual"); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+	if got := categoryByName(t, db, "Butcher"); got.Type != "fixed" {
+		t.Errorf("expected Butcher to stay in its parent's bucket, got %s", got.Type)
+	}
+
+	if err := db.DeleteCategory(groceries.ID); err == nil || !strings.HasPrefix(err.Error(), "cannot delete:") {
+		t.Errorf("expected deleting a parent to be refused, got %v", err)
+	}
+	if err := db.SetCategoryParent(butcher.ID, nil); err != nil {
+		t.Fatalf("SetCategoryParent(nil) failed: %v", err)
+	}
+	if got := categoryByName(t, db, "Butcher"); got.ParentID != nil {
+		t.Error("expected Butcher back at the top level")
+	}
+}
+
+func TestCategoryTree_CreateWithBadParentLeavesNothing(t *testing.T) {
+	db := setupTestDB(t)
+	groc := categoryByName(t, db, "Groceries")
+	child, err := db.CreateCategory("Produce", "🥦", false, "wants", nil, "actual")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	if err := db.SetCategoryParent(child.ID, &groc.ID); err != nil {
+		t.Fatalf("SetCategoryParent failed: %v", err)
+	}
+
+	for _, parent := range []int64{child.ID, 9999} {
+		body := fmt.Sprintf(`{"name": "Bakery", "parentId": %d}`, parent)
+		w := httptest.NewRecorder()
+		categoriesHandler(db)(w, httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("parent %d: expected 400, got %d: %s", parent, w.Code, w.Body.String())
+		}
+	}
+	cats, _ := db.GetAllCategories()
+	for _, cat := range cats {
+		if cat.Name == "Bakery" {
+			t.Fatalf("expected no category created, found %+v", cat)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"name": "Bakery", "parentId": %d}`, groc.ID)
+	categoriesHandler(db)(w, httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(body)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), fmt.Sprintf(`"parentId":%d`, groc.ID)) {
+		t.Errorf("expected the sub-category created, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCategoryTree_StatsRollUp(t *testing.T) {
+	db := setupTestDB(t)
+	cycle := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	date := time.Now().Format("2006-01-02")
+	before, err := db.GetStats(cycle)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+
+	// Budget on the parent: the child's own budget doesn't add to the bucket.
+	groceries := categoryByName(t, db, "Groceries")
+	butcher, _ := db.CreateCategory("Butcher", "🥩", false, "wants", floatPtr(500), "actual")
+	db.SetCategoryParent(butcher.ID, &groceries.ID)
+
+	// Budgets on the children: the parent is measured against their sum.
+	pets, _ := db.CreateCategory("Pets", "🐾", false, "wants", nil, "actual")
+	vet, _ := db.CreateCategory("Vet", "🩺", false, "wants", floatPtr(400), "actual")
+	food, _ := db.CreateCategory("Pet food", "🦴", false, "wants", floatPtr(100), "actual")
+	db.SetCategoryParent(vet.ID, &pets.ID)
+	db.SetCategoryParent(food.ID, &pets.ID)
+
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour", Amount: 300, Date: date, Category: "Groceries", BillingCycle: cycle, Timestamp: date + "T10:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "Al Mawashi", Amount: 200, Date: date, Category: "Butcher", BillingCycle: cycle, Timestamp: date + "T11:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "Vet clinic", Amount: 450, Date: date, Category: "Vet", BillingCycle: cycle, Timestamp: date + "T12:00:00Z", Source: "manual"})
+
+	stats, err := db.GetStats(cycle)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.WantsBudget != before.WantsBudget+500 {
+		t.Errorf("expected wants budget to grow by the Pets children only, got %v → %v", before.WantsBudget, stats.WantsBudget)
+	}
+	if stats.WantsTotal != before.WantsTotal+950 {
+		t.Errorf("expected all child spend in the wants total, got %v", stats.WantsTotal)
+	}
+
+	row := statsRow(t, stats.Categories, "Groceries")
+	if row.Total != 500 || row.Count != 2 || len(row.Children) != 1 || row.Children[0].Category != "Butcher" {
+		t.Errorf("expected Butcher rolled into Groceries, got %+v", row)
+	}
+	if *row.Available != 1500 {
+		t.Errorf("expected 1500 left on Groceries, got %v", *row.Available)
+	}
+
+	row = statsRow(t, stats.Categories, "Pets")
+	if row.Total != 450 || *row.Budget != 500 || *row.Available != 50 {
+		t.Errorf("expected Pets measured against its children's 500, got %+v", row)
+	}
+	for _, r := range stats.Categories {
+		if r.Category == "Butcher" || r.Category == "Vet" {
+			t.Errorf("expected %s only as a child row", r.Category)
+		}
+	}
+}
+
+func TestCategoryTree_PromptAndRules(t *testing.T) {
+	db := setupTestDB(t)
+	dining := categoryByName(t, db, "Dining Out & Delivery")
+	coffee, _ := db.CreateCategory("Coffee", "☕", false, "wants", nil, "actual")
+	db.SetCategoryParent(coffee.ID, &dining.ID)
+	if _, _, _, err := db.CreateRule("tim hortons", MatchContains, "Coffee", 10); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+
+	cats, _ := db.GetAllCategories()
+	prompt := BuildSystemPrompt(cats)
+	if !strings.Contains(prompt, `"Coffee" (under "Dining Out & Delivery")`) {
+		t.Error("expected the prompt to list Coffee under its parent")
+	}
+
+	rules, _ := db.GetAllRules()
+	for _, rule := range rules {
+		if rule.Category == "Coffee" && rule.ParentCategory != "Dining Out & Delivery" {
+			t.Errorf("expected the rule to show Coffee's parent, got %q", rule.ParentCategory)
+		}
+	}
+
+	// Renaming the parent keeps the child attached.
+	if err := db.UpdateCategory(dining.ID, "Eating out", dining.Emoji, false, dining.Type, dining.BudgetAmount, dining.Tracking); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+	if got := categoryByName(t, db, "Coffee"); got.ParentID == nil || *got.ParentID != dining.ID {
+		t.Error("expected Coffee to stay under the renamed parent")
+	}
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CycleSnapshot is the frozen copy of GetStats output taken when a billing
+// cycle is closed. Per-category transaction lists are not kept; only totals.
+type CycleSnapshot struct {
+	Cycle       string            `json:"cycle"`
+	Total       float64           `json:"total"`
+	Count       int               `json:"count"`
+	Categories  []CategoryStats   `json:"categories"`
+	Salary      float64           `json:"salary"`
+	FixedTotal  float64           `json:"fixed_total"`
+	WantsTotal  float64           `json:"wants_total"`
+	GoalsFunded float64           `json:"goals_funded"`
+	SalarySpent float64           `json:"salary_spent"`
+	FixedBudget float64           `json:"fixed_budget"`
+	WantsBudget float64           `json:"wants_budget"`
+	GoalsBudget float64           `json:"goals_budget"`
+	Funding     map[int64]float64 `json:"funding"`
+}
+
+// CycleClosure is one close/reopen record for a billing cycle. A cycle is
+// locked while its latest closure has no ReopenedAt.
+type CycleClosure struct {
+	ID           int64          `json:"id"`
+	Cycle        string         `json:"cycle"`
+	ClosedAt     string         `json:"closedAt"`
+	ReopenedAt   *string        `json:"reopenedAt,omitempty"`
+	ReopenReason string         `json:"reopenReason,omitempty"`
+	Snapshot     *CycleSnapshot `json:"snapshot,omitempty"`
+}
+
+// CycleDiffLine compares one snapshot value against the live value.
+type CycleDiffLine struct {
+	Name     string  `json:"name"`
+	Snapshot float64 `json:"snapshot"`
+	Live     float64 `json:"live"`
+	Delta    float64 `json:"delta"`
+}
+
+type CycleDiff struct {
+	Cycle      string          `json:"cycle"`
+	ClosedAt   string          `json:"closedAt"`
+	Totals     []CycleDiffLine `json:"totals"`
+	Categories []CycleDiffLine `json:"categories"`
+	Changed    bool            `json:"changed"`
+}
+
+func (c *DatabaseClient) migrateCycleClosures() error {
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS cycle_closures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cycle TEXT NOT NULL,
+			snapshot TEXT NOT NULL,
+			closed_at TEXT NOT NULL,
+			reopened_at TEXT,
+			reopen_reason TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cycle_closures_cycle ON cycle_closures(cycle)`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("cycle_closures migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsCycleClosed reports whether a billing cycle is currently locked.
+func (c *DatabaseClient) IsCycleClosed(cycle string) (bool, error) {
+	var count int
+	err := c.db.QueryRow(
+		"SELECT COUNT(*) FROM cycle_closures WHERE cycle = ? AND reopened_at IS NULL", cycle,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check cycle lock: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ensureCycleOpen returns a "cycle closed:" error when writes to the cycle
+// are locked. Handlers map that prefix to 409 Conflict.
+func (c *DatabaseClient) ensureCycleOpen(cycle string) error {
+	closed, err := c.IsCycleClosed(cycle)
+	if err != nil {
+		return err
+	}
+	if closed {
+		return fmt.Errorf("cycle closed: %s", cycle)
+	}
+	return nil
+}
+
+// openCyclesClause filters a transactions query down to rows whose billing
+// cycle is not locked. Used by the retroactive rule paths.
+const openCyclesClause = "billing_cycle NOT IN (SELECT cycle FROM cycle_closures WHERE reopened_at IS NULL)"
+
+func snapshotFromStats(stats *StatsResponse, funding map[int64]float64) *CycleSnapshot {
+	cats := make([]CategoryStats, len(stats.Categories))
+	for i, cat := range stats.Categories {
+		cats[i] = CategoryStats{Category: cat.Category, Emoji: cat.Emoji, Total: cat.Total, Count: cat.Count}
+	}
+	return &CycleSnapshot{
+		Cycle:       stats.Cycle,
+		Total:       stats.Total,
+		Count:       stats.Count,
+		Categories:  cats,
+		Salary:      stats.Salary,
+		FixedTotal:  stats.FixedTotal,
+		WantsTotal:  stats.WantsTotal,
+		GoalsFunded: stats.GoalsFunded,
+		SalarySpent: stats.SalarySpent,
+		FixedBudget: stats.FixedBudget,
+		WantsBudget: stats.WantsBudget,
+		GoalsBudget: stats.GoalsBudget,
+		Funding:     funding,
+	}
+}
+
+// liveSnapshot computes the snapshot GetStats would produce right now.
+func (c *DatabaseClient) liveSnapshot(cycle string) (*CycleSnapshot, error) {
+	stats, err := c.GetStats(cycle)
+	if err != nil {
+		return nil, err
+	}
+	funding, err := c.GetFunding(cycle)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotFromStats(stats, funding), nil
+}
+
+// CloseCycle snapshots a cycle's stats and locks its transactions.
+func (c *DatabaseClient) CloseCycle(cycle string) (*CycleClosure, error) {
+	closed, err := c.IsCycleClosed(cycle)
+	if err != nil {
+		return nil, err
+	}
+	if closed {
+		return nil, fmt.Errorf("cycle already closed")
+	}
+
+	snap, err := c.liveSnapshot(cycle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot cycle: %w", err)
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	carryover, err := c.closingCarryover(cycle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute carryover: %w", err)
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+	result, err := tx.Exec(
+		"INSERT INTO cycle_closures (cycle, snapshot, closed_at) VALUES (?, ?, ?)",
+		cycle, string(data), now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close cycle: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	// The envelope balances are frozen with the cycle.
+	if err := recordCarryover(tx, carryover); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("[Database] Closed cycle %s (total %.2f AED)", cycle, snap.Total)
+	return &CycleClosure{ID: id, Cycle: cycle, ClosedAt: now, Snapshot: snap}, nil
+}
+
+// ReopenCycle unlocks a closed cycle. The closure row (and its snapshot) is
+// kept with the reason so the history of reopens stays auditable.
+func (c *DatabaseClient) ReopenCycle(cycle, reason string) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE cycle_closures SET reopened_at = ?, reopen_reason = ? WHERE cycle = ? AND reopened_at IS NULL",
+		time.Now().Format(time.RFC3339), reason, cycle,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reopen cycle: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("cycle not closed")
+	}
+	// Its envelope balances are live again until it is closed once more.
+	if _, err := tx.Exec("DELETE FROM carryover_ledger WHERE cycle = ?", cycle); err != nil {
+		return fmt.Errorf("failed to clear carryover ledger: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	log.Printf("[Database] Reopened cycle %s: %s", cycle, reason)
+	return nil
+}
+
+// GetCycleClosures returns the close/reopen history for a cycle, newest first.
+func (c *DatabaseClient) GetCycleClosures(cycle string) ([]CycleClosure, error) {
+	rows, err := c.db.Query(
+		"SELECT id, cycle, snapshot, closed_at, reopened_at, reopen_reason FROM cycle_closures WHERE cycle = ? ORDER BY id DESC",
+		cycle,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cycle closures: %w", err)
+	}
+	defer rows.Close()
+
+	var closures []CycleClosure
+	for rows.Next() {
+		var cl CycleClosure
+		var data string
+		var reopenedAt sql.NullString
+		if err := rows.Scan(&cl.ID, &cl.Cycle, &data, &cl.ClosedAt, &reopenedAt, &cl.ReopenReason); err != nil {
+			return nil, fmt.Errorf("failed to scan cycle closure: %w", err)
+		}
+		if reopenedAt.Valid {
+			cl.ReopenedAt = &reopenedAt.String
+		}
+		var snap CycleSnapshot
+		if err := json.Unmarshal([]byte(data), &snap); err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+		}
+		cl.Snapshot = &snap
+		closures = append(closures, cl)
+	}
+	return closures, rows.Err()
+}
+
+// DiffCycle compares the latest snapshot of a cycle against live data.
+func (c *DatabaseClient) DiffCycle(cycle string) (*CycleDiff, error) {
+	closures, err := c.GetCycleClosures(cycle)
+	if err != nil {
+		return nil, err
+	}
+	if len(closures) == 0 {
+		return nil, fmt.Errorf("cycle has no snapshot")
+	}
+	snap := closures[0].Snapshot
+
+	live, err := c.liveSnapshot(cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &CycleDiff{Cycle: cycle, ClosedAt: closures[0].ClosedAt}
+	add := func(lines *[]CycleDiffLine, name string, before, after float64) {
+		line := CycleDiffLine{Name: name, Snapshot: before, Live: after, Delta: after - before}
+		if line.Delta > 0.005 || line.Delta < -0.005 {
+			diff.Changed = true
+		}
+		*lines = append(*lines, line)
+	}
+
+	add(&diff.Totals, "total", snap.Total, live.Total)
+	add(&diff.Totals, "count", float64(snap.Count), float64(live.Count))
+	add(&diff.Totals, "salary", snap.Salary, live.Salary)
+	add(&diff.Totals, "fixed_total", snap.FixedTotal, live.FixedTotal)
+	add(&diff.Totals, "wants_total", snap.WantsTotal, live.WantsTotal)
+	add(&diff.Totals, "goals_funded", snap.GoalsFunded, live.GoalsFunded)
+	add(&diff.Totals, "salary_spent", snap.SalarySpent, live.SalarySpent)
+	add(&diff.Totals, "fixed_budget", snap.FixedBudget, live.FixedBudget)
+	add(&diff.Totals, "wants_budget", snap.WantsBudget, live.WantsBudget)
+	add(&diff.Totals, "goals_budget", snap.GoalsBudget, live.GoalsBudget)
+
+	before := make(map[string]float64)
+	after := make(map[string]float64)
+	for _, cat := range snap.Categories {
+		before[cat.Category] = cat.Total
+	}
+	for _, cat := range live.Categories {
+		after[cat.Category] = cat.Total
+	}
+	var names []string
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(&diff.Categories, name, before[name], after[name])
+	}
+
+	return diff, nil
+}
+
+// cyclesHandler serves the close/reopen lifecycle of a billing cycle:
+//
+//	GET  /cycles/{cycle}         — lock status + closure history
+//	POST /cycles/{cycle}/close   — snapshot stats and lock writes
+//	POST /cycles/{cycle}/reopen  — unlock with {reason}
+//	GET  /cycles/{cycle}/diff    — latest snapshot vs live data
+func cyclesHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/cycles/")
+		cycle, action, _ := strings.Cut(rest, "/")
+		if _, err := time.Parse("Jan 2006", cycle); err != nil {
+			http.Error(w, "Invalid cycle", http.StatusBadRequest)
+			return
+		}
+
+		switch action {
+		case "":
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] GET /cycles/%s - Request from %s", cycle, r.RemoteAddr)
+			closed, err := db.IsCycleClosed(cycle)
+			if err != nil {
+				log.Printf("[API] Failed to check cycle lock: %v", err)
+				http.Error(w, "Failed to retrieve cycle", http.StatusInternalServerError)
+				return
+			}
+			closures, err := db.GetCycleClosures(cycle)
+			if err != nil {
+				log.Printf("[API] Failed to get cycle closures: %v", err)
+				http.Error(w, "Failed to retrieve cycle", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":  true,
+				"cycle":    cycle,
+				"closed":   closed,
+				"closures": closures,
+			})
+
+		case "close":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] POST /cycles/%s/close - Close cycle from %s", cycle, r.RemoteAddr)
+			closure, err := db.CloseCycle(cycle)
+			if err != nil {
+				log.Printf("[API] Failed to close cycle: %v", err)
+				if err.Error() == "cycle already closed" {
+					http.Error(w, "Cycle already closed", http.StatusConflict)
+				} else {
+					http.Error(w, "Failed to close cycle", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"closure": closure,
+			})
+
+		case "reopen":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var req struct {
+				Reason string `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if strings.TrimSpace(req.Reason) == "" {
+				http.Error(w, "Reason is required", http.StatusBadRequest)
+				return
+			}
+			log.Printf("[API] POST /cycles/%s/reopen - Reopen cycle from %s", cycle, r.RemoteAddr)
+			if err := db.ReopenCycle(cycle, req.Reason); err != nil {
+				log.Printf("[API] Failed to reopen cycle: %v", err)
+				if err.Error() == "cycle not closed" {
+					http.Error(w, "Cycle is not closed", http.StatusConflict)
+				} else {
+					http.Error(w, "Failed to reopen cycle", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		case "diff":
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] GET /cycles/%s/diff - Request from %s", cycle, r.RemoteAddr)
+			diff, err := db.DiffCycle(cycle)
+			if err != nil {
+				log.Printf("[API] Failed to diff cycle: %v", err)
+				if err.Error() == "cycle has no snapshot" {
+					http.Error(w, "Cycle has no snapshot", http.StatusNotFound)
+				} else {
+					http.Error(w, "Failed to diff cycle", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"diff":    diff,
+			})
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// writeCycleClosed reports a write rejected because its billing cycle is
+// locked, mirroring the JSON 409 body used for blocked category deletes.
+func writeCycleClosed(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// cyclesBetween lists billing cycle keys from `from` to `to` inclusive, oldest
+// first. It returns nil when either key is malformed or from is after to.
+func cyclesBetween(from, to string) []string {
+	start, err := time.Parse("Jan 2006", from)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse("Jan 2006", to)
+	if err != nil {
+		return nil
+	}
+	var cycles []string
+	for s := start; !s.After(end); s = s.AddDate(0, 1, 0) {
+		cycles = append(cycles, s.Format("Jan 2006"))
+	}
+	return cycles
+}
+
+// shiftCycle moves a billing cycle key by n cycles (negative for earlier).
+func shiftCycle(cycle string, n int) string {
+	t, err := time.Parse("Jan 2006", cycle)
+	if err != nil {
+		return cycle
+	}
+	return t.AddDate(0, n, 0).Format("Jan 2006")
+}
+
+// cycleBounds returns the first and last day of a billing cycle: the 23rd of
+// its start month through the 22nd of the next.
+func cycleBounds(cycle string) (time.Time, time.Time, error) {
+	t, err := time.Parse("Jan 2006", cycle)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid cycle")
+	}
+	start := time.Date(t.Year(), t.Month(), 23, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	return start, end, nil
+}
+
+// cycleElapsed reports how far through a cycle the given day is, from 0
+// (before it starts) to 1 (on or after its last day), counting days inclusively.
+func cycleElapsed(cycle string, now time.Time) float64 {
+	start, end, err := cycleBounds(cycle)
+	if err != nil {
+		return 0
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(start) {
+		return 0
+	}
+	if !day.Before(end) {
+		return 1
+	}
+	total := end.Sub(start).Hours()/24 + 1
+	done := day.Sub(start).Hours()/24 + 1
+	return done / total
+}
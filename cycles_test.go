@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCloseCycle_LocksTransactionWrites(t *testing.T) {
+	db := setupTestDB(t)
+
+	id, err := db.SaveTransaction(Transaction{
+		Description:  "Carrefour",
+		Amount:       120,
+		Date:         "2026-02-10",
+		Category:     "Groceries",
+		Confidence:   90,
+		BillingCycle: "Jan 2026",
+		Timestamp:    "2026-02-10T10:00:00Z",
+		Source:       "openai",
+	})
+	if err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+
+	closure, err := db.CloseCycle("Jan 2026")
+	if err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+	if closure.Snapshot.Total != 120 {
+		t.Errorf("expected snapshot total 120, got %.2f", closure.Snapshot.Total)
+	}
+
+	if _, err := db.CloseCycle("Jan 2026"); err == nil {
+		t.Error("expected error closing an already closed cycle")
+	}
+
+	_, err = db.SaveTransaction(Transaction{
+		Description:  "Late import",
+		Amount:       10,
+		Date:         "2026-02-11",
+		Category:     "Groceries",
+		BillingCycle: "Jan 2026",
+		Timestamp:    "2026-02-11T10:00:00Z",
+		Source:       "openai",
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "cycle closed:") {
+		t.Errorf("expected cycle closed error on save, got %v", err)
+	}
+
+	err = db.UpdateTransaction(id, Transaction{Description: "Carrefour", Amount: 999, Date: "2026-02-10", Category: "Groceries", BillingCycle: "Jan 2026"})
+	if err == nil || !strings.HasPrefix(err.Error(), "cycle closed:") {
+		t.Errorf("expected cycle closed error on update, got %v", err)
+	}
+
+	// Moving the row out into an open cycle is still a write to the closed one.
+	err = db.UpdateTransaction(id, Transaction{Description: "Carrefour", Amount: 120, Date: "2026-03-10", Category: "Groceries", BillingCycle: "Feb 2026"})
+	if err == nil || !strings.HasPrefix(err.Error(), "cycle closed:") {
+		t.Errorf("expected cycle closed error moving out of closed cycle, got %v", err)
+	}
+
+	if err := db.DeleteTransaction(id); err == nil || !strings.HasPrefix(err.Error(), "cycle closed:") {
+		t.Errorf("expected cycle closed error on delete, got %v", err)
+	}
+
+	if err := db.ReopenCycle("Jan 2026", "late refund"); err != nil {
+		t.Fatalf("ReopenCycle failed: %v", err)
+	}
+	if err := db.DeleteTransaction(id); err != nil {
+		t.Errorf("expected delete to succeed after reopen, got %v", err)
+	}
+}
+
+func TestCloseCycle_RulesSkipClosedCycles(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+
+	insertTestTransaction(t, db, Transaction{
+		Description:  "Noon Order",
+		Amount:       80,
+		Date:         "2026-02-10",
+		Category:     "Groceries",
+		Confidence:   50,
+		BillingCycle: "Jan 2026",
+		Timestamp:    "2026-02-10T10:00:00Z",
+		Source:       "openai",
+	})
+	insertTestTransaction(t, db, Transaction{
+		Description:  "Noon Order 2",
+		Amount:       40,
+		Date:         "2026-03-10",
+		Category:     "Groceries",
+		Confidence:   50,
+		BillingCycle: "Feb 2026",
+		Timestamp:    "2026-03-10T10:00:00Z",
+		Source:       "openai",
+	})
+
+	if _, err := db.CloseCycle("Jan 2026"); err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+
+	rule, _, _, err := db.CreateRule("noon", MatchContains, "Shopping & Gifts", 10)
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	updated, _, err := db.ApplyRuleSingle(rule.ID)
+	if err != nil {
+		t.Fatalf("ApplyRuleSingle failed: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("expected only the open-cycle row to update, got %d", updated)
+	}
+
+	updated, _, err = db.ApplyAllRules()
+	if err != nil {
+		t.Fatalf("ApplyAllRules failed: %v", err)
+	}
+	// The open-cycle row is already filed; the closed one must be skipped.
+	if updated != 0 {
+		t.Errorf("expected ApplyAllRules to skip the closed cycle, got %d", updated)
+	}
+
+	var cat string
+	db.db.QueryRow("SELECT category FROM transactions WHERE description = 'Noon Order'").Scan(&cat)
+	if cat != "Groceries" {
+		t.Errorf("closed-cycle row should be untouched, got %s", cat)
+	}
+}
+
+func TestDiffCycle_ReportsLiveChanges(t *testing.T) {
+	db := setupTestDB(t)
+
+	insertTestTransaction(t, db, Transaction{
+		Description:  "Carrefour",
+		Amount:       100,
+		Date:         "2026-02-10",
+		Category:     "Groceries",
+		BillingCycle: "Jan 2026",
+		Timestamp:    "2026-02-10T10:00:00Z",
+		Source:       "openai",
+	})
+	if _, err := db.CloseCycle("Jan 2026"); err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+
+	diff, err := db.DiffCycle("Jan 2026")
+	if err != nil {
+		t.Fatalf("DiffCycle failed: %v", err)
+	}
+	if diff.Changed {
+		t.Error("expected no changes straight after close")
+	}
+
+	db.ReopenCycle("Jan 2026", "missed a receipt")
+	insertTestTransaction(t, db, Transaction{
+		Description:  "Spinneys",
+		Amount:       50,
+		Date:         "2026-02-12",
+		Category:     "Groceries",
+		BillingCycle: "Jan 2026",
+		Timestamp:    "2026-02-12T10:00:00Z",
+		Source:       "openai",
+	})
+
+	diff, err = db.DiffCycle("Jan 2026")
+	if err != nil {
+		t.Fatalf("DiffCycle failed: %v", err)
+	}
+	if !diff.Changed {
+		t.Error("expected diff to report a change")
+	}
+	if diff.Totals[0].Name != "total" || diff.Totals[0].Delta != 50 {
+		t.Errorf("expected total delta 50, got %+v", diff.Totals[0])
+	}
+}
+
+func TestCyclesHandler_ReopenRequiresReason(t *testing.T) {
+	db := setupTestDB(t)
+	handler := cyclesHandler(db)
+
+	req := httptest.NewRequest(http.MethodPost, "/cycles/Jan%202026/close", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 on close, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/cycles/Jan%202026/reopen", strings.NewReader(`{"reason":""}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without reason, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/cycles/Jan%202026/reopen", strings.NewReader(`{"reason":"refund posted late"}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 on reopen, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// parsedReply answers every OpenAI request with transactions as the parsed
+// content.
+type parsedReply []Transaction
+
+func (p parsedReply) RoundTrip(*http.Request) (*http.Response, error) {
+	content, _ := json.Marshal([]Transaction(p))
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": string(content)}}},
+	})
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body))), Header: make(http.Header)}, nil
+}
+
+func TestTransactionHandler_ReportsClosedCycle(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	if _, err := db.CloseCycle("Jan 2026"); err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+	post := func(reply parsedReply) *httptest.ResponseRecorder {
+		openAI := &OpenAIClient{client: &http.Client{Transport: reply}}
+		w := httptest.NewRecorder()
+		transactionHandler(openAI, db)(w, httptest.NewRequest(http.MethodPost, "/transaction", strings.NewReader(`{"text": "sms"}`)))
+		return w
+	}
+	jan := Transaction{Description: "Carrefour", Amount: 120, Date: "2026-02-10", Category: "Groceries", Confidence: 90}
+	feb := Transaction{Description: "Lulu", Amount: 80, Date: "2026-02-25", Category: "Groceries", Confidence: 90}
+
+	if w := post(parsedReply{jan}); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when every transaction is in a closed cycle, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := post(parsedReply{jan, feb})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 when some transactions were saved, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp TransactionResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Count != 1 || len(resp.Rejected) != 1 || resp.Rejected[0] != "Carrefour (Jan 2026)" {
+		t.Errorf("expected Feb saved and the Jan transaction reported, got %+v", resp)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -14,14 +16,38 @@ import (
 
 type DatabaseClient struct {
 	db *sql.DB
+
+	budgetTemplate string // template file a fresh database is seeded from, see runBudgetingSetup
+
+	alertQueue   chan string    // cycles to check for alerts, see alerts.go
+	alertPending sync.WaitGroup // queued checks not yet finished
+	alertDone    chan struct{}  // closed when the alert worker exits
+}
+
+// execer and querier are what the write helpers need from *sql.DB or
+// *sql.Tx, so they can run on their own or inside a caller's transaction.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+type querier interface {
+	execer
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 type MerchantRule struct {
-	ID        int64  `json:"id"`
-	Keyword   string `json:"keyword"`
-	Category  string `json:"category"`
-	Priority  int    `json:"priority"`
-	CreatedAt string `json:"createdAt"`
+	ID             int64          `json:"id"`
+	Keyword        string         `json:"keyword"`
+	MatchType      string         `json:"matchType"` // contains, prefix, exact, regex or word-boundary
+	Category       string         `json:"category"`
+	ParentCategory string         `json:"parentCategory,omitempty"` // set when Category is a sub-category
+	Tags           []string       `json:"tags,omitempty"`           // added to the transactions it matches at ingest
+	Conditions     RuleConditions `json:"conditions"`               // further conditions, ANDed with the keyword
+	Actions        []RuleAction   `json:"actions"`                  // applied in order after the category (see ruleactions.go)
+	Priority       int            `json:"priority"`
+	CreatedAt      string         `json:"createdAt"`
+	HitCount       int            `json:"hitCount"`                // times it has fired, at ingest or retroactively
+	LastMatchedAt  string         `json:"lastMatchedAt,omitempty"` // when it last fired
 }
 
 type Category struct {
@@ -36,11 +62,26 @@ type Category struct {
 	//   "actual"    — real transactions accumulate against the budget (default)
 	//   "allocated" — funded by a per-cycle tick-off ("set aside"), counted at budget
 	Tracking string `json:"tracking"`
+	// Carryover rolls unspent (or overspent) budget into the next cycle,
+	// starting from CarryoverStart. See carryover.go.
+	Carryover      bool   `json:"carryover"`
+	CarryoverStart string `json:"carryoverStart,omitempty"`
+	// ParentID is set on sub-categories. See categorytree.go.
+	ParentID *int64 `json:"parentId,omitempty"`
+	// Archived categories are hidden from pickers but still resolve for
+	// historical cycles. See archive.go.
+	Archived bool `json:"archived"`
 }
 
 func floatPtr(v float64) *float64 { return &v }
 
+// NewDatabaseClient opens the database, seeding a fresh one from the
+// BUDGET_TEMPLATE file when set.
 func NewDatabaseClient(dbPath string) (*DatabaseClient, error) {
+	return openDatabaseClient(dbPath, os.Getenv("BUDGET_TEMPLATE"))
+}
+
+func openDatabaseClient(dbPath, budgetTemplate string) (*DatabaseClient, error) {
 	log.Printf("[Database] Connecting to database at: %s", dbPath)
 
 	db, err := sql.Open("sqlite3", dbPath)
@@ -60,7 +101,7 @@ func NewDatabaseClient(dbPath string) (*DatabaseClient, error) {
 
 	log.Printf("[Database] Connection established successfully")
 
-	client := &DatabaseClient{db: db}
+	client := &DatabaseClient{db: db, budgetTemplate: budgetTemplate}
 
 	// Run migrations to create tables
 	log.Printf("[Database] Running migrations...")
@@ -69,6 +110,7 @@ func NewDatabaseClient(dbPath string) (*DatabaseClient, error) {
 	}
 	log.Printf("[Database] Migrations completed successfully")
 
+	client.startAlertWorker()
 	return client, nil
 }
 
@@ -133,6 +175,7 @@ func (c *DatabaseClient) runMigrations() error {
 	// cycle_funding records a "set aside" tick for an allocated category in a
 	// given billing cycle. One row = funded this cycle; amount snapshots the
 	// category's budget at tick time so later budget edits don't rewrite history.
+	// migrateFundingEntries later rebuilds it to hold several entries per cycle.
 	if _, err := c.db.Exec(`CREATE TABLE IF NOT EXISTS cycle_funding (
 		cycle TEXT NOT NULL,
 		category_id INTEGER NOT NULL,
@@ -151,6 +194,54 @@ func (c *DatabaseClient) runMigrations() error {
 		return fmt.Errorf("settings migration failed: %w", err)
 	}
 
+	if err := c.migrateCycleClosures(); err != nil {
+		return err
+	}
+
+	if err := c.migrateCarryover(); err != nil {
+		return err
+	}
+
+	if err := c.migrateBudgetVersions(); err != nil {
+		return err
+	}
+
+	if err := c.migrateIncome(); err != nil {
+		return err
+	}
+
+	if err := c.migrateAlerts(); err != nil {
+		return err
+	}
+
+	if err := c.migrateGoals(); err != nil {
+		return err
+	}
+
+	if err := c.migrateSinkingFunds(); err != nil {
+		return err
+	}
+
+	if err := c.migrateAutoFund(); err != nil {
+		return err
+	}
+
+	if err := c.migrateFundingEntries(); err != nil {
+		return err
+	}
+
+	if err := c.migrateCategoryTree(); err != nil {
+		return err
+	}
+
+	if err := c.migrateTags(); err != nil {
+		return err
+	}
+
+	if err := c.migrateCategoryArchive(); err != nil {
+		return err
+	}
+
 	if err := c.seedCategories(); err != nil {
 		return fmt.Errorf("failed to seed categories: %w", err)
 	}
@@ -172,6 +263,31 @@ func (c *DatabaseClient) runMigrations() error {
 		}
 	}
 
+	if err := c.migrateRuleMatchTypes(); err != nil {
+		return err
+	}
+
+	if err := c.migrateRuleConditions(); err != nil {
+		return err
+	}
+
+	if err := c.migrateRuleActions(); err != nil {
+		return err
+	}
+
+	if err := c.migrateRuleStats(); err != nil {
+		return err
+	}
+
+	if err := c.migrateRuleSuggestions(); err != nil {
+		return err
+	}
+
+	// After the rule migrations: the spend_lines view reads columns they add.
+	if err := c.migrateSplits(); err != nil {
+		return err
+	}
+
 	if err := c.seedMerchantRules(); err != nil {
 		return fmt.Errorf("failed to seed merchant rules: %w", err)
 	}
@@ -184,78 +300,39 @@ func (c *DatabaseClient) runMigrations() error {
 		return fmt.Errorf("failed to run budgeting setup: %w", err)
 	}
 
+	if err := c.seedBudgetVersions(); err != nil {
+		return err
+	}
+
+	if err := c.seedIncomeSources(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// runBudgetingSetup applies the salary-budget model (fixed / spending / goals,
+// runBudgetingSetup seeds the salary-budget model (fixed / spending / goals,
 // per-category tracking + targets) ONCE, guarded by a settings flag, so that
 // afterwards the user's bucket moves and budget edits persist across reboots.
-// It converges both fresh and existing databases onto the June 2026 budget.
+// The budget comes from the client's template file (BUDGET_TEMPLATE), or
+// the neutral defaultBudgetTemplate when there is none.
 func (c *DatabaseClient) runBudgetingSetup() error {
 	done, _ := c.GetSetting("budgeting_setup_v1")
 	if done == "1" {
 		return nil
 	}
 
-	// Canonical budget lines. tracking: "allocated" = set-aside tick-off,
-	// "actual" = real transactions. Goals are a separate, non-"spent" bucket.
-	cats := []struct {
-		name     string
-		emoji    string
-		catType  string // fixed | wants | goal | other
-		tracking string
-		budget   *float64
-	}{
-		// Fixed — set aside
-		{"Rent", "🏠", "fixed", "allocated", floatPtr(9000)},
-		{"Car loan", "🚙", "fixed", "allocated", floatPtr(1742)},
-		{"Family support", "👨‍👩‍👧", "fixed", "allocated", floatPtr(1469)},
-		{"Therapy", "🧠", "fixed", "allocated", floatPtr(1762)},
-		{"Wife Electrolysis", "💆", "fixed", "allocated", floatPtr(1000)},
-		{"Cleaning", "🧹", "fixed", "allocated", floatPtr(400)},
-		{"Phone", "📞", "fixed", "allocated", floatPtr(160)},
-		{"Car insurance", "🛡️", "fixed", "allocated", floatPtr(117)},
-		{"Car registration", "📋", "fixed", "allocated", floatPtr(33)},
-		// Fixed — actual card charges
-		{"DEWA", "⚡", "fixed", "actual", floatPtr(750)},
-		{"E& Bill", "📡", "fixed", "actual", floatPtr(450)},
-		{"Subscriptions", "📱", "fixed", "actual", floatPtr(579)},
-		// Spending (stored as "wants") — all actual
-		{"Groceries", "🛒", "wants", "actual", floatPtr(2000)},
-		{"Dining Out & Delivery", "🍔", "wants", "actual", floatPtr(2000)},
-		{"Transport", "🚗", "wants", "actual", floatPtr(1100)},
-		{"Shopping & Gifts", "🛍️", "wants", "actual", floatPtr(1500)},
-		{"Healthcare", "💊", "wants", "actual", floatPtr(500)},
-		{"Beauty", "💅", "wants", "actual", floatPtr(400)},
-		{"Entertainment & Going Out", "🎬", "wants", "actual", floatPtr(1000)},
-		{"Misc / Buffer", "🗂️", "wants", "actual", floatPtr(1288)},
-		// Goals — set aside
-		{"Investment", "📈", "goal", "allocated", floatPtr(2500)},
-		{"Savings", "🏦", "goal", "allocated", floatPtr(2000)},
-		{"Emergency fund", "🆘", "goal", "allocated", floatPtr(750)},
-	}
-
-	now := time.Now().Format(time.RFC3339)
-	for _, cat := range cats {
-		if _, err := c.db.Exec(
-			"INSERT OR IGNORE INTO categories (name, emoji, exclude_from_totals, type, budget_amount, tracking, created_at) VALUES (?, ?, 0, ?, ?, ?, ?)",
-			cat.name, cat.emoji, cat.catType, cat.budget, cat.tracking, now,
-		); err != nil {
-			return fmt.Errorf("insert budgeting category %s: %w", cat.name, err)
-		}
-		if _, err := c.db.Exec(
-			"UPDATE categories SET type=?, tracking=?, budget_amount=? WHERE name=?",
-			cat.catType, cat.tracking, cat.budget, cat.name,
-		); err != nil {
-			return fmt.Errorf("update budgeting category %s: %w", cat.name, err)
+	tmpl := &defaultBudgetTemplate
+	if path := c.budgetTemplate; path != "" {
+		t, err := loadBudgetTemplate(path)
+		if err != nil {
+			return err
 		}
+		log.Printf("[Database] Seeding budget from template %s", path)
+		tmpl = t
 	}
-
-	// Default the monthly salary if unset.
-	if _, err := c.db.Exec(
-		"INSERT OR IGNORE INTO settings (key, value) VALUES ('monthly_salary', '32500')",
-	); err != nil {
-		return fmt.Errorf("seed salary: %w", err)
+	if err := c.seedBudgetTemplate(tmpl); err != nil {
+		return err
 	}
 
 	if err := c.SetSetting("budgeting_setup_v1", "1"); err != nil {
@@ -297,6 +374,10 @@ func (c *DatabaseClient) runDataMigrations() error {
 			tx.Rollback()
 			return fmt.Errorf("cascade rename rules %s: %w", pair[0], err)
 		}
+		if _, err := tx.Exec("UPDATE transaction_splits SET category=? WHERE category=?", pair[1], pair[0]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("cascade rename splits %s: %w", pair[0], err)
+		}
 		if err := tx.Commit(); err != nil {
 			return fmt.Errorf("commit rename %s: %w", pair[0], err)
 		}
@@ -323,10 +404,15 @@ func (c *DatabaseClient) runDataMigrations() error {
 }
 
 func (c *DatabaseClient) SaveTransaction(tx Transaction) (int64, error) {
+	if err := c.ensureCycleOpen(tx.BillingCycle); err != nil {
+		return 0, err
+	}
+
 	query := `
 		INSERT INTO transactions
-		(description, amount, transaction_date, category, confidence, billing_cycle, created_at, source)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		(description, amount, transaction_date, category, confidence, billing_cycle, created_at, source, account, currency,
+		 original_description, excluded, note, approved)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	log.Printf("[Database] Saving transaction: %s (%.2f AED)", tx.Description, tx.Amount)
@@ -341,6 +427,13 @@ func (c *DatabaseClient) SaveTransaction(tx Transaction) (int64, error) {
 		tx.BillingCycle,
 		tx.Timestamp,
 		tx.Source,
+		tx.Account,
+		strings.ToUpper(tx.Currency),
+		tx.OriginalDescription,
+		tx.Excluded,
+		tx.Note,
+		// What you enter yourself needs no review.
+		tx.Approved || tx.Source == "manual",
 	)
 
 	if err != nil {
@@ -355,10 +448,31 @@ func (c *DatabaseClient) SaveTransaction(tx Transaction) (int64, error) {
 	}
 
 	log.Printf("[Database] Transaction saved successfully with ID %d", id)
+
+	if len(tx.Tags) > 0 {
+		if err := c.SetTransactionTags(id, tx.Tags); err != nil {
+			log.Printf("[Database] Failed to tag transaction %d: %v", id, err)
+		}
+	}
+
+	if err := c.matchIncomeTransaction(id); err != nil {
+		log.Printf("[Database] Failed to match income for transaction %d: %v", id, err)
+	}
+	if err := c.autoFundTransaction(id); err != nil {
+		log.Printf("[Database] Failed to auto-fund from transaction %d: %v", id, err)
+	}
+	c.checkAlertsAfterWrite(tx.BillingCycle)
 	return id, nil
 }
 
 func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
+	return c.GetStatsForTag(cycle, "")
+}
+
+// GetStatsForTag is GetStats narrowed to the transactions carrying tag:
+// totals, the category breakdown and the bucket spend count only those.
+// Budgets, funding and income are unaffected.
+func (c *DatabaseClient) GetStatsForTag(cycle, tag string) (*StatsResponse, error) {
 	currentCycle := cycle
 	if currentCycle == "" {
 		currentCycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
@@ -372,17 +486,29 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get categories: %w", err)
 	}
+	// Budgets as they stood in this cycle, not today's budget_amount.
+	cycleBudgets, err := c.BudgetsForCycle(currentCycle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %w", err)
+	}
+	for i := range allCats {
+		allCats[i].BudgetAmount = cycleBudgets[allCats[i].ID]
+	}
 	emojiMap := make(map[string]string, len(allCats))
 	typeMap := make(map[string]string, len(allCats))
 	trackingMap := make(map[string]string, len(allCats))
 	excludeMap := make(map[string]bool, len(allCats))
+	budgetMap := make(map[string]*float64, len(allCats))
+	// A sub-category's budget is already covered by a parent budget.
+	shadowed := shadowedBudgets(allCats, cycleBudgets)
 	var fixedBudget, wantsBudget, goalsBudget float64
 	for _, cat := range allCats {
 		emojiMap[cat.Name] = cat.Emoji
 		typeMap[cat.Name] = cat.Type
 		trackingMap[cat.Name] = cat.Tracking
 		excludeMap[cat.Name] = cat.ExcludeFromTotals
-		if cat.BudgetAmount != nil {
+		budgetMap[cat.Name] = cat.BudgetAmount
+		if cat.BudgetAmount != nil && !shadowed[cat.ID] {
 			switch cat.Type {
 			case "fixed":
 				fixedBudget += *cat.BudgetAmount
@@ -399,33 +525,60 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get funding: %w", err)
 	}
+	// Partial entries count toward the bucket totals as they are; only a
+	// fully funded category gets its tick.
+	funding := fundingStatuses(allCats, funded, nil)
 	fundedIDs := make([]int64, 0, len(funded))
 	var fixedAllocated, goalsFunded float64
-	for _, cat := range allCats {
-		amt, ok := funded[cat.ID]
-		if !ok {
-			continue
+	for _, s := range funding {
+		if s.Complete {
+			fundedIDs = append(fundedIDs, s.CategoryID)
 		}
-		fundedIDs = append(fundedIDs, cat.ID)
-		switch cat.Type {
+		switch s.Type {
 		case "fixed":
-			fixedAllocated += amt
+			fixedAllocated += s.Funded
 		case "goal":
-			goalsFunded += amt
+			goalsFunded += s.Funded
 		}
 	}
 
-	salary := c.GetSalary()
+	// Salary is what actually came in this cycle (all income sources).
+	income, err := c.IncomeForCycle(currentCycle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get income: %w", err)
+	}
+	salary := income.Total
+
+	closed, err := c.IsCycleClosed(currentCycle)
+	if err != nil {
+		return nil, err
+	}
+
+	// Envelope balances for carry-over categories, keyed by category name.
+	var envelopes []CarryoverEntry
+	envelopeMap := make(map[string]CarryoverEntry)
+	for _, cat := range allCats {
+		entry, err := c.carryoverFor(cat, currentCycle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute carryover: %w", err)
+		}
+		if entry != nil {
+			envelopes = append(envelopes, *entry)
+			envelopeMap[cat.Name] = *entry
+		}
+	}
 
 	// Query total and count
 	var total float64
 	var count int
 
+	tagClause, tagArgs := tagFilter(tag)
+	cycleArgs := append([]interface{}{currentCycle}, tagArgs...)
 	err = c.db.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0), COUNT(*)
-		FROM transactions
-		WHERE billing_cycle = ? AND category NOT IN (SELECT name FROM categories WHERE exclude_from_totals = 1)
-	`, currentCycle).Scan(&total, &count)
+		SELECT COALESCE(SUM(amount), 0), COUNT(DISTINCT id)
+		FROM spend_lines
+		WHERE billing_cycle = ? AND category NOT IN (SELECT name FROM categories WHERE exclude_from_totals = 1)`+tagClause,
+		cycleArgs...).Scan(&total, &count)
 
 	if err != nil {
 		log.Printf("[Database] Failed to get totals: %v", err)
@@ -442,6 +595,7 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 			Message:             message,
 			Cycle:               currentCycle,
 			CycleLabel:          cycleDisplayLabel(currentCycle),
+			Tag:                 tag,
 			Total:               0,
 			Count:               0,
 			Categories:          []CategoryStats{},
@@ -456,17 +610,21 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 			WantsBudget:         wantsBudget,
 			GoalsBudget:         goalsBudget,
 			FundedCategoryIDs:   fundedIDs,
+			Funding:             funding,
+			Closed:              closed,
+			Envelopes:           envelopes,
+			Income:              income,
 		}, nil
 	}
 
 	// Query category breakdown
 	rows, err := c.db.Query(`
 		SELECT category, SUM(amount) as total, COUNT(*) as count
-		FROM transactions
-		WHERE billing_cycle = ?
+		FROM spend_lines
+		WHERE billing_cycle = ?`+tagClause+`
 		GROUP BY category
 		ORDER BY total DESC
-	`, currentCycle)
+	`, cycleArgs...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get category stats: %w", err)
@@ -484,6 +642,12 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 			emoji = "📌"
 		}
 		cat.Emoji = emoji
+		// available = budget + carried − spent (carried is 0 without carry-over)
+		if budget := budgetMap[cat.Category]; budget != nil {
+			cat.Budget = budget
+			cat.Carried = envelopeMap[cat.Category].CarriedIn
+			cat.Available = floatPtr(*budget + cat.Carried - cat.Total)
+		}
 		categories = append(categories, cat)
 	}
 
@@ -494,11 +658,11 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 	// Fetch transactions for each category
 	for i := range categories {
 		txRows, err := c.db.Query(`
-			SELECT id, description, amount, transaction_date, category, confidence, billing_cycle, created_at
-			FROM transactions
-			WHERE billing_cycle = ? AND category = ?
+			SELECT id, description, amount, transaction_date, category, confidence, billing_cycle, created_at, COALESCE(split_id, 0), note
+			FROM spend_lines
+			WHERE billing_cycle = ? AND category = ?`+tagClause+`
 			ORDER BY transaction_date DESC, created_at DESC
-		`, currentCycle, categories[i].Category)
+		`, append([]interface{}{currentCycle, categories[i].Category}, tagArgs...)...)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to get transactions for category %s: %w", categories[i].Category, err)
@@ -507,7 +671,7 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 		var transactions []Transaction
 		for txRows.Next() {
 			var tx Transaction
-			if err := txRows.Scan(&tx.ID, &tx.Description, &tx.Amount, &tx.Date, &tx.Category, &tx.Confidence, &tx.BillingCycle, &tx.Timestamp); err != nil {
+			if err := txRows.Scan(&tx.ID, &tx.Description, &tx.Amount, &tx.Date, &tx.Category, &tx.Confidence, &tx.BillingCycle, &tx.Timestamp, &tx.SplitID, &tx.Note); err != nil {
 				txRows.Close()
 				return nil, fmt.Errorf("failed to scan transaction: %w", err)
 			}
@@ -518,6 +682,18 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 		categories[i].Transactions = transactions
 	}
 
+	txTags, err := c.transactionTags(currentCycle)
+	if err != nil {
+		return nil, err
+	}
+	for i := range categories {
+		attachTags(categories[i].Transactions, txTags)
+	}
+
+	// Sub-category spend rolls up into the parent's row, with the children
+	// kept alongside as its breakdown.
+	categories = rollupCategoryStats(categories, allCats, envelopeMap)
+
 	// Compute fixed/spending totals. Allocated (set-aside) categories count via
 	// their funding ticks (fixedAllocated, computed above); actual categories
 	// count via real transactions. Spending (wants) is always transaction-driven.
@@ -546,11 +722,12 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 
 	// Query all transactions sorted by date descending for the flat list
 	allTxRows, err := c.db.Query(`
-		SELECT id, description, amount, transaction_date, category, confidence, billing_cycle, created_at
+		SELECT id, description, amount, transaction_date, category, confidence, billing_cycle, created_at,
+			original_description, excluded, approved, note
 		FROM transactions
-		WHERE billing_cycle = ?
+		WHERE billing_cycle = ?`+tagClause+`
 		ORDER BY transaction_date DESC, created_at DESC
-	`, currentCycle)
+	`, cycleArgs...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all transactions: %w", err)
@@ -560,7 +737,8 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 	var allTransactions []Transaction
 	for allTxRows.Next() {
 		var tx Transaction
-		if err := allTxRows.Scan(&tx.ID, &tx.Description, &tx.Amount, &tx.Date, &tx.Category, &tx.Confidence, &tx.BillingCycle, &tx.Timestamp); err != nil {
+		if err := allTxRows.Scan(&tx.ID, &tx.Description, &tx.Amount, &tx.Date, &tx.Category, &tx.Confidence, &tx.BillingCycle, &tx.Timestamp,
+			&tx.OriginalDescription, &tx.Excluded, &tx.Approved, &tx.Note); err != nil {
 			return nil, fmt.Errorf("failed to scan all transaction: %w", err)
 		}
 		allTransactions = append(allTransactions, tx)
@@ -569,16 +747,22 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 	if err := allTxRows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating all transactions: %w", err)
 	}
+	attachTags(allTransactions, txTags)
+	txSplits, err := c.cycleSplits(currentCycle)
+	if err != nil {
+		return nil, err
+	}
+	attachSplits(allTransactions, txSplits)
 
 	// Query last transaction
 	var lastTx TransactionSummary
 	err = c.db.QueryRow(`
 		SELECT description, amount, transaction_date
 		FROM transactions
-		WHERE billing_cycle = ?
+		WHERE billing_cycle = ?`+tagClause+`
 		ORDER BY transaction_date DESC, created_at DESC
 		LIMIT 1
-	`, currentCycle).Scan(&lastTx.Description, &lastTx.Amount, &lastTx.Date)
+	`, cycleArgs...).Scan(&lastTx.Description, &lastTx.Amount, &lastTx.Date)
 
 	var lastTransaction *TransactionSummary
 	if err == nil {
@@ -616,6 +800,7 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 		Message:             message,
 		Cycle:               currentCycle,
 		CycleLabel:          cycleDisplayLabel(currentCycle),
+		Tag:                 tag,
 		Total:               total,
 		Count:               count,
 		Categories:          categories,
@@ -632,12 +817,16 @@ func (c *DatabaseClient) GetStats(cycle string) (*StatsResponse, error) {
 		WantsBudget:         wantsBudget,
 		GoalsBudget:         goalsBudget,
 		FundedCategoryIDs:   fundedIDs,
+		Funding:             funding,
+		Closed:              closed,
+		Envelopes:           envelopes,
+		Income:              income,
 	}, nil
 }
 
 func (c *DatabaseClient) GetAllTransactionsGroupedByCycle() ([]Transaction, error) {
 	rows, err := c.db.Query(`
-		SELECT id, description, amount, transaction_date, category, confidence, billing_cycle, created_at
+		SELECT id, description, amount, transaction_date, category, confidence, billing_cycle, created_at, excluded
 		FROM transactions
 		ORDER BY transaction_date DESC, created_at DESC
 	`)
@@ -649,7 +838,7 @@ func (c *DatabaseClient) GetAllTransactionsGroupedByCycle() ([]Transaction, erro
 	var transactions []Transaction
 	for rows.Next() {
 		var tx Transaction
-		if err := rows.Scan(&tx.ID, &tx.Description, &tx.Amount, &tx.Date, &tx.Category, &tx.Confidence, &tx.BillingCycle, &tx.Timestamp); err != nil {
+		if err := rows.Scan(&tx.ID, &tx.Description, &tx.Amount, &tx.Date, &tx.Category, &tx.Confidence, &tx.BillingCycle, &tx.Timestamp, &tx.Excluded); err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
 		transactions = append(transactions, tx)
@@ -659,13 +848,52 @@ func (c *DatabaseClient) GetAllTransactionsGroupedByCycle() ([]Transaction, erro
 		return nil, fmt.Errorf("error iterating transactions: %w", err)
 	}
 
+	tags, err := c.transactionTags("")
+	if err != nil {
+		return nil, err
+	}
+	attachTags(transactions, tags)
+	splits, err := c.cycleSplits("")
+	if err != nil {
+		return nil, err
+	}
+	attachSplits(transactions, splits)
+
 	return transactions, nil
 }
 
 func (c *DatabaseClient) UpdateTransaction(id int64, tx Transaction) error {
+	var oldCycle string
+	var oldAmount float64
+	err := c.db.QueryRow("SELECT billing_cycle, amount FROM transactions WHERE id = ?", id).Scan(&oldCycle, &oldAmount)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("transaction not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	// A split transaction's lines must keep adding up to its amount.
+	if tx.Amount != oldAmount {
+		split, err := c.hasSplits(id)
+		if err != nil {
+			return err
+		}
+		if split {
+			return fmt.Errorf("invalid amount: transaction is split; change its splits first")
+		}
+	}
+	// Both the cycle it leaves and the cycle it lands in must be open.
+	if err := c.ensureCycleOpen(oldCycle); err != nil {
+		return err
+	}
+	if err := c.ensureCycleOpen(tx.BillingCycle); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE transactions
-		SET description = ?, amount = ?, transaction_date = ?, category = ?, billing_cycle = ?, source = ?
+		SET description = ?, amount = ?, transaction_date = ?, category = ?, billing_cycle = ?, source = ?, approved = 1,
+			excluded = ?, corrected_from = CASE WHEN corrected_from = '' AND category <> ? THEN category ELSE corrected_from END
 		WHERE id = ?
 	`
 
@@ -679,6 +907,8 @@ func (c *DatabaseClient) UpdateTransaction(id int64, tx Transaction) error {
 		tx.Category,
 		tx.BillingCycle,
 		"manual",
+		tx.Excluded,
+		tx.Category,
 		id,
 	)
 
@@ -693,10 +923,33 @@ func (c *DatabaseClient) UpdateTransaction(id int64, tx Transaction) error {
 	}
 
 	log.Printf("[Database] Transaction updated successfully")
+
+	if tx.Tags != nil {
+		if err := c.SetTransactionTags(id, tx.Tags); err != nil {
+			return err
+		}
+	}
+
+	if err := c.matchIncomeTransaction(id); err != nil {
+		log.Printf("[Database] Failed to match income for transaction %d: %v", id, err)
+	}
+	c.checkAlertsAfterWrite(oldCycle, tx.BillingCycle)
 	return nil
 }
 
 func (c *DatabaseClient) DeleteTransaction(id int64) error {
+	var cycle string
+	err := c.db.QueryRow("SELECT billing_cycle FROM transactions WHERE id = ?", id).Scan(&cycle)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("transaction not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if err := c.ensureCycleOpen(cycle); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM transactions WHERE id = ?`
 
 	log.Printf("[Database] Deleting transaction ID %d", id)
@@ -713,6 +966,23 @@ func (c *DatabaseClient) DeleteTransaction(id int64) error {
 	}
 
 	log.Printf("[Database] Transaction deleted successfully")
+
+	if _, err := c.db.Exec("DELETE FROM transaction_tags WHERE transaction_id = ?", id); err != nil {
+		log.Printf("[Database] Failed to untag transaction %d: %v", id, err)
+	}
+	if _, err := c.db.Exec("DELETE FROM transaction_splits WHERE transaction_id = ?", id); err != nil {
+		log.Printf("[Database] Failed to delete splits of transaction %d: %v", id, err)
+	}
+	if _, err := c.db.Exec("DELETE FROM rule_log WHERE transaction_id = ?", id); err != nil {
+		log.Printf("[Database] Failed to delete rule log of transaction %d: %v", id, err)
+	}
+	if err := c.unfundTransaction(id); err != nil {
+		log.Printf("[Database] Failed to remove funding of transaction %d: %v", id, err)
+	}
+
+	if err := c.matchIncomeTransaction(id); err != nil {
+		log.Printf("[Database] Failed to clear income match for transaction %d: %v", id, err)
+	}
 	return nil
 }
 
@@ -826,13 +1096,26 @@ func (c *DatabaseClient) seedMerchantRules() error {
 	return nil
 }
 
-func (c *DatabaseClient) CreateRule(keyword, category string, priority int) (*MerchantRule, int, int, error) {
+func (c *DatabaseClient) CreateRule(keyword, matchType, category string, priority int) (*MerchantRule, int, int, error) {
+	return c.CreateRuleWithConditions(keyword, matchType, category, priority, RuleConditions{})
+}
+
+// CreateRuleWithConditions creates a rule that also requires conditions to
+// match, and counts the existing transactions it would recategorize and the
+// manual ones it would leave alone.
+func (c *DatabaseClient) CreateRuleWithConditions(keyword, matchType, category string, priority int, conds RuleConditions) (*MerchantRule, int, int, error) {
+	matchType, err := validateRuleMatch(keyword, matchType)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if err := validateRuleConditions(&conds); err != nil {
+		return nil, 0, 0, err
+	}
+
+	args := append([]interface{}{keyword, matchType, category, priority, time.Now().Format(time.RFC3339)}, ruleConditionArgs(conds)...)
 	result, err := c.db.Exec(
-		"INSERT INTO merchant_rules (keyword, category, priority, created_at) VALUES (?, ?, ?, ?)",
-		keyword,
-		category,
-		priority,
-		time.Now().Format(time.RFC3339),
+		"INSERT INTO merchant_rules (keyword, match_type, category, priority, created_at, "+ruleConditionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		args...,
 	)
 	if err != nil {
 		return nil, 0, 0, fmt.Errorf("failed to create rule: %w", err)
@@ -843,46 +1126,40 @@ func (c *DatabaseClient) CreateRule(keyword, category string, priority int) (*Me
 		return nil, 0, 0, fmt.Errorf("failed to get last insert ID: %w", err)
 	}
 
-	matchCount, protectedCount, err := c.countRuleMatches(keyword)
-	if err != nil {
-		return nil, 0, 0, err
+	rule := &MerchantRule{
+		ID:         id,
+		Keyword:    keyword,
+		MatchType:  matchType,
+		Category:   category,
+		Conditions: conds,
+		Priority:   priority,
+		CreatedAt:  time.Now().Format(time.RFC3339),
 	}
 
-	rule := &MerchantRule{
-		ID:        id,
-		Keyword:   keyword,
-		Category:  category,
-		Priority:  priority,
-		CreatedAt: time.Now().Format(time.RFC3339),
+	matchCount, protectedCount, err := c.countRuleMatches(*rule)
+	if err != nil {
+		return nil, 0, 0, err
 	}
 
 	return rule, matchCount, protectedCount, nil
 }
 
-func (c *DatabaseClient) countRuleMatches(keyword string) (int, int, error) {
-	var matchCount int
-	err := c.db.QueryRow(
-		"SELECT COUNT(*) FROM transactions WHERE LOWER(description) LIKE '%' || LOWER(?) || '%' AND source != 'manual'",
-		keyword,
-	).Scan(&matchCount)
+func (c *DatabaseClient) countRuleMatches(rule MerchantRule) (int, int, error) {
+	matched, protectedCount, err := c.ruleMatches(rule, false)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to count rule matches: %w", err)
 	}
-
-	var protectedCount int
-	err = c.db.QueryRow(
-		"SELECT COUNT(*) FROM transactions WHERE LOWER(description) LIKE '%' || LOWER(?) || '%' AND source = 'manual'",
-		keyword,
-	).Scan(&protectedCount)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to count protected transactions: %w", err)
-	}
-
-	return matchCount, protectedCount, nil
+	return len(matched), protectedCount, nil
 }
 
 func (c *DatabaseClient) GetAllRules() ([]MerchantRule, error) {
-	rows, err := c.db.Query("SELECT id, keyword, category, priority, created_at FROM merchant_rules ORDER BY priority DESC, id ASC")
+	rows, err := c.db.Query(`
+		SELECT r.id, r.keyword, r.match_type, r.category, COALESCE(p.name, ''), r.priority, r.created_at, r.actions,
+			r.hit_count, r.last_matched_at, r.min_amount, r.max_amount, r.amount_sign, r.day_from, r.day_to, r.account, r.currency
+		FROM merchant_rules r
+		LEFT JOIN categories c ON c.name = r.category
+		LEFT JOIN categories p ON p.id = c.parent_id
+		ORDER BY r.priority DESC, r.id ASC`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query rules: %w", err)
 	}
@@ -891,9 +1168,15 @@ func (c *DatabaseClient) GetAllRules() ([]MerchantRule, error) {
 	var rules []MerchantRule
 	for rows.Next() {
 		var rule MerchantRule
-		if err := rows.Scan(&rule.ID, &rule.Keyword, &rule.Category, &rule.Priority, &rule.CreatedAt); err != nil {
+		var actions string
+		var conds ruleConditionRow
+		dest := append([]interface{}{&rule.ID, &rule.Keyword, &rule.MatchType, &rule.Category, &rule.ParentCategory, &rule.Priority, &rule.CreatedAt, &actions,
+			&rule.HitCount, &rule.LastMatchedAt}, conds.dest()...)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("failed to scan rule: %w", err)
 		}
+		rule.Conditions = conds.conditions()
+		rule.Actions = decodeRuleActions(actions)
 		rules = append(rules, rule)
 	}
 
@@ -901,13 +1184,27 @@ func (c *DatabaseClient) GetAllRules() ([]MerchantRule, error) {
 		return nil, fmt.Errorf("error iterating rules: %w", err)
 	}
 
+	tags, err := c.ruleTags()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		rules[i].Tags = tags[rules[i].ID]
+	}
+
 	return rules, nil
 }
 
-func (c *DatabaseClient) UpdateRule(id int64, keyword, category string, priority int) error {
+func (c *DatabaseClient) UpdateRule(id int64, keyword, matchType, category string, priority int) error {
+	matchType, err := validateRuleMatch(keyword, matchType)
+	if err != nil {
+		return err
+	}
+
 	result, err := c.db.Exec(
-		"UPDATE merchant_rules SET keyword=?, category=?, priority=? WHERE id=?",
+		"UPDATE merchant_rules SET keyword=?, match_type=?, category=?, priority=? WHERE id=?",
 		keyword,
+		matchType,
 		category,
 		priority,
 		id,
@@ -935,6 +1232,10 @@ func (c *DatabaseClient) DeleteRule(id int64) error {
 		return fmt.Errorf("rule not found")
 	}
 
+	if _, err := c.db.Exec("DELETE FROM rule_tags WHERE rule_id=?", id); err != nil {
+		return fmt.Errorf("failed to delete rule tags: %w", err)
+	}
+
 	return nil
 }
 
@@ -990,95 +1291,79 @@ func (c *DatabaseClient) MoveRulePriority(id int64, direction string) error {
 }
 
 func (c *DatabaseClient) ApplyRuleSingle(ruleID int64) (int, int, error) {
-	var rule MerchantRule
-	err := c.db.QueryRow("SELECT id, keyword, category, priority, created_at FROM merchant_rules WHERE id=?", ruleID).Scan(
-		&rule.ID, &rule.Keyword, &rule.Category, &rule.Priority, &rule.CreatedAt,
-	)
+	rule, err := c.getRule(ruleID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, 0, fmt.Errorf("rule not found")
-		}
-		return 0, 0, fmt.Errorf("failed to fetch rule: %w", err)
+		return 0, 0, err
 	}
 
-	result, err := c.db.Exec(
-		"UPDATE transactions SET category=?, source='rule' WHERE LOWER(description) LIKE '%' || LOWER(?) || '%' AND source != 'manual'",
-		rule.Category,
-		rule.Keyword,
-	)
+	candidates, protected, err := c.ruleMatches(*rule, true)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to apply rule: %w", err)
+		return 0, 0, fmt.Errorf("failed to match rule: %w", err)
+	}
+	tags, err := c.transactionTags("")
+	if err != nil {
+		return 0, 0, err
 	}
 
-	updated, _ := result.RowsAffected()
-
-	var protected int
-	err = c.db.QueryRow(
-		"SELECT COUNT(*) FROM transactions WHERE LOWER(description) LIKE '%' || LOWER(?) || '%' AND source = 'manual'",
-		rule.Keyword,
-	).Scan(&protected)
+	sqlTx, err := c.db.Begin()
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to count protected transactions: %w", err)
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer sqlTx.Rollback()
 
-	return int(updated), protected, nil
+	var updated int
+	for _, tx := range candidates {
+		changed, err := applyRuleTo(sqlTx, tx, tags[tx.ID], *rule)
+		if err != nil {
+			return 0, 0, err
+		}
+		if changed {
+			updated++
+		}
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit rule: %w", err)
+	}
+	return updated, protected, nil
 }
 
 func (c *DatabaseClient) ApplyAllRules() (int, int, error) {
-	rules, err := c.GetAllRules()
+	engine, err := c.loadRuleEngine()
 	if err != nil {
 		return 0, 0, err
 	}
-
-	rows, err := c.db.Query("SELECT id, description, source FROM transactions WHERE source != 'manual'")
+	candidates, err := c.ruleCandidates()
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to query transactions: %w", err)
-	}
-	defer rows.Close()
-
-	type txRecord struct {
-		id   int64
-		desc string
+		return 0, 0, err
 	}
-	var transactions []txRecord
-	for rows.Next() {
-		var tx txRecord
-		var source string
-		if err := rows.Scan(&tx.id, &tx.desc, &source); err != nil {
-			return 0, 0, fmt.Errorf("failed to scan transaction: %w", err)
-		}
-		transactions = append(transactions, tx)
+	tags, err := c.transactionTags("")
+	if err != nil {
+		return 0, 0, err
 	}
 
-	updateMap := make(map[string][]int64)
-	for _, tx := range transactions {
-		for _, rule := range rules {
-			if strings.Contains(strings.ToLower(tx.desc), strings.ToLower(rule.Keyword)) {
-				updateMap[rule.Category] = append(updateMap[rule.Category], tx.id)
-				break
-			}
-		}
+	sqlTx, err := c.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer sqlTx.Rollback()
 
 	var totalUpdated int
-	for category, ids := range updateMap {
-		placeholders := make([]string, len(ids))
-		args := []interface{}{category, "rule"}
-		for i, id := range ids {
-			placeholders[i] = "?"
-			args = append(args, id)
+	for _, tx := range candidates {
+		if tx.Source == "manual" || !tx.open {
+			continue
 		}
-
-		query := fmt.Sprintf(
-			"UPDATE transactions SET category=?, source=? WHERE id IN (%s)",
-			strings.Join(placeholders, ","),
-		)
-		result, err := c.db.Exec(query, args...)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to apply rules: %w", err)
+		if rule := engine.Match(tx.Transaction); rule != nil {
+			changed, err := applyRuleTo(sqlTx, tx.Transaction, tags[tx.ID], *rule)
+			if err != nil {
+				return 0, 0, err
+			}
+			if changed {
+				totalUpdated++
+			}
 		}
-		updated, _ := result.RowsAffected()
-		totalUpdated += int(updated)
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit rules: %w", err)
 	}
 
 	var protected int
@@ -1090,30 +1375,25 @@ func (c *DatabaseClient) ApplyAllRules() (int, int, error) {
 	return totalUpdated, protected, nil
 }
 
+// FindMatchingRule matches a description on its own, as a transaction with
+// no amount, date, account or currency.
 func (c *DatabaseClient) FindMatchingRule(description string) (*MerchantRule, error) {
-	rows, err := c.db.Query("SELECT id, keyword, category, priority, created_at FROM merchant_rules ORDER BY priority DESC, id ASC")
-	if err != nil {
-		return nil, fmt.Errorf("failed to query rules: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var rule MerchantRule
-		if err := rows.Scan(&rule.ID, &rule.Keyword, &rule.Category, &rule.Priority, &rule.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan rule: %w", err)
-		}
+	return c.MatchTransaction(Transaction{Description: description})
+}
 
-		if strings.Contains(strings.ToLower(description), strings.ToLower(rule.Keyword)) {
-			return &rule, nil
-		}
+// MatchTransaction returns the first rule, in priority order, whose
+// pattern and conditions all match a transaction, or nil.
+func (c *DatabaseClient) MatchTransaction(tx Transaction) (*MerchantRule, error) {
+	engine, err := c.loadRuleEngine()
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, nil
+	return engine.Match(tx), nil
 }
 
 func (c *DatabaseClient) GetAllCategories() ([]Category, error) {
 	rows, err := c.db.Query(
-		"SELECT id, name, emoji, exclude_from_totals, created_at, type, budget_amount, tracking FROM categories ORDER BY id ASC",
+		"SELECT id, name, emoji, exclude_from_totals, created_at, type, budget_amount, tracking, carryover, carryover_start, parent_id, archived FROM categories ORDER BY id ASC",
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query categories: %w", err)
@@ -1127,10 +1407,15 @@ func (c *DatabaseClient) GetAllCategories() ([]Category, error) {
 		var catType sql.NullString
 		var budget sql.NullFloat64
 		var tracking sql.NullString
-		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Emoji, &excl, &cat.CreatedAt, &catType, &budget, &tracking); err != nil {
+		var carryover int
+		var parentID sql.NullInt64
+		var archived int
+		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Emoji, &excl, &cat.CreatedAt, &catType, &budget, &tracking, &carryover, &cat.CarryoverStart, &parentID, &archived); err != nil {
 			return nil, fmt.Errorf("failed to scan category: %w", err)
 		}
 		cat.ExcludeFromTotals = excl == 1
+		cat.Carryover = carryover == 1
+		cat.Archived = archived == 1
 		if catType.Valid {
 			cat.Type = catType.String
 		} else {
@@ -1145,12 +1430,34 @@ func (c *DatabaseClient) GetAllCategories() ([]Category, error) {
 		} else {
 			cat.Tracking = "actual"
 		}
+		if parentID.Valid {
+			cat.ParentID = &parentID.Int64
+		}
 		categories = append(categories, cat)
 	}
 	return categories, rows.Err()
 }
 
 func (c *DatabaseClient) CreateCategory(name, emoji string, excludeFromTotals bool, catType string, budgetAmount *float64, tracking string) (*Category, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	cat, err := createCategory(tx, name, emoji, excludeFromTotals, catType, budgetAmount, tracking)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit category: %w", err)
+	}
+	return cat, nil
+}
+
+// createCategory does CreateCategory's writes inside an existing SQL
+// transaction.
+func createCategory(tx *sql.Tx, name, emoji string, excludeFromTotals bool, catType string, budgetAmount *float64, tracking string) (*Category, error) {
 	excl := 0
 	if excludeFromTotals {
 		excl = 1
@@ -1161,7 +1468,7 @@ func (c *DatabaseClient) CreateCategory(name, emoji string, excludeFromTotals bo
 	if tracking != "allocated" {
 		tracking = "actual"
 	}
-	result, err := c.db.Exec(
+	result, err := tx.Exec(
 		"INSERT INTO categories (name, emoji, exclude_from_totals, type, budget_amount, tracking, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
 		name, emoji, excl, catType, budgetAmount, tracking, time.Now().Format(time.RFC3339),
 	)
@@ -1172,6 +1479,11 @@ func (c *DatabaseClient) CreateCategory(name, emoji string, excludeFromTotals bo
 	if err != nil {
 		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
 	}
+	// A new category's budget starts with the current cycle.
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	if err := setBudgetVersion(tx, id, current, budgetAmount, false); err != nil {
+		return nil, err
+	}
 	return &Category{
 		ID:                id,
 		Name:              name,
@@ -1185,8 +1497,24 @@ func (c *DatabaseClient) CreateCategory(name, emoji string, excludeFromTotals bo
 }
 
 func (c *DatabaseClient) UpdateCategory(id int64, name, emoji string, excludeFromTotals bool, catType string, budgetAmount *float64, tracking string) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := updateCategory(tx, id, name, emoji, excludeFromTotals, catType, budgetAmount, tracking); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateCategory does UpdateCategory's writes inside an existing SQL
+// transaction.
+func updateCategory(tx *sql.Tx, id int64, name, emoji string, excludeFromTotals bool, catType string, budgetAmount *float64, tracking string) error {
 	var oldName string
-	err := c.db.QueryRow("SELECT name FROM categories WHERE id = ?", id).Scan(&oldName)
+	var oldBudget sql.NullFloat64
+	err := tx.QueryRow("SELECT name, budget_amount FROM categories WHERE id = ?", id).Scan(&oldName, &oldBudget)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("category not found")
 	}
@@ -1204,12 +1532,11 @@ func (c *DatabaseClient) UpdateCategory(id int64, name, emoji string, excludeFro
 	if tracking != "allocated" {
 		tracking = "actual"
 	}
-
-	tx, err := c.db.Begin()
+	// A sub-category stays in its parent's bucket.
+	catType, tracking, excl, err = inheritFromParent(tx, id, catType, tracking, excl)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return err
 	}
-	defer tx.Rollback()
 
 	if _, err := tx.Exec(
 		"UPDATE categories SET name=?, emoji=?, exclude_from_totals=?, type=?, budget_amount=?, tracking=? WHERE id=?",
@@ -1217,6 +1544,27 @@ func (c *DatabaseClient) UpdateCategory(id int64, name, emoji string, excludeFro
 	); err != nil {
 		return fmt.Errorf("failed to update category: %w", err)
 	}
+	// ...and its children follow it. They link by ID, so a rename needs no
+	// cascade here.
+	if _, err := tx.Exec(
+		`UPDATE categories SET exclude_from_totals=?, type=?, tracking=?,
+			carryover = CASE WHEN ? = 'allocated' THEN 0 ELSE carryover END
+		WHERE parent_id=?`,
+		excl, catType, tracking, tracking, id,
+	); err != nil {
+		return fmt.Errorf("failed to cascade bucket to sub-categories: %w", err)
+	}
+
+	// Budget edits apply from the current cycle forward; past cycles keep
+	// the version that was in effect then.
+	budgetChanged := oldBudget.Valid != (budgetAmount != nil) ||
+		(budgetAmount != nil && *budgetAmount != oldBudget.Float64)
+	if budgetChanged {
+		current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+		if err := setBudgetVersion(tx, id, current, budgetAmount, false); err != nil {
+			return err
+		}
+	}
 
 	if name != oldName {
 		if _, err := tx.Exec(
@@ -1229,12 +1577,44 @@ func (c *DatabaseClient) UpdateCategory(id int64, name, emoji string, excludeFro
 		); err != nil {
 			return fmt.Errorf("failed to cascade rename to merchant_rules: %w", err)
 		}
+		if _, err := tx.Exec(
+			"UPDATE transaction_splits SET category=? WHERE category=?", name, oldName,
+		); err != nil {
+			return fmt.Errorf("failed to cascade rename to transaction_splits: %w", err)
+		}
+		if _, err := tx.Exec(
+			"UPDATE alert_rules SET target=? WHERE scope='category' AND target=?", name, oldName,
+		); err != nil {
+			return fmt.Errorf("failed to cascade rename to alert_rules: %w", err)
+		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
 func (c *DatabaseClient) DeleteCategory(id int64) error {
+	var children int
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM categories WHERE parent_id = ?", id).Scan(&children); err != nil {
+		return fmt.Errorf("failed to count sub-categories: %w", err)
+	}
+	if children > 0 {
+		return fmt.Errorf("cannot delete: category has %d sub-categories", children)
+	}
+	var name string
+	err := c.db.QueryRow("SELECT name FROM categories WHERE id = ?", id).Scan(&name)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch category: %w", err)
+	}
+	// Only a category nothing points at can go; anything else is archived.
+	usages, err := c.categoryUsages(id, name)
+	if err != nil {
+		return err
+	}
+	if len(usages) > 0 {
+		return fmt.Errorf("cannot delete: category is used by %s; archive it instead", joinUsages(usages))
+	}
 	result, err := c.db.Exec("DELETE FROM categories WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete category: %w", err)
@@ -1247,17 +1627,10 @@ func (c *DatabaseClient) DeleteCategory(id int64) error {
 }
 
 // SetCategoryTarget sets, edits, or clears a category's spending target
-// (budget_amount). A non-nil amount sets/edits the target; nil clears it.
+// (budget_amount) from the current cycle forward. A non-nil amount
+// sets/edits the target; nil clears it. See SetCategoryBudget for one-offs.
 func (c *DatabaseClient) SetCategoryTarget(id int64, amount *float64) error {
-	result, err := c.db.Exec("UPDATE categories SET budget_amount=? WHERE id=?", amount, id)
-	if err != nil {
-		return fmt.Errorf("failed to set category target: %w", err)
-	}
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("category not found")
-	}
-	return nil
+	return c.SetCategoryBudget(id, "", amount, false)
 }
 
 // --- Settings ---
@@ -1285,28 +1658,78 @@ func (c *DatabaseClient) SetSetting(key, value string) error {
 	return nil
 }
 
-// GetSalary returns the configured monthly salary, defaulting to 32500.
+// GetSalary returns the legacy monthly_salary setting, or 0 when unset.
+// Per-cycle income comes from IncomeForCycle; this is only its fallback.
 func (c *DatabaseClient) GetSalary() float64 {
 	v, err := c.GetSetting("monthly_salary")
 	if err != nil || v == "" {
-		return 32500
+		return 0
 	}
 	f, err := strconv.ParseFloat(v, 64)
 	if err != nil {
-		return 32500
+		return 0
 	}
 	return f
 }
 
+// SetSalary changes the "Salary" income source from the current cycle
+// forward (creating it if needed) and mirrors the value into monthly_salary.
 func (c *DatabaseClient) SetSalary(amount float64) error {
-	return c.SetSetting("monthly_salary", strconv.FormatFloat(amount, 'f', -1, 64))
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	salary, err := c.salarySource(current)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := setSalary(tx, salary, current, amount); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// salarySource returns the "Salary" income source active in cycle, or nil.
+func (c *DatabaseClient) salarySource(cycle string) (*IncomeSource, error) {
+	sources, err := c.activeIncomeSources(cycle)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sources {
+		if s.Name == "Salary" {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+// setSalary does SetSalary's writes inside an existing SQL transaction;
+// salary is the source salarySource found for cycle.
+func setSalary(tx *sql.Tx, salary *IncomeSource, cycle string, amount float64) error {
+	if _, err := tx.Exec(
+		"INSERT INTO settings (key, value) VALUES ('monthly_salary', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		strconv.FormatFloat(amount, 'f', -1, 64),
+	); err != nil {
+		return fmt.Errorf("failed to set setting monthly_salary: %w", err)
+	}
+	var err error
+	if salary != nil {
+		_, err = changeIncomeAmount(tx, salary, cycle, amount)
+	} else {
+		_, err = insertIncomeSource(tx, "Salary", amount, cycle, "", "")
+	}
+	return err
 }
 
 // --- Per-cycle funding (set-aside ticks for allocated categories) ---
 
-// GetFunding returns category_id → funded amount for a billing cycle.
+// GetFunding returns category_id → total funded amount for a billing cycle,
+// summing every funding entry.
 func (c *DatabaseClient) GetFunding(cycle string) (map[int64]float64, error) {
-	rows, err := c.db.Query("SELECT category_id, amount FROM cycle_funding WHERE cycle = ?", cycle)
+	rows, err := c.db.Query("SELECT category_id, SUM(amount) FROM cycle_funding WHERE cycle = ? GROUP BY category_id", cycle)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query funding: %w", err)
 	}
@@ -1324,21 +1747,38 @@ func (c *DatabaseClient) GetFunding(cycle string) (map[int64]float64, error) {
 	return funded, rows.Err()
 }
 
-// SetFunding marks an allocated category as funded for a cycle, snapshotting amount.
+// SetFunding marks an allocated category as funded for a cycle by topping
+// its entries up to amount. Earlier partial and auto-fund entries are kept;
+// nothing is added once they already reach amount.
 func (c *DatabaseClient) SetFunding(cycle string, categoryID int64, amount float64) error {
-	_, err := c.db.Exec(
-		`INSERT INTO cycle_funding (cycle, category_id, amount, funded_at) VALUES (?, ?, ?, ?)
-		 ON CONFLICT(cycle, category_id) DO UPDATE SET amount = excluded.amount, funded_at = excluded.funded_at`,
-		cycle, categoryID, amount, time.Now().Format(time.RFC3339),
-	)
-	if err != nil {
+	if err := c.ensureCycleOpen(cycle); err != nil {
+		return err
+	}
+	var funded float64
+	if err := c.db.QueryRow(
+		"SELECT COALESCE(SUM(amount), 0) FROM cycle_funding WHERE cycle = ? AND category_id = ?",
+		cycle, categoryID,
+	).Scan(&funded); err != nil {
+		return fmt.Errorf("failed to set funding: %w", err)
+	}
+	rest := amount - funded
+	if rest <= 0 {
+		return nil
+	}
+	if _, err := c.db.Exec(
+		"INSERT INTO cycle_funding (cycle, category_id, amount, funded_at) VALUES (?, ?, ?, ?)",
+		cycle, categoryID, rest, time.Now().Format(time.RFC3339),
+	); err != nil {
 		return fmt.Errorf("failed to set funding: %w", err)
 	}
 	return nil
 }
 
-// DeleteFunding clears the funded tick for a category in a cycle.
+// DeleteFunding clears every funding entry for a category in a cycle.
 func (c *DatabaseClient) DeleteFunding(cycle string, categoryID int64) error {
+	if err := c.ensureCycleOpen(cycle); err != nil {
+		return err
+	}
 	_, err := c.db.Exec("DELETE FROM cycle_funding WHERE cycle = ? AND category_id = ?", cycle, categoryID)
 	if err != nil {
 		return fmt.Errorf("failed to delete funding: %w", err)
@@ -1362,5 +1802,6 @@ func (c *DatabaseClient) GetCategory(id int64) (*Category, error) {
 
 func (c *DatabaseClient) Close() error {
 	log.Printf("[Database] Closing database connection")
+	c.stopAlertWorker()
 	return c.db.Close()
 }
@@ -8,9 +8,12 @@ import (
 	"testing"
 )
 
+// setupTestDB seeds the test database from the household budget in
+// testdata rather than the neutral default, since most tests lean on its
+// numbers.
 func setupTestDB(t *testing.T) *DatabaseClient {
 	t.Helper()
-	db, err := NewDatabaseClient(":memory:")
+	db, err := openDatabaseClient(":memory:", "testdata/household.yaml")
 	if err != nil {
 		t.Fatalf("failed to create test db: %v", err)
 	}
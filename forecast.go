@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// forecastLookback is how many earlier cycles feed the historical pattern.
+const forecastLookback = 6
+
+// CategoryForecast projects where a category's spend lands by the cycle's
+// last day. Low/High bound the projection across the methods used. OverUnder
+// is Projected − Budget (positive means over budget).
+type CategoryForecast struct {
+	Category  string   `json:"category"`
+	Type      string   `json:"type"`
+	Spent     float64  `json:"spent"`
+	Projected float64  `json:"projected"`
+	Low       float64  `json:"low"`
+	High      float64  `json:"high"`
+	Budget    *float64 `json:"budget,omitempty"`
+	OverUnder *float64 `json:"overUnder,omitempty"`
+	// Method is how Projected was reached:
+	//   "actual"    — the cycle is over; spend is final
+	//   "pace"      — daily pace so far (no usable history)
+	//   "history"   — pace blended with what past cycles spent after this point
+	//   "allocated" — an allocated category, counted at its funded or budget amount
+	//   "budget"    — a fixed bill not yet paid, expected at its budget
+	Method string `json:"method"`
+}
+
+type BucketForecast struct {
+	Spent     float64  `json:"spent"`
+	Projected float64  `json:"projected"`
+	Low       float64  `json:"low"`
+	High      float64  `json:"high"`
+	Budget    float64  `json:"budget"`
+	OverUnder *float64 `json:"overUnder,omitempty"`
+}
+
+type Forecast struct {
+	Cycle       string             `json:"cycle"`
+	AsOf        string             `json:"asOf"`
+	DaysElapsed int                `json:"daysElapsed"`
+	DaysTotal   int                `json:"daysTotal"`
+	Categories  []CategoryForecast `json:"categories"`
+	Fixed       BucketForecast     `json:"fixed"`
+	Wants       BucketForecast     `json:"wants"`
+}
+
+// cycleProgress returns how many days of a cycle have passed as of now
+// (today counted) and how many days it has in total.
+func cycleProgress(cycle string, now time.Time) (elapsed, total int, err error) {
+	start, end, err := cycleBounds(cycle)
+	if err != nil {
+		return 0, 0, err
+	}
+	total = int(end.Sub(start).Hours()/24) + 1
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	elapsed = int(day.Sub(start).Hours()/24) + 1
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	if elapsed > total {
+		elapsed = total
+	}
+	return elapsed, total, nil
+}
+
+// categorySpend sums spend per category in a cycle: the full total, and the
+// part dated on or before cutoff ("2006-01-02").
+func (c *DatabaseClient) categorySpend(cycle, cutoff string) (total, byCutoff map[string]float64, err error) {
+	rows, err := c.db.Query(
+		`SELECT category, SUM(amount), SUM(CASE WHEN transaction_date <= ? THEN amount ELSE 0 END)
+		 FROM spend_lines WHERE billing_cycle = ? GROUP BY category`,
+		cutoff, cycle,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query category spend: %w", err)
+	}
+	defer rows.Close()
+
+	total = make(map[string]float64)
+	byCutoff = make(map[string]float64)
+	for rows.Next() {
+		var name string
+		var sum, early float64
+		if err := rows.Scan(&name, &sum, &early); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan category spend: %w", err)
+		}
+		total[name] = sum
+		byCutoff[name] = early
+	}
+	return total, byCutoff, rows.Err()
+}
+
+// GetForecast projects end-of-cycle spend per category and for the fixed and
+// wants buckets, as of now. Actual-tracked categories blend the daily pace so
+// far with the spend that came after the same day in up to forecastLookback
+// earlier cycles; the spread between those estimates is the confidence band.
+// Allocated categories count at their funding or budget, whichever is larger,
+// and a fixed bill with no spend yet and none expected from history is
+// counted at budget.
+func (c *DatabaseClient) GetForecast(cycle string, now time.Time) (*Forecast, error) {
+	if cycle == "" {
+		cycle = calculateBillingCycle(now.Format("2006-01-02"))
+	}
+	elapsed, total, err := cycleProgress(cycle, now)
+	if err != nil {
+		return nil, err
+	}
+
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	budgets, err := c.BudgetsForCycle(cycle)
+	if err != nil {
+		return nil, err
+	}
+	shadowed := shadowedBudgets(cats, budgets)
+	funding, err := c.GetFunding(cycle)
+	if err != nil {
+		return nil, err
+	}
+	spent, _, err := c.categorySpend(cycle, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// What each category spent after day `elapsed` in earlier cycles that had
+	// any transactions at all.
+	var history []map[string]float64
+	for i := 1; i <= forecastLookback && elapsed < total; i++ {
+		past := shiftCycle(cycle, -i)
+		start, _, err := cycleBounds(past)
+		if err != nil {
+			return nil, err
+		}
+		cutoff := start.AddDate(0, 0, elapsed-1).Format("2006-01-02")
+		finals, early, err := c.categorySpend(past, cutoff)
+		if err != nil {
+			return nil, err
+		}
+		if len(finals) == 0 {
+			continue
+		}
+		remaining := make(map[string]float64, len(finals))
+		for name, v := range finals {
+			remaining[name] = v - early[name]
+		}
+		history = append(history, remaining)
+	}
+
+	forecast := &Forecast{
+		Cycle:       cycle,
+		AsOf:        now.Format("2006-01-02"),
+		DaysElapsed: elapsed,
+		DaysTotal:   total,
+		Categories:  []CategoryForecast{},
+	}
+	for _, cat := range cats {
+		if cat.ExcludeFromTotals || (cat.Type != "fixed" && cat.Type != "wants") {
+			continue
+		}
+		f := CategoryForecast{Category: cat.Name, Type: cat.Type, Budget: budgets[cat.ID]}
+
+		switch {
+		case cat.Tracking == "allocated":
+			f.Method = "allocated"
+			f.Spent = funding[cat.ID]
+			f.Projected = f.Spent
+			if f.Budget != nil && *f.Budget > f.Projected {
+				f.Projected = *f.Budget
+			}
+			f.Low, f.High = f.Projected, f.Projected
+
+		case elapsed >= total:
+			f.Method = "actual"
+			f.Spent = spent[cat.Name]
+			f.Projected, f.Low, f.High = f.Spent, f.Spent, f.Spent
+
+		default:
+			f.Spent = spent[cat.Name]
+			f.Method = "pace"
+			pace := f.Spent
+			if elapsed > 0 {
+				pace = f.Spent / float64(elapsed) * float64(total)
+			}
+			f.Projected, f.Low, f.High = pace, pace, pace
+			if elapsed == 0 {
+				f.Low, f.High = f.Spent, f.Spent
+			}
+
+			if len(history) > 0 {
+				var sum float64
+				for _, remaining := range history {
+					est := f.Spent + remaining[cat.Name]
+					sum += est
+					if est < f.Low {
+						f.Low = est
+					}
+					if est > f.High {
+						f.High = est
+					}
+				}
+				histAvg := sum / float64(len(history))
+				f.Method = "history"
+				if elapsed == 0 {
+					f.Projected = histAvg
+				} else {
+					f.Projected = (pace + histAvg) / 2
+				}
+			}
+			if cat.Type == "fixed" && f.Spent == 0 && f.Projected == 0 && f.Budget != nil {
+				f.Method = "budget"
+				f.Projected, f.Low, f.High = *f.Budget, *f.Budget, *f.Budget
+			}
+			if f.Low < f.Spent {
+				f.Low = f.Spent
+			}
+		}
+
+		if f.Budget != nil {
+			f.OverUnder = floatPtr(f.Projected - *f.Budget)
+		}
+		if f.Spent == 0 && f.Projected == 0 && f.Budget == nil {
+			continue
+		}
+		forecast.Categories = append(forecast.Categories, f)
+
+		bucket := &forecast.Wants
+		if cat.Type == "fixed" {
+			bucket = &forecast.Fixed
+		}
+		bucket.Spent += f.Spent
+		bucket.Projected += f.Projected
+		bucket.Low += f.Low
+		bucket.High += f.High
+		if f.Budget != nil && !shadowed[cat.ID] {
+			bucket.Budget += *f.Budget
+		}
+	}
+	for _, bucket := range []*BucketForecast{&forecast.Fixed, &forecast.Wants} {
+		if bucket.Budget > 0 {
+			bucket.OverUnder = floatPtr(bucket.Projected - bucket.Budget)
+		}
+	}
+
+	sort.Slice(forecast.Categories, func(i, j int) bool {
+		return forecast.Categories[i].Projected > forecast.Categories[j].Projected
+	})
+	return forecast, nil
+}
+
+func forecastHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[API] GET /forecast - Request from %s", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		forecast, err := db.GetForecast(r.URL.Query().Get("cycle"), time.Now())
+		if err != nil {
+			log.Printf("[API] Failed to get forecast: %v", err)
+			if err.Error() == "invalid cycle" {
+				http.Error(w, "Invalid cycle", http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to compute forecast", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"forecast": forecast,
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func forecastFor(t *testing.T, f *Forecast, name string) CategoryForecast {
+	t.Helper()
+	for _, cf := range f.Categories {
+		if cf.Category == name {
+			return cf
+		}
+	}
+	t.Fatalf("no forecast for %s", name)
+	return CategoryForecast{}
+}
+
+func TestForecast_PaceAndHistory(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, time.April, 7, 9, 0, 0, 0, time.UTC) // day 16 of 31 in Mar 2026
+
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour", Amount: 800, Date: "2026-03-30", Category: "Groceries", BillingCycle: "Mar 2026", Timestamp: "2026-03-30T10:00:00Z", Source: "openai"})
+
+	f, err := db.GetForecast("Mar 2026", now)
+	if err != nil {
+		t.Fatalf("GetForecast failed: %v", err)
+	}
+	if f.DaysElapsed != 16 || f.DaysTotal != 31 {
+		t.Errorf("expected day 16 of 31, got %d of %d", f.DaysElapsed, f.DaysTotal)
+	}
+	groc := forecastFor(t, f, "Groceries")
+	if groc.Method != "pace" || groc.Projected != 1550 {
+		t.Errorf("expected pace projection 1550, got %s %.2f", groc.Method, groc.Projected)
+	}
+	if groc.OverUnder == nil || *groc.OverUnder != -450 {
+		t.Errorf("expected 450 under budget, got %v", groc.OverUnder)
+	}
+	if rent := forecastFor(t, f, "Rent"); rent.Method != "allocated" || rent.Projected != 9000 {
+		t.Errorf("expected unfunded Rent at budget 9000, got %+v", rent)
+	}
+	if dewa := forecastFor(t, f, "DEWA"); dewa.Method != "budget" || dewa.Projected != 750 {
+		t.Errorf("expected unpaid DEWA at budget 750, got %+v", dewa)
+	}
+
+	// Last cycle: 500 by the same day, 1500 more after it.
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour early", Amount: 500, Date: "2026-02-25", Category: "Groceries", BillingCycle: "Feb 2026", Timestamp: "2026-02-25T10:00:00Z", Source: "openai"})
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour late", Amount: 1500, Date: "2026-03-15", Category: "Groceries", BillingCycle: "Feb 2026", Timestamp: "2026-03-15T10:00:00Z", Source: "openai"})
+
+	f, err = db.GetForecast("Mar 2026", now)
+	if err != nil {
+		t.Fatalf("GetForecast failed: %v", err)
+	}
+	groc = forecastFor(t, f, "Groceries")
+	// pace 1550, history 800 + 1500 = 2300 → blended 1925.
+	if groc.Method != "history" || groc.Projected != 1925 || groc.Low != 1550 || groc.High != 2300 {
+		t.Errorf("expected history blend 1925 [1550, 2300], got %+v", groc)
+	}
+	if f.Wants.Projected < groc.Projected || f.Wants.Budget == 0 {
+		t.Errorf("expected wants bucket to include groceries, got %+v", f.Wants)
+	}
+}
+
+func TestForecast_FinishedCycleIsActual(t *testing.T) {
+	db := setupTestDB(t)
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour", Amount: 800, Date: "2026-01-30", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-01-30T10:00:00Z", Source: "openai"})
+
+	f, err := db.GetForecast("Jan 2026", time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetForecast failed: %v", err)
+	}
+	if groc := forecastFor(t, f, "Groceries"); groc.Method != "actual" || groc.Projected != 800 {
+		t.Errorf("expected final actual 800, got %+v", groc)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FundingEntry is one dated set-aside for a category in a cycle. A cycle can
+// hold several (half the rent early, the rest later, extra into Savings);
+// the category's funded amount is their sum.
+type FundingEntry struct {
+	ID            int64   `json:"id"`
+	Cycle         string  `json:"cycle"`
+	CategoryID    int64   `json:"categoryId"`
+	Amount        float64 `json:"amount"`
+	Note          string  `json:"note,omitempty"`
+	TransactionID *int64  `json:"transactionId,omitempty"`
+	FundedAt      string  `json:"fundedAt"`
+}
+
+// FundingStatus is an allocated or goal category's funding in a cycle
+// against its budget. Remaining is never negative; Extra is what was set
+// aside beyond the budget. Complete means the budget is fully set aside (or,
+// with no budget, that anything was).
+type FundingStatus struct {
+	CategoryID int64          `json:"categoryId"`
+	Category   string         `json:"category"`
+	Type       string         `json:"type"`
+	Budget     *float64       `json:"budget"`
+	Funded     float64        `json:"funded"`
+	Remaining  float64        `json:"remaining"`
+	Extra      float64        `json:"extra"`
+	Complete   bool           `json:"complete"`
+	Entries    []FundingEntry `json:"entries,omitempty"`
+}
+
+// migrateFundingEntries rebuilds cycle_funding without its (cycle,
+// category_id) primary key so a category can hold several entries per
+// cycle. Existing ticks become one entry each. Runs after migrateAutoFund.
+func (c *DatabaseClient) migrateFundingEntries() error {
+	var hasID int
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('cycle_funding') WHERE name = 'id'").Scan(&hasID); err != nil {
+		return fmt.Errorf("failed to inspect cycle_funding: %w", err)
+	}
+	if hasID == 1 {
+		return nil
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	migrations := []string{
+		`CREATE TABLE cycle_funding_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cycle TEXT NOT NULL,
+			category_id INTEGER NOT NULL,
+			amount REAL NOT NULL,
+			funded_at TEXT NOT NULL,
+			transaction_id INTEGER,
+			note TEXT NOT NULL DEFAULT ''
+		)`,
+		`INSERT INTO cycle_funding_entries (cycle, category_id, amount, funded_at, transaction_id)
+		 SELECT cycle, category_id, amount, funded_at, transaction_id FROM cycle_funding`,
+		`DROP TABLE cycle_funding`,
+		`ALTER TABLE cycle_funding_entries RENAME TO cycle_funding`,
+		`CREATE INDEX IF NOT EXISTS idx_cycle_funding_cycle ON cycle_funding(cycle, category_id)`,
+	}
+	for _, migration := range migrations {
+		if _, err := tx.Exec(migration); err != nil {
+			return fmt.Errorf("cycle_funding entries migration failed: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// AddFundingEntry sets aside amount for a category in a cycle on top of any
+// earlier entries. date (YYYY-MM-DD) records when the money was set aside,
+// for entries logged after the fact; empty means now.
+func (c *DatabaseClient) AddFundingEntry(cycle string, categoryID int64, amount float64, note, date string) (*FundingEntry, error) {
+	if cycleSortKey(cycle) == "" {
+		return nil, fmt.Errorf("invalid cycle")
+	}
+	if err := c.ensureCycleOpen(cycle); err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("invalid funding amount")
+	}
+	fundedAt := time.Now()
+	if date != "" {
+		d, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid funding date")
+		}
+		fundedAt = d
+	}
+	e := FundingEntry{Cycle: cycle, CategoryID: categoryID, Amount: amount, Note: note, FundedAt: fundedAt.Format(time.RFC3339)}
+	result, err := c.db.Exec(
+		"INSERT INTO cycle_funding (cycle, category_id, amount, funded_at, note) VALUES (?, ?, ?, ?, ?)",
+		e.Cycle, e.CategoryID, e.Amount, e.FundedAt, e.Note,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add funding entry: %w", err)
+	}
+	e.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &e, nil
+}
+
+// DeleteFundingEntry removes a single funding entry.
+func (c *DatabaseClient) DeleteFundingEntry(id int64) error {
+	var cycle string
+	err := c.db.QueryRow("SELECT cycle FROM cycle_funding WHERE id = ?", id).Scan(&cycle)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("funding entry not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch funding entry: %w", err)
+	}
+	if err := c.ensureCycleOpen(cycle); err != nil {
+		return err
+	}
+	if _, err := c.db.Exec("DELETE FROM cycle_funding WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete funding entry: %w", err)
+	}
+	return nil
+}
+
+// GetFundingEntries lists a cycle's funding entries, oldest first.
+func (c *DatabaseClient) GetFundingEntries(cycle string) ([]FundingEntry, error) {
+	rows, err := c.db.Query(
+		"SELECT id, cycle, category_id, amount, note, transaction_id, funded_at FROM cycle_funding WHERE cycle = ? ORDER BY funded_at ASC, id ASC",
+		cycle,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query funding entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []FundingEntry
+	for rows.Next() {
+		var e FundingEntry
+		var txID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.Cycle, &e.CategoryID, &e.Amount, &e.Note, &txID, &e.FundedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan funding entry: %w", err)
+		}
+		if txID.Valid {
+			e.TransactionID = &txID.Int64
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// fundingStatuses reports funded vs budget for every allocated or goal
+// category, plus any other category that has funding. cats must carry the
+// cycle's budgets; entries may be nil when only totals are needed.
+func fundingStatuses(cats []Category, funded map[int64]float64, entries []FundingEntry) []FundingStatus {
+	byCategory := make(map[int64][]FundingEntry)
+	for _, e := range entries {
+		byCategory[e.CategoryID] = append(byCategory[e.CategoryID], e)
+	}
+
+	statuses := []FundingStatus{}
+	for _, cat := range cats {
+		amount, hasFunding := funded[cat.ID]
+		if (cat.Archived || cat.Tracking != "allocated" && cat.Type != "goal") && !hasFunding {
+			continue
+		}
+		s := FundingStatus{
+			CategoryID: cat.ID,
+			Category:   cat.Name,
+			Type:       cat.Type,
+			Budget:     cat.BudgetAmount,
+			Funded:     amount,
+			Entries:    byCategory[cat.ID],
+		}
+		if s.Budget != nil {
+			if s.Funded < *s.Budget {
+				s.Remaining = *s.Budget - s.Funded
+			} else {
+				s.Extra = s.Funded - *s.Budget
+			}
+			s.Complete = s.Funded >= *s.Budget
+		} else {
+			s.Complete = s.Funded > 0
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// GetFundingStatus reports each category's funding in a cycle against the
+// budget in effect for that cycle, with the individual entries.
+func (c *DatabaseClient) GetFundingStatus(cycle string) ([]FundingStatus, error) {
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := c.BudgetsForCycle(cycle)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cats {
+		cats[i].BudgetAmount = budgets[cats[i].ID]
+	}
+	funded, err := c.GetFunding(cycle)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.GetFundingEntries(cycle)
+	if err != nil {
+		return nil, err
+	}
+	return fundingStatuses(cats, funded, entries), nil
+}
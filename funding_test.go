@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFundingEntries_PartialEntriesSumInStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	rent := categoryByName(t, db, "Rent")                // fixed, allocated, budget 9000
+	emergency := categoryByName(t, db, "Emergency fund") // goal, allocated, budget 750
+
+	if _, err := db.AddFundingEntry("Jan 2026", rent.ID, 4500, "first half", ""); err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+	if _, err := db.AddFundingEntry("Jan 2026", emergency.ID, 750, "", ""); err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+	if _, err := db.AddFundingEntry("Jan 2026", emergency.ID, 250, "bonus", ""); err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+
+	stats, err := db.GetStats("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.FixedTotal != 4500 {
+		t.Errorf("expected fixed total 4500 from the partial rent entry, got %v", stats.FixedTotal)
+	}
+	if stats.GoalsFunded != 1000 {
+		t.Errorf("expected goals funded 1000, got %v", stats.GoalsFunded)
+	}
+	// Only the fully funded category gets its tick.
+	if len(stats.FundedCategoryIDs) != 1 || stats.FundedCategoryIDs[0] != emergency.ID {
+		t.Errorf("expected only Emergency fund ticked, got %v", stats.FundedCategoryIDs)
+	}
+
+	statuses, err := db.GetFundingStatus("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetFundingStatus failed: %v", err)
+	}
+	byID := make(map[int64]FundingStatus)
+	for _, s := range statuses {
+		byID[s.CategoryID] = s
+	}
+	if s := byID[rent.ID]; s.Funded != 4500 || s.Remaining != 4500 || s.Complete || len(s.Entries) != 1 {
+		t.Errorf("unexpected rent status: %+v", s)
+	}
+	if s := byID[emergency.ID]; s.Funded != 1000 || s.Remaining != 0 || s.Extra != 250 || !s.Complete || len(s.Entries) != 2 {
+		t.Errorf("unexpected emergency fund status: %+v", s)
+	}
+}
+
+func TestFundingEntries_SetFundingReplacesEntries(t *testing.T) {
+	db := setupTestDB(t)
+	rent := categoryByName(t, db, "Rent")
+
+	first, err := db.AddFundingEntry("Jan 2026", rent.ID, 3000, "", "")
+	if err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+	if _, err := db.AddFundingEntry("Jan 2026", rent.ID, 2000, "", ""); err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+
+	if err := db.DeleteFundingEntry(first.ID); err != nil {
+		t.Fatalf("DeleteFundingEntry failed: %v", err)
+	}
+	funding, _ := db.GetFunding("Jan 2026")
+	if funding[rent.ID] != 2000 {
+		t.Fatalf("expected 2000 left after deleting one entry, got %v", funding[rent.ID])
+	}
+	if err := db.DeleteFundingEntry(first.ID); err == nil || err.Error() != "funding entry not found" {
+		t.Errorf("expected not found on second delete, got %v", err)
+	}
+
+	if err := db.SetFunding("Jan 2026", rent.ID, 9000); err != nil {
+		t.Fatalf("SetFunding failed: %v", err)
+	}
+	entries, err := db.GetFundingEntries("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetFundingEntries failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Amount != 2000 || entries[1].Amount != 7000 {
+		t.Fatalf("expected the tick to top up the partial entry, got %+v", entries)
+	}
+	if err := db.SetFunding("Jan 2026", rent.ID, 9000); err != nil {
+		t.Fatalf("SetFunding failed: %v", err)
+	}
+	if entries, _ := db.GetFundingEntries("Jan 2026"); len(entries) != 2 {
+		t.Fatalf("expected a repeat tick to add nothing, got %+v", entries)
+	}
+
+	if err := db.DeleteFunding("Jan 2026", rent.ID); err != nil {
+		t.Fatalf("DeleteFunding failed: %v", err)
+	}
+	funding, _ = db.GetFunding("Jan 2026")
+	if _, ok := funding[rent.ID]; ok {
+		t.Error("expected unticking to clear all entries")
+	}
+}
+
+func TestFundingEntries_Validation(t *testing.T) {
+	db := setupTestDB(t)
+	rent := categoryByName(t, db, "Rent")
+
+	if _, err := db.AddFundingEntry("Jan 2026", rent.ID, 0, "", ""); err == nil {
+		t.Error("expected zero amount to be rejected")
+	}
+	if _, err := db.AddFundingEntry("2026-01", rent.ID, 100, "", ""); err == nil || err.Error() != "invalid cycle" {
+		t.Errorf("expected invalid cycle, got %v", err)
+	}
+	if _, err := db.AddFundingEntry("Jan 2026", rent.ID, 100, "", "25/01/2026"); err == nil || err.Error() != "invalid funding date" {
+		t.Errorf("expected invalid funding date, got %v", err)
+	}
+
+	entry, err := db.AddFundingEntry("Jan 2026", rent.ID, 100, "", "2026-01-25")
+	if err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+	if entry.FundedAt != "2026-01-25T00:00:00Z" {
+		t.Errorf("expected the entry dated 2026-01-25, got %s", entry.FundedAt)
+	}
+	if _, err := db.CloseCycle("Jan 2026"); err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+	if _, err := db.AddFundingEntry("Jan 2026", rent.ID, 100, "", ""); err == nil {
+		t.Error("expected closed cycle to reject new entries")
+	}
+	entries, _ := db.GetFundingEntries("Jan 2026")
+	if err := db.DeleteFundingEntry(entries[0].ID); err == nil {
+		t.Error("expected closed cycle to reject deleting entries")
+	}
+}
@@ -5,4 +5,5 @@ go 1.22
 require (
 	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/mattn/go-sqlite3 v1.14.33 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SavingsGoal is a target total for a goal-type category ("30,000 in the
+// emergency fund by December"). Progress is StartingBalance plus every
+// cycle_funding tick recorded for the category, so ticking the category off
+// through /funding is what moves a goal forward.
+type SavingsGoal struct {
+	ID              int64   `json:"id"`
+	CategoryID      int64   `json:"categoryId"`
+	Name            string  `json:"name"`
+	TargetAmount    float64 `json:"targetAmount"`
+	TargetDate      string  `json:"targetDate"`
+	StartingBalance float64 `json:"startingBalance"`
+	CreatedAt       string  `json:"createdAt"`
+}
+
+// GoalProgress is a goal with its funded total and projections as of a day.
+// MonthlyContribution is the average of the last funded cycles (or the
+// category budget when nothing is funded yet). RequiredMonthly is what each
+// remaining cycle up to TargetDate must add to finish on time.
+type GoalProgress struct {
+	SavingsGoal
+	Category            string   `json:"category"`
+	Funded              float64  `json:"funded"`
+	Remaining           float64  `json:"remaining"`
+	Percent             float64  `json:"percent"`
+	MonthlyContribution float64  `json:"monthlyContribution"`
+	RequiredMonthly     float64  `json:"requiredMonthly"`
+	CyclesLeft          int      `json:"cyclesLeft"`
+	ProjectedCycle      string   `json:"projectedCycle,omitempty"`
+	ProjectedLabel      string   `json:"projectedLabel,omitempty"`
+	OnTrack             bool     `json:"onTrack"`
+	Complete            bool     `json:"complete"`
+	LastFunded          []string `json:"lastFunded"`
+}
+
+// goalContributionWindow is how many recent funded cycles set the pace.
+const goalContributionWindow = 3
+
+func (c *DatabaseClient) migrateGoals() error {
+	_, err := c.db.Exec(`CREATE TABLE IF NOT EXISTS savings_goals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		category_id INTEGER NOT NULL UNIQUE,
+		name TEXT NOT NULL,
+		target_amount REAL NOT NULL,
+		target_date TEXT NOT NULL,
+		starting_balance REAL NOT NULL DEFAULT 0,
+		created_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("savings_goals migration failed: %w", err)
+	}
+	return nil
+}
+
+func validateGoal(g SavingsGoal) error {
+	if g.TargetAmount <= 0 {
+		return fmt.Errorf("invalid goal target amount")
+	}
+	if _, err := time.Parse("2006-01-02", g.TargetDate); err != nil {
+		return fmt.Errorf("invalid goal target date")
+	}
+	return nil
+}
+
+const goalColumns = "id, category_id, name, target_amount, target_date, starting_balance, created_at"
+
+func (c *DatabaseClient) getGoals(where string, args ...interface{}) ([]SavingsGoal, error) {
+	rows, err := c.db.Query("SELECT "+goalColumns+" FROM savings_goals "+where+" ORDER BY target_date ASC, id ASC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []SavingsGoal{}
+	for rows.Next() {
+		var g SavingsGoal
+		if err := rows.Scan(&g.ID, &g.CategoryID, &g.Name, &g.TargetAmount, &g.TargetDate, &g.StartingBalance, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+func (c *DatabaseClient) getGoal(id int64) (*SavingsGoal, error) {
+	goals, err := c.getGoals("WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(goals) == 0 {
+		return nil, fmt.Errorf("goal not found")
+	}
+	return &goals[0], nil
+}
+
+// goalForCategory returns the goal attached to a category, or nil.
+func (c *DatabaseClient) goalForCategory(categoryID int64) (*SavingsGoal, error) {
+	goals, err := c.getGoals("WHERE category_id = ?", categoryID)
+	if err != nil || len(goals) == 0 {
+		return nil, err
+	}
+	return &goals[0], nil
+}
+
+// CreateGoal attaches a goal to a goal-type category. A category carries at
+// most one goal, since progress is read from its funding history.
+func (c *DatabaseClient) CreateGoal(g SavingsGoal) (*SavingsGoal, error) {
+	if err := validateGoal(g); err != nil {
+		return nil, err
+	}
+	cat, err := c.GetCategory(g.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if cat.Type != "goal" {
+		return nil, fmt.Errorf("category is not a goal")
+	}
+	if existing, err := c.goalForCategory(cat.ID); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, fmt.Errorf("category already has a goal")
+	}
+	if g.Name == "" {
+		g.Name = cat.Name
+	}
+
+	g.CreatedAt = time.Now().Format(time.RFC3339)
+	result, err := c.db.Exec(
+		"INSERT INTO savings_goals (category_id, name, target_amount, target_date, starting_balance, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		g.CategoryID, g.Name, g.TargetAmount, g.TargetDate, g.StartingBalance, g.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+	g.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &g, nil
+}
+
+// UpdateGoal changes a goal's name, target and starting balance. The
+// category cannot change.
+func (c *DatabaseClient) UpdateGoal(g SavingsGoal) error {
+	if err := validateGoal(g); err != nil {
+		return err
+	}
+	result, err := c.db.Exec(
+		"UPDATE savings_goals SET name = ?, target_amount = ?, target_date = ?, starting_balance = ? WHERE id = ?",
+		g.Name, g.TargetAmount, g.TargetDate, g.StartingBalance, g.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update goal: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("goal not found")
+	}
+	return nil
+}
+
+func (c *DatabaseClient) DeleteGoal(id int64) error {
+	result, err := c.db.Exec("DELETE FROM savings_goals WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("goal not found")
+	}
+	return nil
+}
+
+// fundingHistory returns a category's funded total per cycle, newest cycle first.
+func (c *DatabaseClient) fundingHistory(categoryID int64) ([]string, []float64, error) {
+	rows, err := c.db.Query("SELECT cycle, SUM(amount) FROM cycle_funding WHERE category_id = ? GROUP BY cycle", categoryID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query funding: %w", err)
+	}
+	defer rows.Close()
+
+	type tick struct {
+		cycle  string
+		amount float64
+	}
+	var ticks []tick
+	for rows.Next() {
+		var t tick
+		if err := rows.Scan(&t.cycle, &t.amount); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan funding: %w", err)
+		}
+		ticks = append(ticks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	// cycle keys ("Jan 2006") don't sort as text; order by their sort key.
+	sort.Slice(ticks, func(i, j int) bool {
+		return cycleSortKey(ticks[i].cycle) > cycleSortKey(ticks[j].cycle)
+	})
+	cycles := make([]string, len(ticks))
+	amounts := make([]float64, len(ticks))
+	for i, t := range ticks {
+		cycles[i], amounts[i] = t.cycle, t.amount
+	}
+	return cycles, amounts, nil
+}
+
+// goalProgress computes a goal's funded total and projections as of now.
+// Contributions are assumed to land once per cycle, starting with the
+// current cycle unless it is already funded.
+func (c *DatabaseClient) goalProgress(g SavingsGoal, now time.Time) (*GoalProgress, error) {
+	cat, err := c.GetCategory(g.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	cycles, amounts, err := c.fundingHistory(g.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &GoalProgress{SavingsGoal: g, Category: cat.Name, Funded: g.StartingBalance, LastFunded: []string{}}
+	for _, a := range amounts {
+		p.Funded += a
+	}
+	p.Remaining = math.Max(0, g.TargetAmount-p.Funded)
+	p.Percent = math.Min(100, p.Funded/g.TargetAmount*100)
+	p.Complete = p.Remaining == 0
+
+	var recent float64
+	n := 0
+	for i := 0; i < len(amounts) && n < goalContributionWindow; i++ {
+		recent += amounts[i]
+		p.LastFunded = append(p.LastFunded, cycles[i])
+		n++
+	}
+	if n > 0 {
+		p.MonthlyContribution = recent / float64(n)
+	} else if budget, err := c.BudgetFor(cat.ID, calculateBillingCycle(now.Format("2006-01-02"))); err == nil && budget != nil {
+		p.MonthlyContribution = *budget
+	}
+
+	current := calculateBillingCycle(now.Format("2006-01-02"))
+	next := current
+	if len(cycles) > 0 && cycles[0] == current {
+		next = shiftCycle(current, 1)
+	}
+
+	deadline := calculateBillingCycle(g.TargetDate)
+	p.CyclesLeft = len(cyclesBetween(next, deadline))
+
+	if p.Complete {
+		p.OnTrack = true
+		return p, nil
+	}
+	if p.CyclesLeft > 0 {
+		p.RequiredMonthly = p.Remaining / float64(p.CyclesLeft)
+	} else {
+		p.RequiredMonthly = p.Remaining
+	}
+	if p.MonthlyContribution > 0 {
+		needed := int(math.Ceil(p.Remaining/p.MonthlyContribution - 1e-9))
+		p.ProjectedCycle = shiftCycle(next, needed-1)
+		p.ProjectedLabel = cycleDisplayLabel(p.ProjectedCycle)
+		p.OnTrack = cycleSortKey(p.ProjectedCycle) <= cycleSortKey(deadline)
+	}
+	return p, nil
+}
+
+// GetGoalProgress lists every goal with its progress as of now.
+func (c *DatabaseClient) GetGoalProgress(now time.Time) ([]GoalProgress, error) {
+	goals, err := c.getGoals("")
+	if err != nil {
+		return nil, err
+	}
+	progress := []GoalProgress{}
+	for _, g := range goals {
+		p, err := c.goalProgress(g, now)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, *p)
+	}
+	return progress, nil
+}
+
+// FundGoal sets money aside for the goal's category in a cycle through the
+// same cycle_funding entries /funding writes. An explicit amount is added as
+// a new entry; without one the category is topped up to its budget.
+func (c *DatabaseClient) FundGoal(id int64, cycle string, amount *float64) (*GoalProgress, error) {
+	g, err := c.getGoal(id)
+	if err != nil {
+		return nil, err
+	}
+	if cycle == "" {
+		cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+	}
+	if cycleSortKey(cycle) == "" {
+		return nil, fmt.Errorf("invalid cycle")
+	}
+	if amount != nil {
+		if _, err := c.AddFundingEntry(cycle, g.CategoryID, *amount, "", ""); err != nil {
+			return nil, err
+		}
+		return c.goalProgress(*g, time.Now())
+	}
+	budget, err := c.BudgetFor(g.CategoryID, cycle)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		return nil, fmt.Errorf("goal category has no budget to set aside")
+	}
+	if err := c.SetFunding(cycle, g.CategoryID, *budget); err != nil {
+		return nil, err
+	}
+	return c.goalProgress(*g, time.Now())
+}
+
+func writeGoalError(w http.ResponseWriter, err error, fallback string) {
+	msg := err.Error()
+	switch {
+	case msg == "goal not found", msg == "category not found":
+		http.Error(w, strings.ToUpper(msg[:1])+msg[1:], http.StatusNotFound)
+	case strings.HasPrefix(msg, "cycle closed:"):
+		writeCycleClosed(w, err)
+	case strings.HasPrefix(msg, "invalid goal"), msg == "invalid cycle", msg == "invalid funding amount", msg == "category is not a goal",
+		msg == "category already has a goal", msg == "goal category has no budget to set aside":
+		http.Error(w, msg, http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// goalsHandler serves savings goals:
+//
+//	GET    /goals            — all goals with progress and projections
+//	POST   /goals            — create a goal on a goal-type category
+//	PUT    /goals/:id        — update name/target/starting balance
+//	DELETE /goals/:id        — delete a goal (funding history is kept)
+//	POST   /goals/:id/fund   — tick the category off for {cycle} ({amount} optional)
+func goalsHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, "/")
+
+		if path == "/goals" {
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /goals - Request from %s", r.RemoteAddr)
+				goals, err := db.GetGoalProgress(time.Now())
+				if err != nil {
+					log.Printf("[API] Failed to get goals: %v", err)
+					http.Error(w, "Failed to retrieve goals", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"goals":   goals,
+				})
+
+			case http.MethodPost:
+				log.Printf("[API] POST /goals - Create goal from %s", r.RemoteAddr)
+				var req SavingsGoal
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				goal, err := db.CreateGoal(req)
+				if err != nil {
+					log.Printf("[API] Failed to create goal: %v", err)
+					writeGoalError(w, err, "Failed to create goal")
+					return
+				}
+				progress, err := db.goalProgress(*goal, time.Now())
+				if err != nil {
+					log.Printf("[API] Failed to get goal progress: %v", err)
+					http.Error(w, "Failed to create goal", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"goal":    progress,
+				})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		if !strings.HasPrefix(path, "/goals/") {
+			http.NotFound(w, r)
+			return
+		}
+		rest := path[len("/goals/"):]
+		fund := strings.HasSuffix(rest, "/fund")
+		rest = strings.TrimSuffix(rest, "/fund")
+		var id int64
+		if _, err := fmt.Sscanf(rest, "%d", &id); err != nil {
+			http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+			return
+		}
+
+		if fund {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] POST /goals/%d/fund - Fund goal from %s", id, r.RemoteAddr)
+			var req struct {
+				Cycle  string   `json:"cycle"`
+				Amount *float64 `json:"amount"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Amount != nil && *req.Amount <= 0 {
+				http.Error(w, "Amount must be greater than 0", http.StatusBadRequest)
+				return
+			}
+			progress, err := db.FundGoal(id, req.Cycle, req.Amount)
+			if err != nil {
+				log.Printf("[API] Failed to fund goal: %v", err)
+				writeGoalError(w, err, "Failed to fund goal")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"goal":    progress,
+			})
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			log.Printf("[API] PUT /goals/%d - Update goal from %s", id, r.RemoteAddr)
+			var req SavingsGoal
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Name == "" {
+				http.Error(w, "Name is required", http.StatusBadRequest)
+				return
+			}
+			req.ID = id
+			if err := db.UpdateGoal(req); err != nil {
+				log.Printf("[API] Failed to update goal: %v", err)
+				writeGoalError(w, err, "Failed to update goal")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		case http.MethodDelete:
+			log.Printf("[API] DELETE /goals/%d - Delete goal from %s", id, r.RemoteAddr)
+			if err := db.DeleteGoal(id); err != nil {
+				log.Printf("[API] Failed to delete goal: %v", err)
+				writeGoalError(w, err, "Failed to delete goal")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// goalProgressForCategory returns progress for the goal on a category, or
+// nil when the category has none. Used by /funding to echo goal progress.
+func (c *DatabaseClient) goalProgressForCategory(categoryID int64) (*GoalProgress, error) {
+	g, err := c.goalForCategory(categoryID)
+	if err != nil || g == nil {
+		return nil, err
+	}
+	return c.goalProgress(*g, time.Now())
+}
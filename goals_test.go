@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoals_ProgressFromFundingHistory(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, time.April, 7, 9, 0, 0, 0, time.UTC) // Mar 2026 cycle
+
+	fund := categoryByName(t, db, "Emergency fund") // budget 750
+	goal, err := db.CreateGoal(SavingsGoal{CategoryID: fund.ID, TargetAmount: 10000, TargetDate: "2026-12-31", StartingBalance: 5000})
+	if err != nil {
+		t.Fatalf("CreateGoal failed: %v", err)
+	}
+	if goal.Name != "Emergency fund" {
+		t.Errorf("expected name to default to the category, got %q", goal.Name)
+	}
+	for _, cycle := range []string{"Jan 2026", "Feb 2026"} {
+		if err := db.SetFunding(cycle, fund.ID, 750); err != nil {
+			t.Fatalf("SetFunding failed: %v", err)
+		}
+	}
+
+	p, err := db.goalProgress(*goal, now)
+	if err != nil {
+		t.Fatalf("goalProgress failed: %v", err)
+	}
+	if p.Funded != 6500 || p.Remaining != 3500 {
+		t.Errorf("expected funded 6500 / remaining 3500, got %.2f / %.2f", p.Funded, p.Remaining)
+	}
+	// Mar → Dec 2026 is 10 cycles; 750 a cycle finishes in 5 (Jul 2026).
+	if p.CyclesLeft != 10 || p.RequiredMonthly != 350 {
+		t.Errorf("expected 10 cycles left at 350/month, got %d at %.2f", p.CyclesLeft, p.RequiredMonthly)
+	}
+	if p.MonthlyContribution != 750 || p.ProjectedCycle != "Jul 2026" || !p.OnTrack {
+		t.Errorf("expected projection Jul 2026 on track at 750/month, got %+v", p)
+	}
+
+	// Funding the current cycle moves the next contribution to Apr.
+	if err := db.SetFunding("Mar 2026", fund.ID, 750); err != nil {
+		t.Fatalf("SetFunding failed: %v", err)
+	}
+	p, _ = db.goalProgress(*goal, now)
+	if p.Funded != 7250 || p.CyclesLeft != 9 || p.ProjectedCycle != "Jul 2026" {
+		t.Errorf("expected 7250 funded, 9 cycles left, Jul 2026, got %+v", p)
+	}
+}
+
+func TestGoals_ValidationAndFundingEndpoint(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries")
+	if _, err := db.CreateGoal(SavingsGoal{CategoryID: groc.ID, TargetAmount: 1000, TargetDate: "2026-12-31"}); err == nil || err.Error() != "category is not a goal" {
+		t.Errorf("expected non-goal category to be rejected, got %v", err)
+	}
+
+	savings := categoryByName(t, db, "Savings")
+	goal, err := db.CreateGoal(SavingsGoal{CategoryID: savings.ID, TargetAmount: 3000, TargetDate: "2027-06-30"})
+	if err != nil {
+		t.Fatalf("CreateGoal failed: %v", err)
+	}
+	if _, err := db.CreateGoal(SavingsGoal{CategoryID: savings.ID, TargetAmount: 5000, TargetDate: "2027-06-30"}); err == nil {
+		t.Error("expected second goal on the same category to be rejected")
+	}
+
+	p, err := db.FundGoal(goal.ID, "Feb 2026", floatPtr(1200))
+	if err != nil {
+		t.Fatalf("FundGoal failed: %v", err)
+	}
+	if p.Funded != 1200 {
+		t.Errorf("expected funded 1200, got %.2f", p.Funded)
+	}
+	funding, _ := db.GetFunding("Feb 2026")
+	if funding[savings.ID] != 1200 {
+		t.Errorf("expected cycle_funding tick of 1200, got %.2f", funding[savings.ID])
+	}
+	if _, err := db.FundGoal(goal.ID, "Feb 2026", floatPtr(300)); err != nil {
+		t.Fatalf("FundGoal failed: %v", err)
+	}
+	if entries, _ := db.GetFundingEntries("Feb 2026"); len(entries) != 2 {
+		t.Fatalf("expected an explicit amount to add an entry, got %+v", entries)
+	}
+	if _, err := db.FundGoal(goal.ID, "Feb 2026", nil); err != nil {
+		t.Fatalf("FundGoal failed: %v", err)
+	}
+	if funding, _ = db.GetFunding("Feb 2026"); funding[savings.ID] != 2000 {
+		t.Errorf("expected the tick to top Feb up to the 2000 budget, got %.2f", funding[savings.ID])
+	}
+
+	p, err = db.FundGoal(goal.ID, "Mar 2026", nil)
+	if err != nil {
+		t.Fatalf("FundGoal failed: %v", err)
+	}
+	if p.Funded != 4000 || !p.Complete {
+		t.Errorf("expected goal complete at 4000 (budget 2000 added), got %+v", p)
+	}
+
+	if _, err := db.FundGoal(goal.ID, "2026-04", floatPtr(100)); err == nil || err.Error() != "invalid cycle" {
+		t.Errorf("expected invalid cycle, got %v", err)
+	}
+	w := httptest.NewRecorder()
+	goalsHandler(db)(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/goals/%d/fund", goal.ID), strings.NewReader(`{"cycle": "April"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad cycle, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,690 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IncomeSource is a recurring income line (salary, partner's salary, side
+// income) paid every cycle from StartCycle through EndCycle. An empty
+// StartCycle means "since always", an empty EndCycle "still paid". A raise
+// ends the old row and starts a new one with the same name, so past cycles
+// keep the amount that was paid then.
+type IncomeSource struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Amount     float64 `json:"amount"`
+	StartCycle string  `json:"startCycle"`
+	EndCycle   string  `json:"endCycle"`
+	// MatchKeyword links the source to negative-amount Income/Transfer
+	// transactions whose description contains it (case-insensitive).
+	MatchKeyword string `json:"matchKeyword"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// IncomeEntry is income actually received in a cycle: a one-off (bonus,
+// refund) when SourceID is nil, or a matched payment of a recurring source.
+type IncomeEntry struct {
+	ID            int64   `json:"id"`
+	Cycle         string  `json:"cycle"`
+	Description   string  `json:"description"`
+	Amount        float64 `json:"amount"`
+	SourceID      *int64  `json:"sourceId,omitempty"`
+	TransactionID *int64  `json:"transactionId,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+}
+
+// IncomeLine is one contributor to a cycle's income. For recurring sources
+// Amount is the matched actual when any payment was matched, else Expected.
+type IncomeLine struct {
+	SourceID *int64  `json:"sourceId,omitempty"`
+	EntryID  *int64  `json:"entryId,omitempty"`
+	Name     string  `json:"name"`
+	Expected float64 `json:"expected"`
+	Amount   float64 `json:"amount"`
+	Matched  bool    `json:"matched"`
+}
+
+type IncomeSummary struct {
+	Cycle string       `json:"cycle"`
+	Total float64      `json:"total"`
+	Lines []IncomeLine `json:"lines"`
+}
+
+func (c *DatabaseClient) migrateIncome() error {
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS income_sources (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			amount REAL NOT NULL,
+			start_cycle TEXT NOT NULL DEFAULT '',
+			start_key TEXT NOT NULL DEFAULT '',
+			end_cycle TEXT NOT NULL DEFAULT '',
+			end_key TEXT NOT NULL DEFAULT '',
+			match_keyword TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS income_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cycle TEXT NOT NULL,
+			description TEXT NOT NULL,
+			amount REAL NOT NULL,
+			source_id INTEGER,
+			transaction_id INTEGER UNIQUE,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_income_entries_cycle ON income_entries(cycle)`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("income migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// seedIncomeSources turns the legacy monthly_salary setting into a "Salary"
+// source covering all cycles, once. Runs after runBudgetingSetup.
+func (c *DatabaseClient) seedIncomeSources() error {
+	done, _ := c.GetSetting("income_v1")
+	if done == "1" {
+		return nil
+	}
+	var count int
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM income_sources").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count income sources: %w", err)
+	}
+	if salary := c.GetSalary(); count == 0 && salary > 0 {
+		if _, err := c.db.Exec(
+			"INSERT INTO income_sources (name, amount, created_at) VALUES ('Salary', ?, ?)",
+			salary, time.Now().Format(time.RFC3339),
+		); err != nil {
+			return fmt.Errorf("seed salary source: %w", err)
+		}
+	}
+	if err := c.SetSetting("income_v1", "1"); err != nil {
+		return fmt.Errorf("mark income setup done: %w", err)
+	}
+	return nil
+}
+
+func scanIncomeSources(rows *sql.Rows) ([]IncomeSource, error) {
+	defer rows.Close()
+	var sources []IncomeSource
+	for rows.Next() {
+		var s IncomeSource
+		if err := rows.Scan(&s.ID, &s.Name, &s.Amount, &s.StartCycle, &s.EndCycle, &s.MatchKeyword, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan income source: %w", err)
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
+
+const incomeSourceColumns = "id, name, amount, start_cycle, end_cycle, match_keyword, created_at"
+
+// GetIncomeSources lists every source row, including ended ones.
+func (c *DatabaseClient) GetIncomeSources() ([]IncomeSource, error) {
+	rows, err := c.db.Query("SELECT " + incomeSourceColumns + " FROM income_sources ORDER BY name ASC, start_key ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query income sources: %w", err)
+	}
+	return scanIncomeSources(rows)
+}
+
+// activeIncomeSources returns the sources paid in a cycle.
+func (c *DatabaseClient) activeIncomeSources(cycle string) ([]IncomeSource, error) {
+	key := cycleSortKey(cycle)
+	rows, err := c.db.Query(
+		"SELECT "+incomeSourceColumns+" FROM income_sources WHERE start_key <= ? AND (end_key = '' OR end_key >= ?) ORDER BY id ASC",
+		key, key,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query income sources: %w", err)
+	}
+	return scanIncomeSources(rows)
+}
+
+func (c *DatabaseClient) getIncomeSource(id int64) (*IncomeSource, error) {
+	rows, err := c.db.Query("SELECT "+incomeSourceColumns+" FROM income_sources WHERE id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query income source: %w", err)
+	}
+	sources, err := scanIncomeSources(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("income source not found")
+	}
+	return &sources[0], nil
+}
+
+// validIncomeCycles checks optional cycle keys; an empty one is allowed, a
+// malformed one would sort as "" and leave the source open-ended.
+func validIncomeCycles(cycles ...string) error {
+	for _, cycle := range cycles {
+		if cycle != "" && cycleSortKey(cycle) == "" {
+			return fmt.Errorf("invalid cycle")
+		}
+	}
+	return nil
+}
+
+func (c *DatabaseClient) CreateIncomeSource(name string, amount float64, startCycle, endCycle, matchKeyword string) (*IncomeSource, error) {
+	if err := validIncomeCycles(startCycle, endCycle); err != nil {
+		return nil, err
+	}
+	return insertIncomeSource(c.db, name, amount, startCycle, endCycle, matchKeyword)
+}
+
+func insertIncomeSource(ex execer, name string, amount float64, startCycle, endCycle, matchKeyword string) (*IncomeSource, error) {
+	now := time.Now().Format(time.RFC3339)
+	result, err := ex.Exec(
+		`INSERT INTO income_sources (name, amount, start_cycle, start_key, end_cycle, end_key, match_keyword, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		name, amount, startCycle, cycleSortKey(startCycle), endCycle, cycleSortKey(endCycle), matchKeyword, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create income source: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &IncomeSource{
+		ID:           id,
+		Name:         name,
+		Amount:       amount,
+		StartCycle:   startCycle,
+		EndCycle:     endCycle,
+		MatchKeyword: matchKeyword,
+		CreatedAt:    now,
+	}, nil
+}
+
+// ChangeIncomeAmount changes a source's amount from cycle forward. Earlier
+// cycles keep the old amount: the row is ended the cycle before and a new
+// row with the same name and keyword takes over.
+func (c *DatabaseClient) ChangeIncomeAmount(id int64, cycle string, amount float64) (*IncomeSource, error) {
+	if cycle == "" {
+		cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+	}
+	if err := validIncomeCycles(cycle); err != nil {
+		return nil, err
+	}
+	src, err := c.getIncomeSource(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	changed, err := changeIncomeAmount(tx, src, cycle, amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit income change: %w", err)
+	}
+	return changed, nil
+}
+
+// changeIncomeAmount does ChangeIncomeAmount's writes inside an existing
+// SQL transaction.
+func changeIncomeAmount(tx *sql.Tx, src *IncomeSource, cycle string, amount float64) (*IncomeSource, error) {
+	if src.StartCycle == cycle {
+		if _, err := tx.Exec("UPDATE income_sources SET amount = ? WHERE id = ?", amount, src.ID); err != nil {
+			return nil, fmt.Errorf("failed to update income source: %w", err)
+		}
+		changed := *src
+		changed.Amount = amount
+		return &changed, nil
+	}
+	if err := endIncomeSource(tx, src.ID, cycle); err != nil {
+		return nil, err
+	}
+	return insertIncomeSource(tx, src.Name, amount, cycle, src.EndCycle, src.MatchKeyword)
+}
+
+// EndIncomeSource stops a source from cycle onward; it was last paid the
+// cycle before.
+func (c *DatabaseClient) EndIncomeSource(id int64, cycle string) error {
+	if cycle == "" {
+		cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+	}
+	if err := validIncomeCycles(cycle); err != nil {
+		return err
+	}
+	return endIncomeSource(c.db, id, cycle)
+}
+
+func endIncomeSource(ex execer, id int64, cycle string) error {
+	last := shiftCycle(cycle, -1)
+	result, err := ex.Exec(
+		"UPDATE income_sources SET end_cycle = ?, end_key = ? WHERE id = ?",
+		last, cycleSortKey(last), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to end income source: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("income source not found")
+	}
+	return nil
+}
+
+// AddIncomeEntry records income received in a cycle.
+func (c *DatabaseClient) AddIncomeEntry(entry IncomeEntry) (*IncomeEntry, error) {
+	if cycleSortKey(entry.Cycle) == "" {
+		return nil, fmt.Errorf("invalid cycle")
+	}
+	if entry.SourceID != nil {
+		if _, err := c.getIncomeSource(*entry.SourceID); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.ensureCycleOpen(entry.Cycle); err != nil {
+		return nil, err
+	}
+	entry.CreatedAt = time.Now().Format(time.RFC3339)
+	result, err := c.db.Exec(
+		"INSERT INTO income_entries (cycle, description, amount, source_id, transaction_id, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		entry.Cycle, entry.Description, entry.Amount, entry.SourceID, entry.TransactionID, entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add income entry: %w", err)
+	}
+	entry.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &entry, nil
+}
+
+func (c *DatabaseClient) DeleteIncomeEntry(id int64) error {
+	var cycle string
+	err := c.db.QueryRow("SELECT cycle FROM income_entries WHERE id = ?", id).Scan(&cycle)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("income entry not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch income entry: %w", err)
+	}
+	if err := c.ensureCycleOpen(cycle); err != nil {
+		return err
+	}
+	if _, err := c.db.Exec("DELETE FROM income_entries WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete income entry: %w", err)
+	}
+	return nil
+}
+
+func (c *DatabaseClient) getIncomeEntries(cycle string) ([]IncomeEntry, error) {
+	rows, err := c.db.Query(
+		"SELECT id, cycle, description, amount, source_id, transaction_id, created_at FROM income_entries WHERE cycle = ? ORDER BY id ASC",
+		cycle,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query income entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []IncomeEntry
+	for rows.Next() {
+		var e IncomeEntry
+		var sourceID, txID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.Cycle, &e.Description, &e.Amount, &sourceID, &txID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan income entry: %w", err)
+		}
+		if sourceID.Valid {
+			e.SourceID = &sourceID.Int64
+		}
+		if txID.Valid {
+			e.TransactionID = &txID.Int64
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// IncomeForCycle totals what came in during a cycle: every active recurring
+// source (its matched payments if any, else its expected amount) plus
+// one-off entries. With no sources or entries at all it falls back to the
+// legacy monthly_salary setting.
+func (c *DatabaseClient) IncomeForCycle(cycle string) (*IncomeSummary, error) {
+	sources, err := c.activeIncomeSources(cycle)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.getIncomeEntries(cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &IncomeSummary{Cycle: cycle, Lines: []IncomeLine{}}
+	matched := make(map[int64]float64)
+	hasMatch := make(map[int64]bool)
+	for _, e := range entries {
+		if e.SourceID == nil {
+			id := e.ID
+			summary.Lines = append(summary.Lines, IncomeLine{EntryID: &id, Name: e.Description, Amount: e.Amount})
+			summary.Total += e.Amount
+			continue
+		}
+		matched[*e.SourceID] += e.Amount
+		hasMatch[*e.SourceID] = true
+	}
+	for _, s := range sources {
+		id := s.ID
+		line := IncomeLine{SourceID: &id, Name: s.Name, Expected: s.Amount, Amount: s.Amount}
+		if hasMatch[s.ID] {
+			line.Amount = matched[s.ID]
+			line.Matched = true
+		}
+		summary.Lines = append(summary.Lines, line)
+		summary.Total += line.Amount
+	}
+
+	if len(sources) == 0 && len(entries) == 0 {
+		salary := c.GetSalary()
+		summary.Lines = append(summary.Lines, IncomeLine{Name: "Salary", Expected: salary, Amount: salary})
+		summary.Total = salary
+	}
+	return summary, nil
+}
+
+// matchIncomeTransaction links a negative-amount Income/Transfer transaction
+// to the first active source whose keyword appears in its description. Any
+// previous match for the transaction is replaced (or dropped when it no
+// longer qualifies), so it is safe to call after every save or edit.
+func (c *DatabaseClient) matchIncomeTransaction(txID int64) error {
+	var desc, category, cycle string
+	var amount float64
+	err := c.db.QueryRow(
+		"SELECT description, amount, category, billing_cycle FROM transactions WHERE id = ?", txID,
+	).Scan(&desc, &amount, &category, &cycle)
+	if err == sql.ErrNoRows {
+		_, err = c.db.Exec("DELETE FROM income_entries WHERE transaction_id = ?", txID)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+
+	if _, err := c.db.Exec("DELETE FROM income_entries WHERE transaction_id = ?", txID); err != nil {
+		return fmt.Errorf("failed to clear income match: %w", err)
+	}
+	if amount >= 0 || category != "Income/Transfer" {
+		return nil
+	}
+
+	sources, err := c.activeIncomeSources(cycle)
+	if err != nil {
+		return err
+	}
+	lower := strings.ToLower(desc)
+	for _, s := range sources {
+		if s.MatchKeyword == "" || !strings.Contains(lower, strings.ToLower(s.MatchKeyword)) {
+			continue
+		}
+		sourceID := s.ID
+		if _, err := c.AddIncomeEntry(IncomeEntry{
+			Cycle:         cycle,
+			Description:   desc,
+			Amount:        -amount,
+			SourceID:      &sourceID,
+			TransactionID: &txID,
+		}); err != nil {
+			return err
+		}
+		log.Printf("[Database] Matched transaction %d to income source %s", txID, s.Name)
+		return nil
+	}
+	return nil
+}
+
+// incomeHandler serves the income model:
+//
+//	GET    /income?cycle=             — cycle income breakdown
+//	GET    /income/sources            — all recurring sources (incl. ended)
+//	POST   /income/sources            — add a source
+//	PUT    /income/sources/:id        — change amount from {cycle} forward
+//	DELETE /income/sources/:id        — stop the source from ?cycle= onward
+//	POST   /income/entries            — record a one-off entry
+//	DELETE /income/entries/:id        — remove an entry
+func incomeHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, "/")
+
+		switch {
+		case path == "/income":
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			cycle := r.URL.Query().Get("cycle")
+			if cycle == "" {
+				cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+			}
+			log.Printf("[API] GET /income - %s from %s", cycle, r.RemoteAddr)
+			summary, err := db.IncomeForCycle(cycle)
+			if err != nil {
+				log.Printf("[API] Failed to get income: %v", err)
+				http.Error(w, "Failed to retrieve income", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"income":  summary,
+			})
+
+		case path == "/income/sources":
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /income/sources - Request from %s", r.RemoteAddr)
+				sources, err := db.GetIncomeSources()
+				if err != nil {
+					log.Printf("[API] Failed to get income sources: %v", err)
+					http.Error(w, "Failed to retrieve income sources", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"sources": sources,
+				})
+
+			case http.MethodPost:
+				log.Printf("[API] POST /income/sources - Create source from %s", r.RemoteAddr)
+				var req struct {
+					Name         string  `json:"name"`
+					Amount       float64 `json:"amount"`
+					StartCycle   string  `json:"startCycle"`
+					EndCycle     string  `json:"endCycle"`
+					MatchKeyword string  `json:"matchKeyword"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				if req.Name == "" {
+					http.Error(w, "Name is required", http.StatusBadRequest)
+					return
+				}
+				if req.Amount <= 0 {
+					http.Error(w, "Amount must be greater than 0", http.StatusBadRequest)
+					return
+				}
+				source, err := db.CreateIncomeSource(req.Name, req.Amount, req.StartCycle, req.EndCycle, req.MatchKeyword)
+				if err != nil {
+					log.Printf("[API] Failed to create income source: %v", err)
+					if err.Error() == "invalid cycle" {
+						http.Error(w, "Invalid cycle", http.StatusBadRequest)
+					} else {
+						http.Error(w, "Failed to create income source", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"source":  source,
+				})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+
+		case strings.HasPrefix(path, "/income/sources/"):
+			var id int64
+			if _, err := fmt.Sscanf(path[len("/income/sources/"):], "%d", &id); err != nil {
+				http.Error(w, "Invalid income source ID", http.StatusBadRequest)
+				return
+			}
+			switch r.Method {
+			case http.MethodPut:
+				log.Printf("[API] PUT /income/sources/%d - Change amount from %s", id, r.RemoteAddr)
+				var req struct {
+					Amount float64 `json:"amount"`
+					Cycle  string  `json:"cycle"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				if req.Amount <= 0 {
+					http.Error(w, "Amount must be greater than 0", http.StatusBadRequest)
+					return
+				}
+				source, err := db.ChangeIncomeAmount(id, req.Cycle, req.Amount)
+				if err != nil {
+					log.Printf("[API] Failed to change income source: %v", err)
+					if err.Error() == "income source not found" {
+						http.Error(w, "Income source not found", http.StatusNotFound)
+					} else if err.Error() == "invalid cycle" {
+						http.Error(w, "Invalid cycle", http.StatusBadRequest)
+					} else {
+						http.Error(w, "Failed to update income source", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"source":  source,
+				})
+
+			case http.MethodDelete:
+				log.Printf("[API] DELETE /income/sources/%d - End source from %s", id, r.RemoteAddr)
+				if err := db.EndIncomeSource(id, r.URL.Query().Get("cycle")); err != nil {
+					log.Printf("[API] Failed to end income source: %v", err)
+					if err.Error() == "income source not found" {
+						http.Error(w, "Income source not found", http.StatusNotFound)
+					} else if err.Error() == "invalid cycle" {
+						http.Error(w, "Invalid cycle", http.StatusBadRequest)
+					} else {
+						http.Error(w, "Failed to end income source", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+
+		case path == "/income/entries":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] POST /income/entries - Add entry from %s", r.RemoteAddr)
+			var req struct {
+				Cycle       string  `json:"cycle"`
+				Description string  `json:"description"`
+				Amount      float64 `json:"amount"`
+				SourceID    *int64  `json:"sourceId"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Description == "" {
+				http.Error(w, "Description is required", http.StatusBadRequest)
+				return
+			}
+			if req.Amount <= 0 {
+				http.Error(w, "Amount must be greater than 0", http.StatusBadRequest)
+				return
+			}
+			if req.Cycle == "" {
+				req.Cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+			}
+			entry, err := db.AddIncomeEntry(IncomeEntry{
+				Cycle:       req.Cycle,
+				Description: req.Description,
+				Amount:      req.Amount,
+				SourceID:    req.SourceID,
+			})
+			if err != nil {
+				log.Printf("[API] Failed to add income entry: %v", err)
+				switch {
+				case err.Error() == "invalid cycle":
+					http.Error(w, "Invalid cycle", http.StatusBadRequest)
+				case err.Error() == "income source not found":
+					http.Error(w, "Income source not found", http.StatusNotFound)
+				case strings.HasPrefix(err.Error(), "cycle closed:"):
+					writeCycleClosed(w, err)
+				default:
+					http.Error(w, "Failed to add income entry", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"entry":   entry,
+			})
+
+		case strings.HasPrefix(path, "/income/entries/"):
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var id int64
+			if _, err := fmt.Sscanf(path[len("/income/entries/"):], "%d", &id); err != nil {
+				http.Error(w, "Invalid income entry ID", http.StatusBadRequest)
+				return
+			}
+			log.Printf("[API] DELETE /income/entries/%d - Delete entry from %s", id, r.RemoteAddr)
+			if err := db.DeleteIncomeEntry(id); err != nil {
+				log.Printf("[API] Failed to delete income entry: %v", err)
+				if err.Error() == "income entry not found" {
+					http.Error(w, "Income entry not found", http.StatusNotFound)
+				} else if strings.HasPrefix(err.Error(), "cycle closed:") {
+					writeCycleClosed(w, err)
+				} else {
+					http.Error(w, "Failed to delete income entry", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncome_SeededSalaryCoversAllCycles(t *testing.T) {
+	db := setupTestDB(t)
+
+	income, err := db.IncomeForCycle("Jan 2026")
+	if err != nil {
+		t.Fatalf("IncomeForCycle failed: %v", err)
+	}
+	if income.Total != 32500 {
+		t.Errorf("expected seeded salary 32500, got %.2f", income.Total)
+	}
+
+	stats, err := db.GetStats("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.Salary != 32500 {
+		t.Errorf("expected stats salary 32500, got %.2f", stats.Salary)
+	}
+}
+
+func TestIncome_RaiseKeepsPastCycles(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := db.SetSalary(35000); err != nil {
+		t.Fatalf("SetSalary failed: %v", err)
+	}
+
+	past, _ := db.IncomeForCycle("Jan 2026")
+	if past.Total != 32500 {
+		t.Errorf("expected Jan 2026 income to stay 32500, got %.2f", past.Total)
+	}
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	now, _ := db.IncomeForCycle(current)
+	if now.Total != 35000 {
+		t.Errorf("expected current income 35000, got %.2f", now.Total)
+	}
+
+	sources, err := db.GetIncomeSources()
+	if err != nil {
+		t.Fatalf("GetIncomeSources failed: %v", err)
+	}
+	if len(sources) != 2 {
+		t.Errorf("expected old and new Salary rows, got %d", len(sources))
+	}
+}
+
+func TestIncome_MultipleSourcesAndOneOffs(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, err := db.CreateIncomeSource("Partner salary", 18000, "Feb 2026", "", ""); err != nil {
+		t.Fatalf("CreateIncomeSource failed: %v", err)
+	}
+	if _, err := db.AddIncomeEntry(IncomeEntry{Cycle: "Feb 2026", Description: "Bonus", Amount: 5000}); err != nil {
+		t.Fatalf("AddIncomeEntry failed: %v", err)
+	}
+
+	jan, _ := db.IncomeForCycle("Jan 2026")
+	if jan.Total != 32500 {
+		t.Errorf("expected Jan 2026 income 32500, got %.2f", jan.Total)
+	}
+	feb, _ := db.IncomeForCycle("Feb 2026")
+	if feb.Total != 32500+18000+5000 {
+		t.Errorf("expected Feb 2026 income 55500, got %.2f", feb.Total)
+	}
+	if len(feb.Lines) != 3 {
+		t.Errorf("expected 3 income lines, got %d", len(feb.Lines))
+	}
+}
+
+func TestIncome_MatchesIncomeTransferTransactions(t *testing.T) {
+	db := setupTestDB(t)
+
+	src, err := db.CreateIncomeSource("Side gig", 2000, "", "", "upwork")
+	if err != nil {
+		t.Fatalf("CreateIncomeSource failed: %v", err)
+	}
+
+	id, err := db.SaveTransaction(Transaction{
+		Description:  "UPWORK ESCROW payout",
+		Amount:       -2600,
+		Date:         "2026-02-05",
+		Category:     "Income/Transfer",
+		BillingCycle: "Jan 2026",
+		Timestamp:    "2026-02-05T10:00:00Z",
+		Source:       "openai",
+	})
+	if err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+
+	income, _ := db.IncomeForCycle("Jan 2026")
+	var found bool
+	for _, line := range income.Lines {
+		if line.SourceID != nil && *line.SourceID == src.ID {
+			found = true
+			if !line.Matched || line.Amount != 2600 {
+				t.Errorf("expected matched actual 2600, got %+v", line)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("side gig line missing")
+	}
+
+	// Recategorizing the transaction away from Income/Transfer drops the match.
+	if err := db.UpdateTransaction(id, Transaction{Description: "UPWORK ESCROW payout", Amount: -2600, Date: "2026-02-05", Category: "Shopping & Gifts", BillingCycle: "Jan 2026"}); err != nil {
+		t.Fatalf("UpdateTransaction failed: %v", err)
+	}
+	income, _ = db.IncomeForCycle("Jan 2026")
+	if income.Total != 32500+2000 {
+		t.Errorf("expected income to fall back to expected amount, got %.2f", income.Total)
+	}
+}
+
+func TestIncome_InvalidCycleRejected(t *testing.T) {
+	db := setupTestDB(t)
+	sources, _ := db.GetIncomeSources()
+	salary := sources[0]
+
+	if _, err := db.ChangeIncomeAmount(salary.ID, "Foo 20", 40000); err == nil || err.Error() != "invalid cycle" {
+		t.Errorf("expected invalid cycle, got %v", err)
+	}
+	if err := db.EndIncomeSource(salary.ID, "Foo 20"); err == nil || err.Error() != "invalid cycle" {
+		t.Errorf("expected invalid cycle, got %v", err)
+	}
+	if _, err := db.CreateIncomeSource("Side gig", 2000, "Foo 20", "", ""); err == nil || err.Error() != "invalid cycle" {
+		t.Errorf("expected invalid cycle, got %v", err)
+	}
+	if _, err := db.AddIncomeEntry(IncomeEntry{Cycle: "Foo 20", Description: "Bonus", Amount: 500}); err == nil || err.Error() != "invalid cycle" {
+		t.Errorf("expected invalid cycle, got %v", err)
+	}
+	missing := int64(9999)
+	if _, err := db.AddIncomeEntry(IncomeEntry{Cycle: "Jan 2026", Description: "Bonus", Amount: 500, SourceID: &missing}); err == nil || err.Error() != "income source not found" {
+		t.Errorf("expected income source not found, got %v", err)
+	}
+	w := httptest.NewRecorder()
+	incomeHandler(db)(w, httptest.NewRequest(http.MethodPost, "/income/entries", strings.NewReader(`{"cycle": "2026-01", "description": "Bonus", "amount": 500}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad cycle, got %d: %s", w.Code, w.Body.String())
+	}
+	if after, _ := db.GetIncomeSources(); len(after) != 1 || after[0].EndCycle != "" {
+		t.Errorf("expected the salary left alone, got %+v", after)
+	}
+	if jan, _ := db.IncomeForCycle("Jan 2026"); jan.Total != 32500 {
+		t.Errorf("expected Jan 2026 income unchanged, got %.2f", jan.Total)
+	}
+}
@@ -27,10 +27,11 @@ type TransactionRequest struct {
 }
 
 type ManualTransactionRequest struct {
-	Description string  `json:"description"`
-	Amount      float64 `json:"amount"`
-	Date        string  `json:"date"`
-	Category    string  `json:"category"`
+	Description string   `json:"description"`
+	Amount      float64  `json:"amount"`
+	Date        string   `json:"date"`
+	Category    string   `json:"category"`
+	Tags        []string `json:"tags"`
 }
 
 type TransactionResponse struct {
@@ -39,6 +40,9 @@ type TransactionResponse struct {
 	Count        int           `json:"count"`
 	Total        float64       `json:"total"`
 	Transactions []Transaction `json:"transactions,omitempty"`
+	// Rejected lists transactions that were not saved because their billing
+	// cycle is closed.
+	Rejected []string `json:"rejected,omitempty"`
 }
 
 type StatsResponse struct {
@@ -46,6 +50,7 @@ type StatsResponse struct {
 	Message             string              `json:"message"`
 	Cycle               string              `json:"cycle"`
 	CycleLabel          string              `json:"cycleLabel"`
+	Tag                 string              `json:"tag,omitempty"`
 	Total               float64             `json:"total"`
 	Count               int                 `json:"count"`
 	Categories          []CategoryStats     `json:"categories,omitempty"`
@@ -53,15 +58,21 @@ type StatsResponse struct {
 	AllTransactions     []Transaction       `json:"allTransactions,omitempty"`
 	CategoryDefinitions []Category          `json:"categoryDefinitions,omitempty"`
 	AvailableCycles     []CycleOption       `json:"availableCycles,omitempty"`
-	Salary            float64 `json:"salary"`
-	FixedTotal        float64 `json:"fixed_total"`
-	WantsTotal        float64 `json:"wants_total"`
-	GoalsFunded       float64 `json:"goals_funded"`
-	SalarySpent       float64 `json:"salary_spent"`
-	FixedBudget       float64 `json:"fixed_budget"`
-	WantsBudget       float64 `json:"wants_budget"`
-	GoalsBudget       float64 `json:"goals_budget"`
-	FundedCategoryIDs []int64 `json:"fundedCategoryIds"`
+	Salary              float64             `json:"salary"`
+	FixedTotal          float64             `json:"fixed_total"`
+	WantsTotal          float64             `json:"wants_total"`
+	GoalsFunded         float64             `json:"goals_funded"`
+	SalarySpent         float64             `json:"salary_spent"`
+	FixedBudget         float64             `json:"fixed_budget"`
+	WantsBudget         float64             `json:"wants_budget"`
+	GoalsBudget         float64             `json:"goals_budget"`
+	FundedCategoryIDs   []int64             `json:"fundedCategoryIds"`
+	Funding             []FundingStatus     `json:"funding,omitempty"`
+	Closed              bool                `json:"closed"`
+	Envelopes           []CarryoverEntry    `json:"envelopes,omitempty"`
+	Income              *IncomeSummary      `json:"income,omitempty"`
+	Forecast            *Forecast           `json:"forecast,omitempty"`
+	SinkingFunds        []SinkingFundStatus `json:"sinkingFunds,omitempty"`
 }
 
 type CategoryStats struct {
@@ -69,7 +80,12 @@ type CategoryStats struct {
 	Emoji        string        `json:"emoji"`
 	Total        float64       `json:"total"`
 	Count        int           `json:"count"`
+	Budget       *float64      `json:"budget,omitempty"`
+	Carried      float64       `json:"carried,omitempty"`
+	Available    *float64      `json:"available,omitempty"`
 	Transactions []Transaction `json:"transactions,omitempty"`
+	// Children breaks a parent's rolled-up total down by sub-category.
+	Children []CategoryStats `json:"children,omitempty"`
 }
 
 type TransactionSummary struct {
@@ -138,6 +154,7 @@ func main() {
 	http.HandleFunc("/transaction/manual", manualTransactionHandler(dbClient))
 	http.HandleFunc("/transaction", transactionHandler(openAIClient, dbClient))
 	http.HandleFunc("/transaction/", transactionDetailHandler(dbClient))
+	http.HandleFunc("/review", reviewHandler(dbClient))
 	http.HandleFunc("/dashboard", dashboardHandler(dbClient))
 	http.HandleFunc("/export", exportHandler(dbClient))
 	http.HandleFunc("/import", importHandler(dbClient))
@@ -146,7 +163,22 @@ func main() {
 	http.HandleFunc("/categories", categoriesHandler(dbClient))
 	http.HandleFunc("/categories/", categoryDetailHandler(dbClient))
 	http.HandleFunc("/funding", fundingHandler(dbClient))
+	http.HandleFunc("/funding/", fundingDetailHandler(dbClient))
 	http.HandleFunc("/salary", salaryHandler(dbClient))
+	http.HandleFunc("/budget/template", budgetTemplateHandler(dbClient))
+	http.HandleFunc("/budget/simulate", budgetSimulateHandler(dbClient))
+	http.HandleFunc("/cycles/", cyclesHandler(dbClient))
+	http.HandleFunc("/income", incomeHandler(dbClient))
+	http.HandleFunc("/income/", incomeHandler(dbClient))
+	http.HandleFunc("/alerts/", alertsHandler(dbClient))
+	http.HandleFunc("/forecast", forecastHandler(dbClient))
+	http.HandleFunc("/safe-to-spend", safeToSpendHandler(dbClient))
+	http.HandleFunc("/goals", goalsHandler(dbClient))
+	http.HandleFunc("/goals/", goalsHandler(dbClient))
+	http.HandleFunc("/sinking-funds", sinkingFundsHandler(dbClient))
+	http.HandleFunc("/reports/", reportsHandler(dbClient))
+	http.HandleFunc("/tags", tagsHandler(dbClient))
+	http.HandleFunc("/tags/", tagsHandler(dbClient))
 	http.Handle("/js/", staticHandler)
 	http.HandleFunc("/", indexHandler)
 
@@ -162,22 +194,86 @@ func main() {
 	log.Printf("[Server]   POST   /transaction/manual - Add manual transaction")
 	log.Printf("[Server]   PUT    /transaction/:id - Update transaction")
 	log.Printf("[Server]   DELETE /transaction/:id - Delete transaction")
+	log.Printf("[Server]   GET    /transaction/:id/splits - Get split lines")
+	log.Printf("[Server]   PUT    /transaction/:id/splits - Split across categories")
+	log.Printf("[Server]   GET    /transaction/:id/rule-log - Changes merchant rules made")
+	log.Printf("[Server]   POST   /transaction/:id/approve - Approve out of the review queue")
+	log.Printf("[Server]   GET    /review        - Transactions awaiting review")
 	log.Printf("[Server]   GET    /dashboard     - Get dashboard data (renamed from /stats)")
+	log.Printf("[Server]   GET    /forecast      - End-of-cycle spending forecast")
+	log.Printf("[Server]   GET    /safe-to-spend - Today's spending allowance (JSON or ?format=text)")
+	log.Printf("[Server]   GET    /goals         - Savings goals with progress")
+	log.Printf("[Server]   POST   /goals         - Create savings goal")
+	log.Printf("[Server]   PUT    /goals/:id     - Update savings goal")
+	log.Printf("[Server]   DELETE /goals/:id     - Delete savings goal")
+	log.Printf("[Server]   POST   /goals/:id/fund - Fund a goal for a cycle")
+	log.Printf("[Server]   GET    /sinking-funds - Sinking fund balances + warnings")
+	log.Printf("[Server]   GET    /reports/budget-vs-actual - Budget vs actual per category and cycle (JSON or ?format=csv)")
+	log.Printf("[Server]   GET    /funding - Funded vs budget per category (?cycle=)")
+	log.Printf("[Server]   DELETE /funding/entries/:id - Remove one funding entry")
+	log.Printf("[Server]   GET    /funding/matchers - List auto-fund matchers")
+	log.Printf("[Server]   POST   /funding/matchers - Create auto-fund matcher")
+	log.Printf("[Server]   DELETE /funding/matchers/:id - Delete auto-fund matcher")
+	log.Printf("[Server]   GET    /funding/audit - Auto-fund audit log")
+	log.Printf("[Server]   POST   /funding/audit/:id/undo - Undo an automatic funding")
+	log.Printf("[Server]   GET    /budget/template - Export budget template (JSON or ?format=yaml)")
+	log.Printf("[Server]   POST   /budget/template - Preview/apply budget template")
+	log.Printf("[Server]   POST   /budget/simulate - What-if budget changes over recent cycles")
 	log.Printf("[Server]   GET    /export        - Export CSV")
 	log.Printf("[Server]   POST   /import        - Import CSV")
 	log.Printf("[Server]   GET    /categories    - Get all categories")
 	log.Printf("[Server]   POST   /categories    - Create category")
 	log.Printf("[Server]   PUT    /categories/:id - Update category")
 	log.Printf("[Server]   DELETE /categories/:id - Delete category")
+	log.Printf("[Server]   PUT    /categories/:id/parent - Move under a parent category")
+	log.Printf("[Server]   POST   /categories/:id/merge-into/:target - Merge into another category")
+	log.Printf("[Server]   POST   /categories/:id/archive - Archive category")
+	log.Printf("[Server]   POST   /categories/:id/restore - Restore archived category")
 	log.Printf("[Server]   PUT    /categories/:id/target - Set category target")
 	log.Printf("[Server]   DELETE /categories/:id/target - Remove category target")
+	log.Printf("[Server]   GET    /categories/:id/budgets - Budget version history")
+	log.Printf("[Server]   GET    /categories/:id/carryover - Carry-over ledger")
+	log.Printf("[Server]   PUT    /categories/:id/carryover - Enable/disable carry-over")
+	log.Printf("[Server]   POST   /categories/:id/carryover/reset - Reset carried balance")
+	log.Printf("[Server]   GET    /categories/:id/sinking - Sinking fund balance")
+	log.Printf("[Server]   PUT    /categories/:id/sinking - Make category a sinking fund")
+	log.Printf("[Server]   DELETE /categories/:id/sinking - Remove sinking fund")
 	log.Printf("[Server]   GET    /rules         - Get all merchant rules")
 	log.Printf("[Server]   POST   /rules         - Create merchant rule")
 	log.Printf("[Server]   PUT    /rules/:id     - Update merchant rule")
 	log.Printf("[Server]   DELETE /rules/:id     - Delete merchant rule")
 	log.Printf("[Server]   POST   /rules/:id/apply - Apply single rule")
 	log.Printf("[Server]   POST   /rules/apply-all - Apply all rules")
+	log.Printf("[Server]   POST   /rules/simulate - Preview rule changes without applying")
+	log.Printf("[Server]   GET    /rules/report  - Dead and shadowed rules")
+	log.Printf("[Server]   GET    /rules/suggestions - Rules suggested by category corrections")
+	log.Printf("[Server]   POST   /rules/suggestions - Accept a suggested rule")
 	log.Printf("[Server]   POST   /rules/:id/move - Move rule priority")
+	log.Printf("[Server]   GET    /tags          - List tags with usage counts")
+	log.Printf("[Server]   POST   /tags          - Create tag")
+	log.Printf("[Server]   PUT    /tags/:id      - Rename tag")
+	log.Printf("[Server]   DELETE /tags/:id      - Delete tag")
+	log.Printf("[Server]   GET    /tags/totals   - Per-tag spend across cycles")
+	log.Printf("[Server]   GET    /cycles/:cycle - Cycle lock status + closures")
+	log.Printf("[Server]   POST   /cycles/:cycle/close - Snapshot and lock a cycle")
+	log.Printf("[Server]   POST   /cycles/:cycle/reopen - Reopen a closed cycle")
+	log.Printf("[Server]   GET    /cycles/:cycle/diff - Snapshot vs live data")
+	log.Printf("[Server]   GET    /income        - Cycle income breakdown")
+	log.Printf("[Server]   GET    /income/sources - List income sources")
+	log.Printf("[Server]   POST   /income/sources - Create income source")
+	log.Printf("[Server]   PUT    /income/sources/:id - Change amount from a cycle forward")
+	log.Printf("[Server]   DELETE /income/sources/:id - Stop an income source")
+	log.Printf("[Server]   POST   /income/entries - Record one-off income")
+	log.Printf("[Server]   DELETE /income/entries/:id - Delete income entry")
+	log.Printf("[Server]   GET    /alerts/rules  - List budget alert rules")
+	log.Printf("[Server]   POST   /alerts/rules  - Create alert rule")
+	log.Printf("[Server]   PUT    /alerts/rules/:id - Update alert rule")
+	log.Printf("[Server]   DELETE /alerts/rules/:id - Delete alert rule")
+	log.Printf("[Server]   GET    /alerts/webhooks - List alert webhooks")
+	log.Printf("[Server]   POST   /alerts/webhooks - Add alert webhook")
+	log.Printf("[Server]   DELETE /alerts/webhooks/:id - Remove alert webhook")
+	log.Printf("[Server]   POST   /alerts/webhooks/:id/test - Test-fire a webhook")
+	log.Printf("[Server]   GET    /alerts/history - Fired alerts + deliveries")
 	log.Printf("[Server]   GET    /health        - Health check")
 	log.Printf("[Server] ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	log.Printf("[Server] Server ready at http://localhost:%s", config.Port)
@@ -263,18 +359,24 @@ func transactionHandler(openAI *OpenAIClient, db *DatabaseClient) http.HandlerFu
 			return
 		}
 
+		rules, err := db.loadRuleEngine()
+		if err != nil {
+			log.Printf("[API] Failed to load merchant rules: %v", err)
+		}
+
 		// Process and save each transaction to database
 		var savedTransactions []Transaction
 		var total float64
+		var rejected []string
+		var closedErr error
 
 		for i, tx := range transactions {
 			log.Printf("[API] Processing transaction %d/%d", i+1, len(transactions))
 			enriched := enrichTransaction(tx)
 
-			rule, err := db.FindMatchingRule(enriched.Description)
-			if err == nil && rule != nil {
-				enriched.Category = rule.Category
-				enriched.Source = "rule"
+			var ruleLog []RuleLogEntry
+			if rule := rules.Match(enriched); rule != nil {
+				ruleLog = rule.apply(&enriched)
 			} else {
 				enriched.Source = "openai"
 			}
@@ -282,8 +384,15 @@ func transactionHandler(openAI *OpenAIClient, db *DatabaseClient) http.HandlerFu
 			id, err := db.SaveTransaction(enriched)
 			if err != nil {
 				log.Printf("[API] Failed to save transaction to database: %v", err)
+				if strings.HasPrefix(err.Error(), "cycle closed:") {
+					closedErr = err
+					rejected = append(rejected, fmt.Sprintf("%s (%s)", enriched.Description, enriched.BillingCycle))
+				}
 				continue
 			}
+			if err := db.saveRuleLog(id, ruleLog); err != nil {
+				log.Printf("[API] Failed to save rule log for transaction %d: %v", id, err)
+			}
 
 			enriched.ID = id
 			savedTransactions = append(savedTransactions, enriched)
@@ -291,6 +400,10 @@ func transactionHandler(openAI *OpenAIClient, db *DatabaseClient) http.HandlerFu
 		}
 
 		log.Printf("[API] Successfully saved %d/%d transaction(s), total: %.2f AED", len(savedTransactions), len(transactions), total)
+		if len(savedTransactions) == 0 && closedErr != nil {
+			writeCycleClosed(w, closedErr)
+			return
+		}
 
 		// Build response message
 		message := fmt.Sprintf("✅ Added %d transaction%s!\n\n", len(savedTransactions), pluralize(len(savedTransactions)))
@@ -305,6 +418,9 @@ func transactionHandler(openAI *OpenAIClient, db *DatabaseClient) http.HandlerFu
 			message += fmt.Sprintf("   📅 Cycle: %s\n\n", tx.BillingCycle)
 		}
 		message += fmt.Sprintf("━━━━━━━━━━━━━━━\n💵 Total: %.2f AED", total)
+		if len(rejected) > 0 {
+			message += fmt.Sprintf("\n\n🔒 Not added, cycle closed: %s", strings.Join(rejected, ", "))
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(TransactionResponse{
@@ -313,6 +429,7 @@ func transactionHandler(openAI *OpenAIClient, db *DatabaseClient) http.HandlerFu
 			Count:        len(savedTransactions),
 			Total:        total,
 			Transactions: savedTransactions,
+			Rejected:     rejected,
 		})
 	}
 }
@@ -328,12 +445,18 @@ func dashboardHandler(db *DatabaseClient) http.HandlerFunc {
 		}
 
 		cycle := r.URL.Query().Get("cycle")
-		stats, err := db.GetStats(cycle)
+		stats, err := db.GetStatsForTag(cycle, r.URL.Query().Get("tag"))
 		if err != nil {
 			log.Printf("[API] Failed to get stats: %v", err)
 			http.Error(w, "Failed to retrieve statistics", http.StatusInternalServerError)
 			return
 		}
+		if stats.Forecast, err = db.GetForecast(stats.Cycle, time.Now()); err != nil {
+			log.Printf("[API] Failed to get forecast: %v", err)
+		}
+		if stats.SinkingFunds, err = db.GetSinkingFundStatuses(time.Now()); err != nil {
+			log.Printf("[API] Failed to get sinking funds: %v", err)
+		}
 
 		log.Printf("[API] Returning stats: %d transactions, %.2f AED total", stats.Count, stats.Total)
 		w.Header().Set("Content-Type", "application/json")
@@ -357,6 +480,16 @@ func exportHandler(db *DatabaseClient) http.HandlerFunc {
 			http.Error(w, "Failed to export transactions", http.StatusInternalServerError)
 			return
 		}
+		// ?tag= exports only the transactions carrying that tag.
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			tagged := transactions[:0]
+			for _, tx := range transactions {
+				if hasTag(tx.Tags, tag) {
+					tagged = append(tagged, tx)
+				}
+			}
+			transactions = tagged
+		}
 
 		// Fetch categories and build excluded map
 		allCats, err := db.GetAllCategories()
@@ -379,7 +512,7 @@ func exportHandler(db *DatabaseClient) http.HandlerFunc {
 		defer writer.Flush()
 
 		// Header row
-		writer.Write([]string{"Date", "Description", "Amount (AED)", "Category"})
+		writer.Write([]string{"Date", "Description", "Amount (AED)", "Category", "Tags"})
 
 		var grandTotal float64
 		var currentCycle string
@@ -389,31 +522,42 @@ func exportHandler(db *DatabaseClient) http.HandlerFunc {
 			if tx.BillingCycle != currentCycle {
 				// Write subtotal for previous cycle (if any)
 				if currentCycle != "" {
-					writer.Write([]string{"", "Subtotal", fmt.Sprintf("%.2f", cycleSubtotal), ""})
-					writer.Write([]string{"", "", "", ""})
+					writer.Write([]string{"", "Subtotal", fmt.Sprintf("%.2f", cycleSubtotal), "", ""})
+					writer.Write([]string{"", "", "", "", ""})
 					grandTotal += cycleSubtotal
 					cycleSubtotal = 0
 				}
 				currentCycle = tx.BillingCycle
-				writer.Write([]string{fmt.Sprintf("--- %s ---", currentCycle), "", "", ""})
+				writer.Write([]string{fmt.Sprintf("--- %s ---", currentCycle), "", "", "", ""})
 			}
 
-			writer.Write([]string{tx.Date, tx.Description, fmt.Sprintf("%.2f", tx.Amount), tx.Category})
+			// A split transaction is exported as its split lines.
+			lines := tx.Splits
+			if len(lines) == 0 {
+				lines = []TransactionSplit{{Amount: tx.Amount, Category: tx.Category}}
+			}
+			for _, line := range lines {
+				description := tx.Description
+				if line.Note != "" {
+					description += " (" + line.Note + ")"
+				}
+				writer.Write([]string{tx.Date, description, fmt.Sprintf("%.2f", line.Amount), line.Category, strings.Join(tx.Tags, "; ")})
 
-			if !excludedCats[tx.Category] {
-				cycleSubtotal += tx.Amount
+				if !excludedCats[line.Category] && !tx.Excluded {
+					cycleSubtotal += line.Amount
+				}
 			}
 		}
 
 		// Write final cycle subtotal
 		if currentCycle != "" {
-			writer.Write([]string{"", "Subtotal", fmt.Sprintf("%.2f", cycleSubtotal), ""})
-			writer.Write([]string{"", "", "", ""})
+			writer.Write([]string{"", "Subtotal", fmt.Sprintf("%.2f", cycleSubtotal), "", ""})
+			writer.Write([]string{"", "", "", "", ""})
 			grandTotal += cycleSubtotal
 		}
 
 		// Grand total
-		writer.Write([]string{"", "Grand Total", fmt.Sprintf("%.2f", grandTotal), ""})
+		writer.Write([]string{"", "Grand Total", fmt.Sprintf("%.2f", grandTotal), "", ""})
 
 		log.Printf("[API] CSV export completed: %d transactions", len(transactions))
 	}
@@ -454,6 +598,11 @@ func importHandler(db *DatabaseClient) http.HandlerFunc {
 		reader := csv.NewReader(file)
 		reader.FieldsPerRecord = -1 // allow variable field counts
 
+		rules, err := db.loadRuleEngine()
+		if err != nil {
+			log.Printf("[API] Failed to load merchant rules: %v", err)
+		}
+
 		var imported, duplicates int
 		var errors []string
 		rowNum := 0
@@ -503,13 +652,13 @@ func importHandler(db *DatabaseClient) http.HandlerFunc {
 				continue
 			}
 
-			// Parse data row: Date, Description, Amount, Category
+			// Parse data row: Date, Description, Amount, Category[, Tags]
 			date := strings.TrimSpace(record[0])
 			description := desc
 			amountStr := strings.TrimSpace(record[2])
 			category := strings.TrimSpace(record[3])
 
-			if date == "" || description == "" || category == "" {
+			if date == "" || description == "" {
 				continue
 			}
 
@@ -526,9 +675,22 @@ func importHandler(db *DatabaseClient) http.HandlerFunc {
 				Category:    category,
 				Confidence:  100,
 			}
+			if len(record) > 4 {
+				tx.Tags = parseTagList(record[4])
+			}
+			// A row without a category is filed by the merchant rules, and
+			// skipped when none matches.
+			var ruleLog []RuleLogEntry
+			if category == "" {
+				rule := rules.Match(tx)
+				if rule == nil {
+					continue
+				}
+				ruleLog = rule.apply(&tx)
+			}
 			enriched := enrichTransaction(tx)
 
-			_, err = db.SaveTransaction(enriched)
+			id, err := db.SaveTransaction(enriched)
 			if err != nil {
 				if strings.Contains(err.Error(), "UNIQUE constraint") {
 					duplicates++
@@ -537,6 +699,9 @@ func importHandler(db *DatabaseClient) http.HandlerFunc {
 				}
 				continue
 			}
+			if err := db.saveRuleLog(id, ruleLog); err != nil {
+				log.Printf("[API] Failed to save rule log for row %d: %v", rowNum, err)
+			}
 
 			imported++
 		}
@@ -605,6 +770,7 @@ func manualTransactionHandler(db *DatabaseClient) http.HandlerFunc {
 			Category:    req.Category,
 			Confidence:  100, // Manual entry has 100% confidence
 			Source:      "manual",
+			Tags:        req.Tags,
 		}
 
 		// Enrich with timestamp and billing cycle
@@ -614,7 +780,11 @@ func manualTransactionHandler(db *DatabaseClient) http.HandlerFunc {
 		id, err := db.SaveTransaction(enriched)
 		if err != nil {
 			log.Printf("[API] Failed to save transaction to database: %v", err)
-			http.Error(w, "Failed to save transaction", http.StatusInternalServerError)
+			if strings.HasPrefix(err.Error(), "cycle closed:") {
+				writeCycleClosed(w, err)
+			} else {
+				http.Error(w, "Failed to save transaction", http.StatusInternalServerError)
+			}
 			return
 		}
 
@@ -720,22 +890,51 @@ func rulesHandler(db *DatabaseClient) http.HandlerFunc {
 		case http.MethodPost:
 			log.Printf("[API] POST /rules - Create rule request from %s", r.RemoteAddr)
 			var req struct {
-				Keyword  string `json:"keyword"`
-				Category string `json:"category"`
-				Priority int    `json:"priority"`
+				Keyword    string         `json:"keyword"`
+				MatchType  string         `json:"matchType"`
+				Category   string         `json:"category"`
+				Priority   int            `json:"priority"`
+				Tags       []string       `json:"tags"`
+				Conditions RuleConditions `json:"conditions"`
+				Actions    []RuleAction   `json:"actions"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				log.Printf("[API] Invalid request body: %v", err)
 				http.Error(w, "Invalid request body", http.StatusBadRequest)
 				return
 			}
+			if err := validateRuleActions(req.Actions); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
-			rule, matchCount, protectedCount, err := db.CreateRule(req.Keyword, req.Category, req.Priority)
+			rule, matchCount, protectedCount, err := db.CreateRuleWithConditions(req.Keyword, req.MatchType, req.Category, req.Priority, req.Conditions)
 			if err != nil {
 				log.Printf("[API] Failed to create rule: %v", err)
-				http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+				if strings.HasPrefix(err.Error(), "invalid ") {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				} else {
+					http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+				}
 				return
 			}
+			if len(req.Tags) > 0 {
+				if err := db.SetRuleTags(rule.ID, req.Tags); err != nil {
+					log.Printf("[API] Failed to tag rule: %v", err)
+					http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+					return
+				}
+				rule.Tags = cleanTagNames(req.Tags)
+			}
+			rule.Actions = []RuleAction{}
+			if len(req.Actions) > 0 {
+				if err := db.SetRuleActions(rule.ID, req.Actions); err != nil {
+					log.Printf("[API] Failed to set rule actions: %v", err)
+					http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+					return
+				}
+				rule.Actions = req.Actions
+			}
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -755,6 +954,21 @@ func ruleDetailHandler(db *DatabaseClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 
+		if path == "/rules/suggestions" {
+			ruleSuggestionsHandler(db)(w, r)
+			return
+		}
+
+		if path == "/rules/report" {
+			ruleReportHandler(db)(w, r)
+			return
+		}
+
+		if path == "/rules/simulate" {
+			simulateRulesHandler(db)(w, r)
+			return
+		}
+
 		if path == "/rules/apply-all" {
 			if r.Method != http.MethodPost {
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -850,9 +1064,13 @@ func ruleDetailHandler(db *DatabaseClient) http.HandlerFunc {
 		case http.MethodPut:
 			log.Printf("[API] PUT /rules/%d - Update rule from %s", id, r.RemoteAddr)
 			var req struct {
-				Keyword  string `json:"keyword"`
-				Category string `json:"category"`
-				Priority int    `json:"priority"`
+				Keyword    string          `json:"keyword"`
+				MatchType  *string         `json:"matchType"` // omitted leaves the rule's match type alone
+				Category   string          `json:"category"`
+				Priority   int             `json:"priority"`
+				Tags       []string        `json:"tags"`       // omitted leaves the rule's tags alone
+				Conditions *RuleConditions `json:"conditions"` // omitted leaves the rule's conditions alone
+				Actions    *[]RuleAction   `json:"actions"`    // omitted leaves the rule's actions alone
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				log.Printf("[API] Invalid request body: %v", err)
@@ -860,15 +1078,66 @@ func ruleDetailHandler(db *DatabaseClient) http.HandlerFunc {
 				return
 			}
 
-			if err := db.UpdateRule(id, req.Keyword, req.Category, req.Priority); err != nil {
+			if req.Conditions != nil {
+				if err := validateRuleConditions(req.Conditions); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if req.Actions != nil {
+				if err := validateRuleActions(*req.Actions); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			var matchType string
+			if req.MatchType != nil {
+				matchType = *req.MatchType
+			} else {
+				rule, err := db.getRule(id)
+				if err != nil {
+					log.Printf("[API] Failed to fetch rule: %v", err)
+					if err.Error() == "rule not found" {
+						http.Error(w, "Rule not found", http.StatusNotFound)
+					} else {
+						http.Error(w, "Failed to update rule", http.StatusInternalServerError)
+					}
+					return
+				}
+				matchType = rule.MatchType
+			}
+			if err := db.UpdateRule(id, req.Keyword, matchType, req.Category, req.Priority); err != nil {
 				log.Printf("[API] Failed to update rule: %v", err)
 				if err.Error() == "rule not found" {
 					http.Error(w, "Rule not found", http.StatusNotFound)
+				} else if strings.HasPrefix(err.Error(), "invalid ") {
+					http.Error(w, err.Error(), http.StatusBadRequest)
 				} else {
 					http.Error(w, "Failed to update rule", http.StatusInternalServerError)
 				}
 				return
 			}
+			if req.Tags != nil {
+				if err := db.SetRuleTags(id, req.Tags); err != nil {
+					log.Printf("[API] Failed to tag rule: %v", err)
+					http.Error(w, "Failed to update rule", http.StatusInternalServerError)
+					return
+				}
+			}
+			if req.Conditions != nil {
+				if err := db.SetRuleConditions(id, *req.Conditions); err != nil {
+					log.Printf("[API] Failed to set rule conditions: %v", err)
+					http.Error(w, "Failed to update rule", http.StatusInternalServerError)
+					return
+				}
+			}
+			if req.Actions != nil {
+				if err := db.SetRuleActions(id, *req.Actions); err != nil {
+					log.Printf("[API] Failed to set rule actions: %v", err)
+					http.Error(w, "Failed to update rule", http.StatusInternalServerError)
+					return
+				}
+			}
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -916,6 +1185,16 @@ func categoriesHandler(db *DatabaseClient) http.HandlerFunc {
 				http.Error(w, "Failed to retrieve categories", http.StatusInternalServerError)
 				return
 			}
+			// Archived categories only on request (?archived=true).
+			if r.URL.Query().Get("archived") != "true" {
+				active := cats[:0]
+				for _, cat := range cats {
+					if !cat.Archived {
+						active = append(active, cat)
+					}
+				}
+				cats = active
+			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success":    true,
@@ -931,6 +1210,7 @@ func categoriesHandler(db *DatabaseClient) http.HandlerFunc {
 				Type              string   `json:"type"`
 				BudgetAmount      *float64 `json:"budgetAmount"`
 				Tracking          string   `json:"tracking"`
+				ParentID          *int64   `json:"parentId"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -940,12 +1220,36 @@ func categoriesHandler(db *DatabaseClient) http.HandlerFunc {
 				http.Error(w, "Name is required", http.StatusBadRequest)
 				return
 			}
+			// Check the parent first, so a bad one leaves nothing behind.
+			if req.ParentID != nil {
+				if err := db.CheckCategoryParent(*req.ParentID); err != nil {
+					log.Printf("[API] Invalid category parent: %v", err)
+					if strings.HasPrefix(err.Error(), "invalid parent") {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					} else {
+						http.Error(w, "Failed to create category", http.StatusInternalServerError)
+					}
+					return
+				}
+			}
 			cat, err := db.CreateCategory(req.Name, req.Emoji, req.ExcludeFromTotals, req.Type, req.BudgetAmount, req.Tracking)
 			if err != nil {
 				log.Printf("[API] Failed to create category: %v", err)
 				http.Error(w, "Failed to create category", http.StatusInternalServerError)
 				return
 			}
+			if req.ParentID != nil {
+				if err := db.SetCategoryParent(cat.ID, req.ParentID); err != nil {
+					log.Printf("[API] Failed to set category parent: %v", err)
+					http.Error(w, "Failed to create category", http.StatusInternalServerError)
+					return
+				}
+				if cat, err = db.GetCategory(cat.ID); err != nil {
+					log.Printf("[API] Failed to fetch category: %v", err)
+					http.Error(w, "Failed to create category", http.StatusInternalServerError)
+					return
+				}
+			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success":  true,
@@ -968,14 +1272,311 @@ func categoryDetailHandler(db *DatabaseClient) http.HandlerFunc {
 			return
 		}
 
+		// /categories/:id/carryover — opt in/out of envelope carry-over, view
+		// the ledger, or reset the carried balance (/carryover/reset).
+		if strings.HasSuffix(path, "/carryover/reset") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] POST /categories/%d/carryover/reset - Reset carry-over from %s", id, r.RemoteAddr)
+			var req struct {
+				Cycle string `json:"cycle"`
+			}
+			if r.ContentLength > 0 {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+			}
+			if err := db.ResetCarryover(id, req.Cycle); err != nil {
+				log.Printf("[API] Failed to reset carry-over: %v", err)
+				if err.Error() == "category not found" {
+					http.Error(w, "Category not found", http.StatusNotFound)
+				} else if err.Error() == "invalid cycle" {
+					http.Error(w, "Invalid cycle", http.StatusBadRequest)
+				} else {
+					http.Error(w, "Failed to reset carry-over", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+			return
+		}
+		if strings.HasSuffix(path, "/carryover") {
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /categories/%d/carryover - Request from %s", id, r.RemoteAddr)
+				cat, err := db.GetCategory(id)
+				if err != nil {
+					http.Error(w, "Category not found", http.StatusNotFound)
+					return
+				}
+				cycle := r.URL.Query().Get("cycle")
+				if cycle == "" {
+					cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+				}
+				ledger, err := db.GetCarryoverLedger(*cat, cycle)
+				if err != nil {
+					log.Printf("[API] Failed to get carry-over ledger: %v", err)
+					http.Error(w, "Failed to retrieve carry-over", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"ledger":  ledger,
+				})
+
+			case http.MethodPut:
+				log.Printf("[API] PUT /categories/%d/carryover - Set carry-over from %s", id, r.RemoteAddr)
+				var req struct {
+					Enabled bool `json:"enabled"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				if err := db.SetCarryover(id, req.Enabled); err != nil {
+					log.Printf("[API] Failed to set carry-over: %v", err)
+					switch err.Error() {
+					case "category not found":
+						http.Error(w, "Category not found", http.StatusNotFound)
+					case "carry-over requires actual tracking":
+						http.Error(w, "Carry-over requires actual tracking", http.StatusBadRequest)
+					default:
+						http.Error(w, "Failed to set carry-over", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// /categories/:id/sinking — sinking fund settings and balance.
+		if strings.HasSuffix(path, "/sinking") {
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /categories/%d/sinking - Request from %s", id, r.RemoteAddr)
+				fund, err := db.GetSinkingFund(id)
+				if err != nil {
+					log.Printf("[API] Failed to get sinking fund: %v", err)
+					http.Error(w, "Failed to retrieve sinking fund", http.StatusInternalServerError)
+					return
+				}
+				if fund == nil {
+					http.Error(w, "Sinking fund not found", http.StatusNotFound)
+					return
+				}
+				status, err := db.sinkingFundStatus(*fund, time.Now())
+				if err != nil {
+					log.Printf("[API] Failed to get sinking fund status: %v", err)
+					http.Error(w, "Failed to retrieve sinking fund", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"fund":    status,
+				})
+
+			case http.MethodPut:
+				log.Printf("[API] PUT /categories/%d/sinking - Set sinking fund from %s", id, r.RemoteAddr)
+				var req SinkingFund
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				req.CategoryID = id
+				fund, err := db.SetSinkingFund(req)
+				if err != nil {
+					log.Printf("[API] Failed to set sinking fund: %v", err)
+					switch err.Error() {
+					case "category not found":
+						http.Error(w, "Category not found", http.StatusNotFound)
+					case "sinking fund requires allocated tracking", "invalid due date", "invalid cycle", "expected bill is required":
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					default:
+						http.Error(w, "Failed to set sinking fund", http.StatusInternalServerError)
+					}
+					return
+				}
+				status, err := db.sinkingFundStatus(*fund, time.Now())
+				if err != nil {
+					log.Printf("[API] Failed to get sinking fund status: %v", err)
+					http.Error(w, "Failed to set sinking fund", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"fund":    status,
+				})
+
+			case http.MethodDelete:
+				log.Printf("[API] DELETE /categories/%d/sinking - Remove sinking fund from %s", id, r.RemoteAddr)
+				if err := db.DeleteSinkingFund(id); err != nil {
+					log.Printf("[API] Failed to delete sinking fund: %v", err)
+					if err.Error() == "sinking fund not found" {
+						http.Error(w, "Sinking fund not found", http.StatusNotFound)
+					} else {
+						http.Error(w, "Failed to delete sinking fund", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// /categories/:id/archive and /restore — retire a category without
+		// losing its history, or bring it back.
+		if strings.HasSuffix(path, "/archive") || strings.HasSuffix(path, "/restore") {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			action := path[strings.LastIndex(path, "/")+1:]
+			log.Printf("[API] POST /categories/%d/%s - Request from %s", id, action, r.RemoteAddr)
+			var err error
+			if action == "archive" {
+				err = db.ArchiveCategory(id)
+			} else {
+				err = db.RestoreCategory(id)
+			}
+			if err != nil {
+				log.Printf("[API] Failed to %s category: %v", action, err)
+				switch {
+				case err.Error() == "category not found":
+					http.Error(w, "Category not found", http.StatusNotFound)
+				case strings.HasPrefix(err.Error(), "cannot "+action+":"):
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"success": false,
+						"message": err.Error(),
+					})
+				default:
+					http.Error(w, "Failed to "+action+" category", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+			return
+		}
+
+		// /categories/:id/merge-into/:target — fold this category into another
+		// and delete it.
+		if i := strings.Index(path, "/merge-into/"); i >= 0 {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var target int64
+			if _, err := fmt.Sscanf(path[i+len("/merge-into/"):], "%d", &target); err != nil {
+				http.Error(w, "Invalid target category ID", http.StatusBadRequest)
+				return
+			}
+			log.Printf("[API] POST /categories/%d/merge-into/%d - Merge from %s", id, target, r.RemoteAddr)
+			result, err := db.MergeCategory(id, target)
+			if err != nil {
+				log.Printf("[API] Failed to merge category: %v", err)
+				switch {
+				case err.Error() == "category not found":
+					http.Error(w, "Category not found", http.StatusNotFound)
+				case strings.HasPrefix(err.Error(), "invalid merge"):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				case strings.HasPrefix(err.Error(), "cycle closed:"):
+					writeCycleClosed(w, err)
+				default:
+					http.Error(w, "Failed to merge category", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"merged":  result,
+			})
+			return
+		}
+
+		// /categories/:id/parent — move under a parent, or back to the top
+		// level with {"parentId": null}.
+		if strings.HasSuffix(path, "/parent") {
+			if r.Method != http.MethodPut {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] PUT /categories/%d/parent - Set parent from %s", id, r.RemoteAddr)
+			var req struct {
+				ParentID *int64 `json:"parentId"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := db.SetCategoryParent(id, req.ParentID); err != nil {
+				log.Printf("[API] Failed to set category parent: %v", err)
+				switch {
+				case err.Error() == "category not found":
+					http.Error(w, "Category not found", http.StatusNotFound)
+				case strings.HasPrefix(err.Error(), "invalid parent"):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				default:
+					http.Error(w, "Failed to set parent", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+			return
+		}
+
+		// /categories/:id/budgets — budget version history.
+		if strings.HasSuffix(path, "/budgets") {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			log.Printf("[API] GET /categories/%d/budgets - Request from %s", id, r.RemoteAddr)
+			versions, err := db.GetBudgetVersions(id)
+			if err != nil {
+				log.Printf("[API] Failed to get budget versions: %v", err)
+				http.Error(w, "Failed to retrieve budgets", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":  true,
+				"versions": versions,
+			})
+			return
+		}
+
 		// /categories/:id/target — dedicated set/edit/remove of a category's
 		// spending target (budget_amount), independent of full category edits.
+		// scope "forward" (default) applies from cycle onward; scope "cycle"
+		// is a one-off override for that cycle only. cycle defaults to current.
 		if strings.HasSuffix(path, "/target") {
 			switch r.Method {
 			case http.MethodPut:
 				log.Printf("[API] PUT /categories/%d/target - Set target from %s", id, r.RemoteAddr)
 				var req struct {
 					BudgetAmount *float64 `json:"budgetAmount"`
+					Cycle        string   `json:"cycle"`
+					Scope        string   `json:"scope"`
 				}
 				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 					http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -985,11 +1586,18 @@ func categoryDetailHandler(db *DatabaseClient) http.HandlerFunc {
 					http.Error(w, "budgetAmount must be greater than 0", http.StatusBadRequest)
 					return
 				}
-				if err := db.SetCategoryTarget(id, req.BudgetAmount); err != nil {
+				if req.Scope != "" && req.Scope != "forward" && req.Scope != "cycle" {
+					http.Error(w, "scope must be forward or cycle", http.StatusBadRequest)
+					return
+				}
+				if err := db.SetCategoryBudget(id, req.Cycle, req.BudgetAmount, req.Scope == "cycle"); err != nil {
 					log.Printf("[API] Failed to set category target: %v", err)
-					if err.Error() == "category not found" {
+					switch err.Error() {
+					case "category not found":
 						http.Error(w, "Category not found", http.StatusNotFound)
-					} else {
+					case "invalid cycle":
+						http.Error(w, "Invalid cycle", http.StatusBadRequest)
+					default:
 						http.Error(w, "Failed to set target", http.StatusInternalServerError)
 					}
 					return
@@ -999,11 +1607,15 @@ func categoryDetailHandler(db *DatabaseClient) http.HandlerFunc {
 
 			case http.MethodDelete:
 				log.Printf("[API] DELETE /categories/%d/target - Remove target from %s", id, r.RemoteAddr)
-				if err := db.SetCategoryTarget(id, nil); err != nil {
+				q := r.URL.Query()
+				if err := db.SetCategoryBudget(id, q.Get("cycle"), nil, q.Get("scope") == "cycle"); err != nil {
 					log.Printf("[API] Failed to remove category target: %v", err)
-					if err.Error() == "category not found" {
+					switch err.Error() {
+					case "category not found":
 						http.Error(w, "Category not found", http.StatusNotFound)
-					} else {
+					case "invalid cycle":
+						http.Error(w, "Invalid cycle", http.StatusBadRequest)
+					default:
 						http.Error(w, "Failed to remove target", http.StatusInternalServerError)
 					}
 					return
@@ -1076,19 +1688,47 @@ func categoryDetailHandler(db *DatabaseClient) http.HandlerFunc {
 	}
 }
 
-// fundingHandler toggles the "set aside" funding tick for an allocated category
-// in a billing cycle. POST /funding {cycle, categoryId, funded}.
+// fundingHandler reports and records "set aside" funding for a billing cycle.
+// GET /funding?cycle= lists each allocated or goal category's funded amount
+// against its budget. POST /funding {cycle, categoryId, funded} toggles the
+// full tick (funded=true tops the entries up to the budget); with an amount
+// (and optional note) it adds a partial entry on top of what is already
+// funded instead.
 func fundingHandler(db *DatabaseClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
+		switch r.Method {
+		case http.MethodGet:
+			cycle := r.URL.Query().Get("cycle")
+			if cycle == "" {
+				cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+			}
+			log.Printf("[API] GET /funding - %s from %s", cycle, r.RemoteAddr)
+			funding, err := db.GetFundingStatus(cycle)
+			if err != nil {
+				log.Printf("[API] Failed to get funding: %v", err)
+				http.Error(w, "Failed to retrieve funding", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"cycle":   cycle,
+				"funding": funding,
+			})
+			return
+		case http.MethodPost:
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		var req struct {
-			Cycle      string `json:"cycle"`
-			CategoryID int64  `json:"categoryId"`
-			Funded     bool   `json:"funded"`
+			Cycle      string   `json:"cycle"`
+			CategoryID int64    `json:"categoryId"`
+			Funded     bool     `json:"funded"`
+			Amount     *float64 `json:"amount"`
+			Note       string   `json:"note"`
+			Date       string   `json:"date"` // when an entry was set aside (YYYY-MM-DD), default now
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -1106,33 +1746,79 @@ func fundingHandler(db *DatabaseClient) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("[API] POST /funding - %s category %d (%s) funded=%v from %s",
-			cycle, req.CategoryID, cat.Name, req.Funded, r.RemoteAddr)
+		resp := map[string]interface{}{"success": true}
+		switch {
+		case req.Amount != nil:
+			log.Printf("[API] POST /funding - %s category %d (%s) entry %.2f from %s",
+				cycle, req.CategoryID, cat.Name, *req.Amount, r.RemoteAddr)
+			entry, err := db.AddFundingEntry(cycle, req.CategoryID, *req.Amount, req.Note, req.Date)
+			if err != nil {
+				log.Printf("[API] Failed to add funding entry: %v", err)
+				switch {
+				case err.Error() == "invalid funding amount":
+					http.Error(w, "Amount must be greater than 0", http.StatusBadRequest)
+				case err.Error() == "invalid cycle":
+					http.Error(w, "Invalid cycle", http.StatusBadRequest)
+				case err.Error() == "invalid funding date":
+					http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+				case strings.HasPrefix(err.Error(), "cycle closed:"):
+					writeCycleClosed(w, err)
+				default:
+					http.Error(w, "Failed to add funding entry", http.StatusInternalServerError)
+				}
+				return
+			}
+			resp["entry"] = entry
 
-		if req.Funded {
-			if cat.BudgetAmount == nil {
+		case req.Funded:
+			log.Printf("[API] POST /funding - %s category %d (%s) funded=true from %s",
+				cycle, req.CategoryID, cat.Name, r.RemoteAddr)
+			budget, err := db.BudgetFor(cat.ID, cycle)
+			if err != nil {
+				log.Printf("[API] Failed to resolve budget: %v", err)
+				http.Error(w, "Failed to set funding", http.StatusInternalServerError)
+				return
+			}
+			if budget == nil {
 				http.Error(w, "Category has no budget to set aside", http.StatusBadRequest)
 				return
 			}
-			if err := db.SetFunding(cycle, req.CategoryID, *cat.BudgetAmount); err != nil {
+			if err := db.SetFunding(cycle, req.CategoryID, *budget); err != nil {
 				log.Printf("[API] Failed to set funding: %v", err)
-				http.Error(w, "Failed to set funding", http.StatusInternalServerError)
+				if strings.HasPrefix(err.Error(), "cycle closed:") {
+					writeCycleClosed(w, err)
+				} else {
+					http.Error(w, "Failed to set funding", http.StatusInternalServerError)
+				}
 				return
 			}
-		} else {
+
+		default:
+			log.Printf("[API] POST /funding - %s category %d (%s) funded=false from %s",
+				cycle, req.CategoryID, cat.Name, r.RemoteAddr)
 			if err := db.DeleteFunding(cycle, req.CategoryID); err != nil {
 				log.Printf("[API] Failed to delete funding: %v", err)
-				http.Error(w, "Failed to clear funding", http.StatusInternalServerError)
+				if strings.HasPrefix(err.Error(), "cycle closed:") {
+					writeCycleClosed(w, err)
+				} else {
+					http.Error(w, "Failed to clear funding", http.StatusInternalServerError)
+				}
 				return
 			}
 		}
 
+		if goal, err := db.goalProgressForCategory(cat.ID); err != nil {
+			log.Printf("[API] Failed to get goal progress: %v", err)
+		} else if goal != nil {
+			resp["goal"] = goal
+		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		json.NewEncoder(w).Encode(resp)
 	}
 }
 
-// salaryHandler updates the configured monthly salary. PUT /salary {salary}.
+// salaryHandler updates the "Salary" income source from the current cycle
+// forward. PUT /salary {salary}.
 func salaryHandler(db *DatabaseClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
@@ -1207,6 +1893,19 @@ func transactionDetailHandler(db *DatabaseClient) http.HandlerFunc {
 		var transactionID int64
 		fmt.Sscanf(idStr, "%d", &transactionID)
 
+		if strings.HasSuffix(path, "/splits") {
+			splitsHandler(db, transactionID)(w, r)
+			return
+		}
+		if strings.HasSuffix(path, "/rule-log") {
+			ruleLogHandler(db, transactionID)(w, r)
+			return
+		}
+		if strings.HasSuffix(path, "/approve") {
+			approveHandler(db, transactionID)(w, r)
+			return
+		}
+
 		switch r.Method {
 		case http.MethodPut:
 			updateTransactionHandler(db, transactionID)(w, r)
@@ -1222,20 +1921,37 @@ func updateTransactionHandler(db *DatabaseClient, id int64) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[API] PUT /transaction/%d - Update request from %s", id, r.RemoteAddr)
 
-		var tx Transaction
-		if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+		var req struct {
+			Transaction
+			Excluded *bool `json:"excluded"` // omitted leaves it as it is
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Printf("[API] Invalid request body: %v", err)
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
+		tx := req.Transaction
 
 		// Recalculate billing cycle based on new date
 		tx.BillingCycle = calculateBillingCycle(tx.Date)
 
-		if err := db.UpdateTransaction(id, tx); err != nil {
+		var err error
+		if req.Excluded != nil {
+			tx.Excluded = *req.Excluded
+		} else {
+			tx.Excluded, err = db.transactionExcluded(id)
+		}
+		if err == nil {
+			err = db.UpdateTransaction(id, tx)
+		}
+		if err != nil {
 			log.Printf("[API] Failed to update transaction: %v", err)
 			if err.Error() == "transaction not found" {
 				http.Error(w, "Transaction not found", http.StatusNotFound)
+			} else if strings.HasPrefix(err.Error(), "invalid amount") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if strings.HasPrefix(err.Error(), "cycle closed:") {
+				writeCycleClosed(w, err)
 			} else {
 				http.Error(w, "Failed to update transaction", http.StatusInternalServerError)
 			}
@@ -1259,6 +1975,8 @@ func deleteTransactionHandler(db *DatabaseClient, id int64) http.HandlerFunc {
 			log.Printf("[API] Failed to delete transaction: %v", err)
 			if err.Error() == "transaction not found" {
 				http.Error(w, "Transaction not found", http.StatusNotFound)
+			} else if strings.HasPrefix(err.Error(), "cycle closed:") {
+				writeCycleClosed(w, err)
 			} else {
 				http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
 			}
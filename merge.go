@@ -0,0 +1,287 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// MergeResult counts what a category merge moved onto the target.
+type MergeResult struct {
+	Source         string `json:"source"`
+	Target         string `json:"target"`
+	Transactions   int64  `json:"transactions"`
+	Splits         int64  `json:"splits"`
+	Rules          int64  `json:"rules"`
+	FundingEntries int64  `json:"fundingEntries"`
+	Budgets        int64  `json:"budgets"`
+	Matchers       int64  `json:"matchers"`
+	AlertRules     int64  `json:"alertRules"`
+	Goals          int64  `json:"goals"`
+	SinkingFunds   int64  `json:"sinkingFunds"`
+}
+
+// mergeVersion is one budget_versions row as the merge sees it.
+type mergeVersion struct {
+	cycle  string
+	key    string
+	amount *float64
+	oneOff bool
+}
+
+// MergeCategory folds category id into target in one SQL transaction:
+// transactions, split lines, merchant rules, alert rules, funding history and auto-fund
+// matchers move over, a goal or sinking fund moves when the target has none,
+// and the budgets are combined so each cycle's target budget is the sum of
+// what the two categories had then. The source is deleted afterwards.
+// Nothing moves if the source has entries in a closed cycle.
+func (c *DatabaseClient) MergeCategory(id, target int64) (*MergeResult, error) {
+	if id == target {
+		return nil, fmt.Errorf("invalid merge: a category can't be merged into itself")
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var source, dest Category
+	var sourceBudget, destBudget sql.NullFloat64
+	err = tx.QueryRow("SELECT id, name, budget_amount FROM categories WHERE id = ?", id).Scan(&source.ID, &source.Name, &sourceBudget)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category: %w", err)
+	}
+	err = tx.QueryRow("SELECT id, name, budget_amount, archived FROM categories WHERE id = ?", target).Scan(&dest.ID, &dest.Name, &destBudget, &dest.Archived)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid merge: target category not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch target category: %w", err)
+	}
+	if dest.Archived {
+		return nil, fmt.Errorf("invalid merge: target category is archived")
+	}
+
+	var children int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM categories WHERE parent_id = ?", id).Scan(&children); err != nil {
+		return nil, fmt.Errorf("failed to count sub-categories: %w", err)
+	}
+	if children > 0 {
+		return nil, fmt.Errorf("invalid merge: category has %d sub-categories", children)
+	}
+	for _, owned := range []struct{ table, what string }{
+		{"savings_goals", "savings goal"},
+		{"sinking_funds", "sinking fund"},
+	} {
+		var both int
+		if err := tx.QueryRow(
+			"SELECT COUNT(DISTINCT category_id) FROM "+owned.table+" WHERE category_id IN (?, ?)", id, target,
+		).Scan(&both); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", owned.table, err)
+		}
+		if both == 2 {
+			return nil, fmt.Errorf("invalid merge: both categories have a %s", owned.what)
+		}
+	}
+
+	// Closed cycles stay as they were snapshotted.
+	var closed string
+	err = tx.QueryRow(`
+		SELECT billing_cycle FROM transactions
+		WHERE (category = ? OR id IN (SELECT transaction_id FROM transaction_splits WHERE category = ?))
+			AND NOT `+openCyclesClause+`
+		UNION
+		SELECT cycle FROM cycle_funding WHERE category_id = ?
+			AND cycle IN (SELECT cycle FROM cycle_closures WHERE reopened_at IS NULL)
+		LIMIT 1`, source.Name, source.Name, id,
+	).Scan(&closed)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check closed cycles: %w", err)
+	}
+	if err == nil {
+		return nil, fmt.Errorf("cycle closed: %s", closed)
+	}
+
+	result := &MergeResult{Source: source.Name, Target: dest.Name}
+	moves := []struct {
+		count *int64
+		query string
+		args  []interface{}
+	}{
+		{&result.Transactions, "UPDATE transactions SET category = ? WHERE category = ?", []interface{}{dest.Name, source.Name}},
+		{&result.Splits, "UPDATE transaction_splits SET category = ? WHERE category = ?", []interface{}{dest.Name, source.Name}},
+		{&result.Rules, "UPDATE merchant_rules SET category = ? WHERE category = ?", []interface{}{dest.Name, source.Name}},
+		{&result.AlertRules, "UPDATE alert_rules SET target = ? WHERE scope = 'category' AND target = ?", []interface{}{dest.Name, source.Name}},
+		{&result.FundingEntries, "UPDATE cycle_funding SET category_id = ? WHERE category_id = ?", []interface{}{target, id}},
+		{nil, "UPDATE funding_audit SET category_id = ? WHERE category_id = ?", []interface{}{target, id}},
+		{&result.Matchers, "UPDATE funding_matchers SET category_id = ? WHERE category_id = ?", []interface{}{target, id}},
+		{&result.Goals, "UPDATE savings_goals SET category_id = ? WHERE category_id = ?", []interface{}{target, id}},
+		{&result.SinkingFunds, "UPDATE sinking_funds SET category_id = ? WHERE category_id = ?", []interface{}{target, id}},
+		{nil, "DELETE FROM carryover_ledger WHERE category_id IN (?, ?) AND cycle NOT IN (SELECT cycle FROM cycle_closures WHERE reopened_at IS NULL)", []interface{}{id, target}},
+	}
+	for _, m := range moves {
+		res, err := tx.Exec(m.query, m.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge category: %w", err)
+		}
+		if m.count != nil {
+			*m.count, _ = res.RowsAffected()
+		}
+	}
+
+	sourceVersions, err := mergeVersions(tx, id, sourceBudget)
+	if err != nil {
+		return nil, err
+	}
+	destVersions, err := mergeVersions(tx, target, destBudget)
+	if err != nil {
+		return nil, err
+	}
+	result.Budgets = int64(len(sourceVersions))
+	if _, err := tx.Exec("DELETE FROM budget_versions WHERE category_id IN (?, ?)", id, target); err != nil {
+		return nil, fmt.Errorf("failed to clear budget versions: %w", err)
+	}
+	for _, v := range combineBudgetVersions(sourceVersions, destVersions) {
+		if err := setBudgetVersion(tx, target, v.cycle, v.amount, v.oneOff); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(
+		"UPDATE categories SET budget_amount = ? WHERE id = ?",
+		sumBudgets(nullFloatPtr(sourceBudget), nullFloatPtr(destBudget)), target,
+	); err != nil {
+		return nil, fmt.Errorf("failed to set category target: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM categories WHERE id = ?", id); err != nil {
+		return nil, fmt.Errorf("failed to delete category: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return result, nil
+}
+
+// mergeVersions loads a category's budget history. A category that never
+// got one is treated as having a base version holding budget_amount, the
+// same fallback BudgetsForCycle applies.
+func mergeVersions(tx *sql.Tx, id int64, budget sql.NullFloat64) ([]mergeVersion, error) {
+	rows, err := tx.Query(
+		"SELECT effective_cycle, effective_key, amount, one_off FROM budget_versions WHERE category_id = ? ORDER BY effective_key ASC",
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []mergeVersion
+	for rows.Next() {
+		var v mergeVersion
+		var amount sql.NullFloat64
+		var oneOff int
+		if err := rows.Scan(&v.cycle, &v.key, &amount, &oneOff); err != nil {
+			return nil, fmt.Errorf("failed to scan budget version: %w", err)
+		}
+		v.amount = nullFloatPtr(amount)
+		v.oneOff = oneOff == 1
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		versions = []mergeVersion{{amount: nullFloatPtr(budget)}}
+	}
+	return versions, nil
+}
+
+// combineBudgetVersions sums two budget histories: at every cycle where
+// either one changes, the combined history holds the sum of what each had
+// in effect there.
+func combineBudgetVersions(a, b []mergeVersion) []mergeVersion {
+	cycles := make(map[string]string)
+	forwards := make(map[string]bool)
+	oneOffs := make(map[string]bool)
+	for _, v := range append(append([]mergeVersion{}, a...), b...) {
+		cycles[v.key] = v.cycle
+		if v.oneOff {
+			oneOffs[v.key] = true
+		} else {
+			forwards[v.key] = true
+		}
+	}
+	keys := make([]string, 0, len(cycles))
+	for key := range cycles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var combined []mergeVersion
+	for _, key := range keys {
+		if forwards[key] {
+			combined = append(combined, mergeVersion{
+				cycle:  cycles[key],
+				key:    key,
+				amount: sumBudgets(budgetAt(a, key, false), budgetAt(b, key, false)),
+			})
+		}
+		if oneOffs[key] {
+			combined = append(combined, mergeVersion{
+				cycle:  cycles[key],
+				key:    key,
+				amount: sumBudgets(budgetAt(a, key, true), budgetAt(b, key, true)),
+				oneOff: true,
+			})
+		}
+	}
+	return combined
+}
+
+// budgetAt resolves a history at a cycle key, the way BudgetsForCycle does:
+// the latest forward version at or before it, overridden by a one-off for
+// that exact cycle when withOneOffs is set. versions are sorted by key.
+func budgetAt(versions []mergeVersion, key string, withOneOffs bool) *float64 {
+	var forward, override *float64
+	var overridden bool
+	for _, v := range versions {
+		if v.key > key {
+			break
+		}
+		if !v.oneOff {
+			forward = v.amount
+		} else if withOneOffs && v.key == key {
+			override, overridden = v.amount, true
+		}
+	}
+	if overridden {
+		return override
+	}
+	return forward
+}
+
+// sumBudgets adds two optional budgets; no budget on either side is nil.
+func sumBudgets(a, b *float64) *float64 {
+	if a == nil && b == nil {
+		return nil
+	}
+	var sum float64
+	if a != nil {
+		sum += *a
+	}
+	if b != nil {
+		sum += *b
+	}
+	return &sum
+}
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return floatPtr(v.Float64)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeCategory_MovesEverything(t *testing.T) {
+	db := setupTestDB(t)
+	internet, err := db.CreateCategory("Internet & TV", "💡", false, "fixed", floatPtr(600), "allocated")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	eAnd := categoryByName(t, db, "E& Bill")
+	if err := db.SetCategoryBudget(internet.ID, "Mar 2026", floatPtr(800), false); err != nil {
+		t.Fatalf("SetCategoryBudget failed: %v", err)
+	}
+	if err := db.SetCategoryBudget(internet.ID, "May 2026", floatPtr(100), true); err != nil {
+		t.Fatalf("SetCategoryBudget failed: %v", err)
+	}
+
+	insertTestTransaction(t, db, Transaction{Description: "Virgin Mobile", Amount: 99, Date: "2026-01-25", Category: "Internet & TV", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual", Tags: []string{"phone"}})
+	insertTestTransaction(t, db, Transaction{Description: "Virgin Mobile", Amount: 99, Date: "2026-02-25", Category: "Internet & TV", BillingCycle: "Feb 2026", Timestamp: "2026-02-25T10:00:00Z", Source: "manual"})
+	if _, _, _, err := db.CreateRule("virgin mobile", MatchContains, "Internet & TV", 50); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if _, err := db.CreateAlertRule(AlertRule{Scope: "category", Target: "Internet & TV", Kind: "percent", Threshold: 80}); err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+	if _, err := db.AddFundingEntry("Feb 2026", internet.ID, 300, "half", ""); err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+
+	budgetsBefore := make(map[string][2]*float64)
+	for _, cycle := range []string{"Jan 2026", "Mar 2026", "Apr 2026", "May 2026", "Jun 2026"} {
+		b, _ := db.BudgetFor(internet.ID, cycle)
+		e, _ := db.BudgetFor(eAnd.ID, cycle)
+		budgetsBefore[cycle] = [2]*float64{b, e}
+	}
+
+	result, err := db.MergeCategory(internet.ID, eAnd.ID)
+	if err != nil {
+		t.Fatalf("MergeCategory failed: %v", err)
+	}
+	if result.Transactions != 2 || result.Rules != 1 || result.AlertRules != 1 || result.FundingEntries != 1 || result.Budgets != 3 {
+		t.Errorf("unexpected merge counts: %+v", result)
+	}
+
+	cats, _ := db.GetAllCategories()
+	for _, cat := range cats {
+		if cat.Name == "Internet & TV" {
+			t.Error("expected the source category to be deleted")
+		}
+	}
+	txs, _ := db.GetAllTransactionsGroupedByCycle()
+	for _, tx := range txs {
+		if tx.Description == "Virgin Mobile" && tx.Category != "E& Bill" {
+			t.Errorf("expected the transaction moved to E& Bill, got %s", tx.Category)
+		}
+		if tx.Date == "2026-01-25" && (len(tx.Tags) != 1 || tx.Tags[0] != "phone") {
+			t.Errorf("expected tags to survive the merge, got %v", tx.Tags)
+		}
+	}
+	if rule, _ := db.FindMatchingRule("VIRGIN MOBILE UAE"); rule == nil || rule.Category != "E& Bill" {
+		t.Errorf("expected the rule to point at E& Bill, got %+v", rule)
+	}
+	alerts, _ := db.GetAlertRules()
+	if len(alerts) != 1 || alerts[0].Target != "E& Bill" {
+		t.Errorf("expected the alert rule retargeted, got %+v", alerts)
+	}
+	entries, _ := db.GetFundingEntries("Feb 2026")
+	if len(entries) != 1 || entries[0].CategoryID != eAnd.ID {
+		t.Errorf("expected the funding entry on E& Bill, got %+v", entries)
+	}
+
+	for cycle, before := range budgetsBefore {
+		got, _ := db.BudgetFor(eAnd.ID, cycle)
+		want := sumBudgets(before[0], before[1])
+		if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("%s: expected combined budget %v, got %v", cycle, budgetValue(want), budgetValue(got))
+		}
+	}
+}
+
+func TestMergeCategory_Refusals(t *testing.T) {
+	db := setupTestDB(t)
+	groceries := categoryByName(t, db, "Groceries")
+	dining := categoryByName(t, db, "Dining Out & Delivery")
+	savings := categoryByName(t, db, "Savings")
+	investment := categoryByName(t, db, "Investment")
+
+	if _, err := db.MergeCategory(groceries.ID, groceries.ID); err == nil || !strings.HasPrefix(err.Error(), "invalid merge") {
+		t.Errorf("expected merging into itself to be refused, got %v", err)
+	}
+	if _, err := db.MergeCategory(9999, groceries.ID); err == nil || err.Error() != "category not found" {
+		t.Errorf("expected category not found, got %v", err)
+	}
+
+	butcher, _ := db.CreateCategory("Butcher", "🥩", false, "wants", nil, "actual")
+	db.SetCategoryParent(butcher.ID, &groceries.ID)
+	if _, err := db.MergeCategory(groceries.ID, dining.ID); err == nil || !strings.HasPrefix(err.Error(), "invalid merge") {
+		t.Errorf("expected a parent with sub-categories to be refused, got %v", err)
+	}
+
+	for _, cat := range []Category{savings, investment} {
+		if _, err := db.CreateGoal(SavingsGoal{CategoryID: cat.ID, Name: cat.Name, TargetAmount: 10000, TargetDate: "2027-12-31"}); err != nil {
+			t.Fatalf("CreateGoal failed: %v", err)
+		}
+	}
+	if _, err := db.MergeCategory(savings.ID, investment.ID); err == nil || !strings.Contains(err.Error(), "savings goal") {
+		t.Errorf("expected two goals to be refused, got %v", err)
+	}
+
+	insertTestTransaction(t, db, Transaction{Description: "Zomato", Amount: 80, Date: "2026-01-25", Category: "Dining Out & Delivery", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual"})
+	if _, err := db.CloseCycle("Jan 2026"); err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+	if _, err := db.MergeCategory(dining.ID, butcher.ID); err == nil || !strings.HasPrefix(err.Error(), "cycle closed:") {
+		t.Errorf("expected a closed cycle to block the merge, got %v", err)
+	}
+	if got := categoryByName(t, db, "Dining Out & Delivery"); got.ID != dining.ID {
+		t.Error("expected the refused merge to leave the source in place")
+	}
+}
+
+func TestMergeCategory_KeepsClosedCarryover(t *testing.T) {
+	db := setupTestDB(t)
+	groc := categoryByName(t, db, "Groceries") // budget 2000
+	db.SetCarryover(groc.ID, true)
+	db.ResetCarryover(groc.ID, "Jan 2026")
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour Jan", Amount: 1500, Date: "2026-02-01", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "openai"})
+	if _, err := db.CloseCycle("Jan 2026"); err != nil {
+		t.Fatalf("CloseCycle failed: %v", err)
+	}
+
+	butcher, _ := db.CreateCategory("Butcher", "🥩", false, "wants", nil, "actual")
+	if _, err := db.MergeCategory(butcher.ID, groc.ID); err != nil {
+		t.Fatalf("MergeCategory failed: %v", err)
+	}
+	var rows int
+	db.db.QueryRow("SELECT COUNT(*) FROM carryover_ledger WHERE category_id = ? AND cycle = 'Jan 2026'", groc.ID).Scan(&rows)
+	if rows != 1 {
+		t.Errorf("expected the closed cycle's carry-over to survive the merge, got %d rows", rows)
+	}
+}
@@ -24,6 +24,27 @@ type Transaction struct {
 	Timestamp    string  `json:"timestamp,omitempty"`
 	BillingCycle string  `json:"billingCycle,omitempty"`
 	Source       string  `json:"source,omitempty"`
+	// Account is the card or account the SMS names ("Visa ending 1234") and
+	// Currency the original currency when it wasn't AED. Merchant rule
+	// conditions can match on either.
+	Account  string `json:"account,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// OriginalDescription is the description as received when a rule has
+	// renamed it; Excluded keeps the transaction out of spend totals and
+	// Approved takes it out of the review queue (see ruleactions.go).
+	OriginalDescription string `json:"originalDescription,omitempty"`
+	Excluded            bool   `json:"excluded,omitempty"`
+	Approved            bool   `json:"approved"`
+	// Tags are the transaction's free-form tags (see tags.go). On update,
+	// nil leaves them alone and an empty list clears them.
+	Tags []string `json:"tags,omitempty"`
+	// Splits are the lines a split transaction is counted through (see
+	// splits.go). In a category breakdown a split line is listed on its own,
+	// with SplitID set, the line's amount and category, and the line's note
+	// in place of the transaction's.
+	Splits  []TransactionSplit `json:"splits,omitempty"`
+	SplitID int64              `json:"splitId,omitempty"`
+	Note    string             `json:"note,omitempty"`
 }
 
 type openAIRequest struct {
@@ -46,12 +67,32 @@ type openAIResponse struct {
 	} `json:"choices"`
 }
 
-func BuildSystemPrompt(categories []Category) string {
-	names := make([]string, len(categories))
-	for i, c := range categories {
-		names[i] = fmt.Sprintf("%q", c.Name)
+// categoryPromptList renders the category names for the LLM prompt, with
+// each sub-category followed by its parent so the model can see the tree.
+// Archived categories are left out.
+func categoryPromptList(categories []Category) string {
+	byID := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c.Name
+	}
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		if c.Archived {
+			continue
+		}
+		name := fmt.Sprintf("%q", c.Name)
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				name += fmt.Sprintf(" (under %q)", parent)
+			}
+		}
+		names = append(names, name)
 	}
-	categoryList := strings.Join(names, ", ")
+	return strings.Join(names, ", ")
+}
+
+func BuildSystemPrompt(categories []Category) string {
+	categoryList := categoryPromptList(categories)
 
 	return `You are a financial transaction parser for UAE-based transactions. Extract transaction details from SMS messages and convert ALL amounts to AED (UAE Dirham).
 
@@ -62,7 +103,10 @@ Each transaction object must have these exact fields:
 - description: merchant or transaction description
 - amount: numeric value CONVERTED TO AED as a number (positive for expenses, negative for income/deposits)
 - category: exactly ONE of these categories: ` + categoryList + `
+  (a category marked "under" another is a sub-category; prefer it over its parent when it fits, and return only its own name)
 - confidence: number from 0-100
+- account: the card or account named in the SMS (e.g. "Visa ending 1234"), or "" if none
+- currency: the ISO code of the ORIGINAL currency before conversion (e.g. "USD"), or "AED"
 
 Currency Conversion Rules:
 - If amount is in AED: keep as-is
@@ -97,7 +141,9 @@ Example response for multiple transactions:
     "description": "Starbucks Dubai Mall",
     "amount": 25.50,
     "category": "Dining Out",
-    "confidence": 95
+    "confidence": 95,
+    "account": "Visa ending 1234",
+    "currency": "AED"
   }
 ]`
 }
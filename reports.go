@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ReportCell is one category (or bucket) in one cycle. Actual is what the
+// dashboard counts: the funded amount for allocated and goal lines, real
+// transactions otherwise. Variance is Budget − Actual, so a positive
+// variance is money left under budget. Funded is set for allocated lines
+// only.
+type ReportCell struct {
+	Budget   *float64 `json:"budget"`
+	Actual   float64  `json:"actual"`
+	Variance *float64 `json:"variance"`
+	Funded   *bool    `json:"funded,omitempty"`
+}
+
+// ReportRow is a category across the report's cycles; Cells line up with
+// BudgetVsActualReport.Cycles.
+type ReportRow struct {
+	CategoryID int64        `json:"categoryId"`
+	Category   string       `json:"category"`
+	Emoji      string       `json:"emoji"`
+	Type       string       `json:"type"`
+	Tracking   string       `json:"tracking"`
+	Cells      []ReportCell `json:"cells"`
+	Total      ReportCell   `json:"total"`
+}
+
+// ReportBucket is a fixed/wants/goals subtotal per cycle, taken from the
+// same figures GetStats reports for the dashboard.
+type ReportBucket struct {
+	Bucket string       `json:"bucket"`
+	Cells  []ReportCell `json:"cells"`
+	Total  ReportCell   `json:"total"`
+}
+
+type BudgetVsActualReport struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Cycles  []string       `json:"cycles"`
+	Rows    []ReportRow    `json:"rows"`
+	Buckets []ReportBucket `json:"buckets"`
+}
+
+func newReportCell(budget *float64, actual float64) ReportCell {
+	cell := ReportCell{Budget: budget, Actual: actual}
+	if budget != nil {
+		cell.Variance = floatPtr(*budget - actual)
+	}
+	return cell
+}
+
+// sumReportCells totals a row's cells; the budget is nil only when no
+// cycle had one.
+func sumReportCells(cells []ReportCell) ReportCell {
+	var budget *float64
+	var actual float64
+	for _, cell := range cells {
+		if cell.Budget != nil {
+			if budget == nil {
+				budget = floatPtr(0)
+			}
+			*budget += *cell.Budget
+		}
+		actual += cell.Actual
+	}
+	return newReportCell(budget, actual)
+}
+
+// reportMaxCycles caps a report's range: every cycle costs a GetStats.
+const reportMaxCycles = 24
+
+// GetBudgetVsActual builds the budget-vs-actual matrix for every cycle from
+// `from` through `to`, at most reportMaxCycles of them. Categories excluded
+// from totals are left out, as are categories with neither a budget nor any
+// actual in the range.
+func (c *DatabaseClient) GetBudgetVsActual(from, to string) (*BudgetVsActualReport, error) {
+	cycles := cyclesBetween(from, to)
+	if len(cycles) == 0 || len(cycles) > reportMaxCycles {
+		return nil, fmt.Errorf("invalid cycle range")
+	}
+
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]ReportRow, len(cats))
+	rowIndex := make(map[int64]int, len(cats))
+	for i, cat := range cats {
+		rowIndex[cat.ID] = i
+		rows[i] = ReportRow{
+			CategoryID: cat.ID,
+			Category:   cat.Name,
+			Emoji:      cat.Emoji,
+			Type:       cat.Type,
+			Tracking:   cat.Tracking,
+			Cells:      make([]ReportCell, len(cycles)),
+		}
+	}
+	buckets := []ReportBucket{
+		{Bucket: "fixed", Cells: make([]ReportCell, len(cycles))},
+		{Bucket: "wants", Cells: make([]ReportCell, len(cycles))},
+		{Bucket: "goals", Cells: make([]ReportCell, len(cycles))},
+	}
+
+	for j, cycle := range cycles {
+		stats, err := c.GetStats(cycle)
+		if err != nil {
+			return nil, err
+		}
+		spent := make(map[string]float64, len(stats.Categories))
+		for _, cs := range flatCategoryStats(stats.Categories) {
+			spent[cs.Category] = cs.Total
+		}
+		funding := make(map[int64]FundingStatus, len(stats.Funding))
+		for _, f := range stats.Funding {
+			funding[f.CategoryID] = f
+		}
+
+		for _, cat := range stats.CategoryDefinitions {
+			i, ok := rowIndex[cat.ID]
+			if !ok {
+				continue
+			}
+			actual := spent[cat.Name]
+			if cat.Tracking == "allocated" || cat.Type == "goal" {
+				actual = funding[cat.ID].Funded
+			}
+			cell := newReportCell(cat.BudgetAmount, actual)
+			if cat.Tracking == "allocated" {
+				funded := funding[cat.ID].Complete
+				cell.Funded = &funded
+			}
+			rows[i].Cells[j] = cell
+		}
+
+		buckets[0].Cells[j] = newReportCell(floatPtr(stats.FixedBudget), stats.FixedTotal)
+		buckets[1].Cells[j] = newReportCell(floatPtr(stats.WantsBudget), stats.WantsTotal)
+		buckets[2].Cells[j] = newReportCell(floatPtr(stats.GoalsBudget), stats.GoalsFunded)
+	}
+
+	report := &BudgetVsActualReport{From: cycles[0], To: cycles[len(cycles)-1], Cycles: cycles, Rows: []ReportRow{}}
+	for i, row := range rows {
+		if cats[i].ExcludeFromTotals {
+			continue
+		}
+		row.Total = sumReportCells(row.Cells)
+		if row.Total.Budget == nil && row.Total.Actual == 0 {
+			continue
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	for i := range buckets {
+		buckets[i].Total = sumReportCells(buckets[i].Cells)
+	}
+	report.Buckets = buckets
+	return report, nil
+}
+
+func formatReportAmount(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", *v)
+}
+
+// writeReportCSV writes the matrix one category per line, with budget,
+// actual, variance and funded columns for each cycle and the range total,
+// followed by the bucket subtotals.
+func writeReportCSV(w *csv.Writer, report *BudgetVsActualReport) {
+	header := []string{"Category", "Type", "Tracking"}
+	for _, cycle := range append(append([]string{}, report.Cycles...), "Total") {
+		header = append(header, cycle+" Budget", cycle+" Actual", cycle+" Variance", cycle+" Funded")
+	}
+	w.Write(header)
+
+	cellColumns := func(cell ReportCell) []string {
+		funded := ""
+		if cell.Funded != nil {
+			funded = "no"
+			if *cell.Funded {
+				funded = "yes"
+			}
+		}
+		return []string{formatReportAmount(cell.Budget), fmt.Sprintf("%.2f", cell.Actual), formatReportAmount(cell.Variance), funded}
+	}
+	for _, row := range report.Rows {
+		record := []string{row.Category, row.Type, row.Tracking}
+		for _, cell := range append(append([]ReportCell{}, row.Cells...), row.Total) {
+			record = append(record, cellColumns(cell)...)
+		}
+		w.Write(record)
+	}
+	for _, bucket := range report.Buckets {
+		record := []string{strings.ToUpper(bucket.Bucket[:1]) + bucket.Bucket[1:] + " total", bucket.Bucket, ""}
+		for _, cell := range append(append([]ReportCell{}, bucket.Cells...), bucket.Total) {
+			record = append(record, cellColumns(cell)...)
+		}
+		w.Write(record)
+	}
+}
+
+// reportsHandler serves GET /reports/budget-vs-actual?from=&to=. The range
+// defaults to the current cycle and the two before it (a quarter). JSON by
+// default; ?format=csv downloads the matrix.
+func reportsHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimSuffix(r.URL.Path, "/") != "/reports/budget-vs-actual" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		to := r.URL.Query().Get("to")
+		if to == "" {
+			to = calculateBillingCycle(time.Now().Format("2006-01-02"))
+		}
+		from := r.URL.Query().Get("from")
+		if from == "" {
+			from = shiftCycle(to, -2)
+		}
+		log.Printf("[API] GET /reports/budget-vs-actual - %s to %s from %s", from, to, r.RemoteAddr)
+
+		report, err := db.GetBudgetVsActual(from, to)
+		if err != nil {
+			log.Printf("[API] Failed to build budget-vs-actual report: %v", err)
+			if err.Error() == "invalid cycle range" {
+				http.Error(w, "Invalid cycle range", http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to build report", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if r.URL.Query().Get("format") == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="budget-vs-actual.csv"`)
+			writer := csv.NewWriter(w)
+			writeReportCSV(writer, report)
+			writer.Flush()
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"report":  report,
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func reportRow(t *testing.T, report *BudgetVsActualReport, name string) ReportRow {
+	t.Helper()
+	for _, row := range report.Rows {
+		if row.Category == name {
+			return row
+		}
+	}
+	t.Fatalf("row %s not in report", name)
+	return ReportRow{}
+}
+
+func seedReportData(t *testing.T, db *DatabaseClient) {
+	t.Helper()
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour", Amount: 1500, Date: "2026-01-25", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "Spinneys", Amount: 2500, Date: "2026-02-25", Category: "Groceries", BillingCycle: "Feb 2026", Timestamp: "2026-02-25T10:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "DEWA", Amount: 700, Date: "2026-02-01", Category: "DEWA", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "manual"})
+	rent := categoryByName(t, db, "Rent")
+	if err := db.SetFunding("Jan 2026", rent.ID, 9000); err != nil {
+		t.Fatalf("SetFunding failed: %v", err)
+	}
+	if _, err := db.AddFundingEntry("Feb 2026", rent.ID, 4500, "", ""); err != nil {
+		t.Fatalf("AddFundingEntry failed: %v", err)
+	}
+}
+
+func TestBudgetVsActual_Matrix(t *testing.T) {
+	db := setupTestDB(t)
+	seedReportData(t, db)
+
+	report, err := db.GetBudgetVsActual("Jan 2026", "Feb 2026")
+	if err != nil {
+		t.Fatalf("GetBudgetVsActual failed: %v", err)
+	}
+	if len(report.Cycles) != 2 || report.Cycles[0] != "Jan 2026" || report.Cycles[1] != "Feb 2026" {
+		t.Fatalf("unexpected cycles: %v", report.Cycles)
+	}
+
+	groc := reportRow(t, report, "Groceries")
+	if *groc.Cells[0].Variance != 500 || *groc.Cells[1].Variance != -500 {
+		t.Errorf("expected Groceries variance 500 then -500, got %v / %v", *groc.Cells[0].Variance, *groc.Cells[1].Variance)
+	}
+	if groc.Cells[0].Funded != nil {
+		t.Error("expected no funded flag on an actual-tracked line")
+	}
+	if *groc.Total.Budget != 4000 || groc.Total.Actual != 4000 || *groc.Total.Variance != 0 {
+		t.Errorf("unexpected Groceries total: %+v", groc.Total)
+	}
+
+	rent := reportRow(t, report, "Rent")
+	if rent.Cells[0].Actual != 9000 || !*rent.Cells[0].Funded {
+		t.Errorf("expected Rent fully funded in Jan, got %+v", rent.Cells[0])
+	}
+	if rent.Cells[1].Actual != 4500 || *rent.Cells[1].Funded {
+		t.Errorf("expected Rent half funded in Feb, got %+v", rent.Cells[1])
+	}
+
+	for _, row := range report.Rows {
+		if row.Category == "Income/Transfer" {
+			t.Error("expected excluded categories to be left out")
+		}
+	}
+
+	// Bucket subtotals are the dashboard's own numbers.
+	for j, cycle := range report.Cycles {
+		stats, err := db.GetStats(cycle)
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		fixed, wants, goals := report.Buckets[0].Cells[j], report.Buckets[1].Cells[j], report.Buckets[2].Cells[j]
+		if fixed.Actual != stats.FixedTotal || *fixed.Budget != stats.FixedBudget {
+			t.Errorf("%s: fixed bucket %+v does not match stats %v/%v", cycle, fixed, stats.FixedTotal, stats.FixedBudget)
+		}
+		if wants.Actual != stats.WantsTotal || goals.Actual != stats.GoalsFunded {
+			t.Errorf("%s: wants/goals buckets do not match stats", cycle)
+		}
+	}
+	if report.Buckets[0].Cells[0].Actual != 9700 {
+		t.Errorf("expected Jan fixed actual 9000 funded + 700 DEWA, got %v", report.Buckets[0].Cells[0].Actual)
+	}
+
+	if _, err := db.GetBudgetVsActual("Mar 2026", "Jan 2026"); err == nil || err.Error() != "invalid cycle range" {
+		t.Errorf("expected invalid cycle range, got %v", err)
+	}
+}
+
+func TestBudgetVsActualHandler_CSV(t *testing.T) {
+	db := setupTestDB(t)
+	seedReportData(t, db)
+	handler := reportsHandler(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/reports/budget-vs-actual?from=Jan%202026&to=Feb%202026&format=csv", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if got := records[0][3]; got != "Jan 2026 Budget" || len(records[0]) != 3+4*3 {
+		t.Errorf("unexpected header: %v", records[0])
+	}
+	last := records[len(records)-1]
+	if last[0] != "Goals total" {
+		t.Errorf("expected bucket subtotals last, got %v", last)
+	}
+
+	for _, query := range []string{"from=bogus&to=Feb%202026", "from=Jan%202000&to=Feb%202026"} {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/reports/budget-vs-actual?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 for a bad range, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// When a merchant rule fires it sets the transaction's category and, after
+// that, runs its actions in order: renaming the description to a clean
+// merchant name, adding tags, excluding the transaction from totals (a
+// transfer between your own accounts), setting a note, or approving it out
+// of the review queue. The tags on a rule (rule_tags) are applied as if
+// they were an add_tags action at the front of the list. Every change is
+// written to the transaction's rule log.
+//
+// A renamed transaction keeps its original description, and rules are
+// matched against that, so re-applying rules sees the bank's text rather
+// than an earlier rename.
+
+// RuleAction is one step a rule applies after setting the category.
+type RuleAction struct {
+	Type  string   `json:"type"`            // rename, add_tags, exclude, set_note or approve
+	Value string   `json:"value,omitempty"` // the new description for rename, the note for set_note
+	Tags  []string `json:"tags,omitempty"`  // for add_tags
+}
+
+const (
+	ActionRename  = "rename"
+	ActionAddTags = "add_tags"
+	ActionExclude = "exclude"
+	ActionSetNote = "set_note"
+	ActionApprove = "approve"
+)
+
+// RuleLogEntry records one change a rule made to a transaction.
+type RuleLogEntry struct {
+	RuleID    int64  `json:"ruleId"`
+	Keyword   string `json:"keyword"`
+	Action    string `json:"action"` // "category" or an action type
+	Detail    string `json:"detail,omitempty"`
+	AppliedAt string `json:"appliedAt"`
+}
+
+func (c *DatabaseClient) migrateRuleActions() error {
+	columns := []struct{ table, def string }{
+		{"merchant_rules", "actions TEXT NOT NULL DEFAULT '[]'"},
+		{"transactions", "original_description TEXT NOT NULL DEFAULT ''"},
+		{"transactions", "excluded INTEGER NOT NULL DEFAULT 0"},
+		{"transactions", "note TEXT NOT NULL DEFAULT ''"},
+		// Transactions from before the review queue count as reviewed; new
+		// ones are inserted with an explicit value.
+		{"transactions", "approved INTEGER NOT NULL DEFAULT 1"},
+	}
+	for _, col := range columns {
+		if err := c.addColumnIfNotExists(col.table, col.def); err != nil {
+			return fmt.Errorf("failed to add %s column to %s: %w", col.def, col.table, err)
+		}
+	}
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS rule_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id INTEGER NOT NULL,
+			rule_id INTEGER NOT NULL,
+			keyword TEXT NOT NULL,
+			action TEXT NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			applied_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rule_log_tx ON rule_log(transaction_id)`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("rule_log migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateRuleActions normalizes actions in place and checks each has what
+// it needs.
+func validateRuleActions(actions []RuleAction) error {
+	for i := range actions {
+		a := &actions[i]
+		a.Type = strings.ToLower(strings.TrimSpace(a.Type))
+		a.Value = strings.TrimSpace(a.Value)
+		switch a.Type {
+		case ActionRename, ActionSetNote:
+			if a.Value == "" {
+				return fmt.Errorf("invalid actions: %s needs a value", a.Type)
+			}
+		case ActionAddTags:
+			a.Tags = cleanTagNames(a.Tags)
+			if len(a.Tags) == 0 {
+				return fmt.Errorf("invalid actions: add_tags needs tags")
+			}
+		case ActionExclude, ActionApprove:
+		default:
+			return fmt.Errorf("invalid actions: unknown action %q", a.Type)
+		}
+	}
+	return nil
+}
+
+// apply sets the rule's category on tx and runs its actions, returning what
+// changed for the rule log. Steps that would change nothing are skipped, so
+// re-applying a rule is a no-op, and set_note never replaces an existing
+// note.
+func (rule MerchantRule) apply(tx *Transaction) []RuleLogEntry {
+	now := time.Now().Format(time.RFC3339)
+	entry := func(action, detail string) RuleLogEntry {
+		return RuleLogEntry{RuleID: rule.ID, Keyword: rule.Keyword, Action: action, Detail: detail, AppliedAt: now}
+	}
+
+	var entries []RuleLogEntry
+	if tx.Category != rule.Category || tx.Source != "rule" {
+		tx.Category = rule.Category
+		tx.Source = "rule"
+		entries = append(entries, entry("category", rule.Category))
+	}
+
+	actions := rule.Actions
+	if len(rule.Tags) > 0 {
+		actions = append([]RuleAction{{Type: ActionAddTags, Tags: rule.Tags}}, actions...)
+	}
+	for _, a := range actions {
+		switch a.Type {
+		case ActionRename:
+			if tx.Description == a.Value {
+				continue
+			}
+			if tx.OriginalDescription == "" {
+				tx.OriginalDescription = tx.Description
+			}
+			tx.Description = a.Value
+			entries = append(entries, entry(a.Type, a.Value))
+		case ActionAddTags:
+			var added []string
+			for _, tag := range cleanTagNames(a.Tags) {
+				if !hasTag(tx.Tags, tag) {
+					added = append(added, tag)
+				}
+			}
+			if len(added) == 0 {
+				continue
+			}
+			tx.Tags = append(tx.Tags, added...)
+			entries = append(entries, entry(a.Type, strings.Join(added, ", ")))
+		case ActionExclude:
+			if tx.Excluded {
+				continue
+			}
+			tx.Excluded = true
+			entries = append(entries, entry(a.Type, ""))
+		case ActionSetNote:
+			if tx.Note != "" {
+				continue
+			}
+			tx.Note = a.Value
+			entries = append(entries, entry(a.Type, a.Value))
+		case ActionApprove:
+			if tx.Approved {
+				continue
+			}
+			tx.Approved = true
+			entries = append(entries, entry(a.Type, ""))
+		}
+	}
+	return entries
+}
+
+// saveRuleLog appends entries to a transaction's rule log and counts the
+// hit against the rule that made them.
+func (c *DatabaseClient) saveRuleLog(txID int64, entries []RuleLogEntry) error {
+	return saveRuleLog(c.db, txID, entries)
+}
+
+func saveRuleLog(ex execer, txID int64, entries []RuleLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := recordRuleHit(ex, entries[0].RuleID, entries[0].AppliedAt); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := ex.Exec(
+			"INSERT INTO rule_log (transaction_id, rule_id, keyword, action, detail, applied_at) VALUES (?, ?, ?, ?, ?, ?)",
+			txID, e.RuleID, e.Keyword, e.Action, e.Detail, e.AppliedAt,
+		); err != nil {
+			return fmt.Errorf("failed to save rule log: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetRuleLog returns the changes rules have made to a transaction, oldest
+// first.
+func (c *DatabaseClient) GetRuleLog(txID int64) ([]RuleLogEntry, error) {
+	var exists bool
+	if err := c.db.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions WHERE id = ?)", txID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("transaction not found")
+	}
+
+	rows, err := c.db.Query(
+		"SELECT rule_id, keyword, action, detail, applied_at FROM rule_log WHERE transaction_id = ? ORDER BY id ASC", txID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rule log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []RuleLogEntry{}
+	for rows.Next() {
+		var e RuleLogEntry
+		if err := rows.Scan(&e.RuleID, &e.Keyword, &e.Action, &e.Detail, &e.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rule log: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// applyRuleTo applies a rule to a stored transaction, given its current
+// tags, inside a SQL transaction: what the rule changes is written back and
+// logged. It reports whether anything changed.
+func applyRuleTo(sqlTx *sql.Tx, tx Transaction, tags []string, rule MerchantRule) (bool, error) {
+	tx.Tags = tags
+	before := len(tx.Tags)
+
+	entries := rule.apply(&tx)
+	if len(entries) == 0 {
+		return false, nil
+	}
+	if _, err := sqlTx.Exec(
+		`UPDATE transactions SET category = ?, source = ?, description = ?, original_description = ?,
+			excluded = ?, note = ?, approved = ? WHERE id = ?`,
+		tx.Category, tx.Source, tx.Description, tx.OriginalDescription, tx.Excluded, tx.Note, tx.Approved, tx.ID,
+	); err != nil {
+		return false, fmt.Errorf("failed to apply rule: %w", err)
+	}
+	if len(tx.Tags) != before {
+		if err := setLinkedTagsTx(sqlTx, "transaction_tags", "transaction_id", tx.ID, tx.Tags); err != nil {
+			return false, err
+		}
+	}
+	return true, saveRuleLog(sqlTx, tx.ID, entries)
+}
+
+// SetRuleActions replaces a rule's actions.
+func (c *DatabaseClient) SetRuleActions(id int64, actions []RuleAction) error {
+	if err := validateRuleActions(actions); err != nil {
+		return err
+	}
+	encoded, err := encodeRuleActions(actions)
+	if err != nil {
+		return err
+	}
+	result, err := c.db.Exec("UPDATE merchant_rules SET actions = ? WHERE id = ?", encoded, id)
+	if err != nil {
+		return fmt.Errorf("failed to update rule actions: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("rule not found")
+	}
+	return nil
+}
+
+func encodeRuleActions(actions []RuleAction) (string, error) {
+	if actions == nil {
+		actions = []RuleAction{}
+	}
+	encoded, err := json.Marshal(actions)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode rule actions: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func decodeRuleActions(encoded string) []RuleAction {
+	var actions []RuleAction
+	if err := json.Unmarshal([]byte(encoded), &actions); err != nil {
+		log.Printf("[Database] Ignoring unreadable rule actions %q: %v", encoded, err)
+		return nil
+	}
+	return actions
+}
+
+// GetReviewQueue returns a cycle's transactions that haven't been approved,
+// newest first. Manual entries and edits count as approved.
+func (c *DatabaseClient) GetReviewQueue(cycle string) ([]Transaction, error) {
+	rows, err := c.db.Query(`
+		SELECT id, description, amount, transaction_date, category, confidence, billing_cycle, created_at, source, note, excluded
+		FROM transactions
+		WHERE billing_cycle = ? AND approved = 0
+		ORDER BY transaction_date DESC, created_at DESC`, cycle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review queue: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []Transaction{}
+	for rows.Next() {
+		var tx Transaction
+		if err := rows.Scan(&tx.ID, &tx.Description, &tx.Amount, &tx.Date, &tx.Category, &tx.Confidence, &tx.BillingCycle, &tx.Timestamp, &tx.Source, &tx.Note, &tx.Excluded); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
+// ApproveTransaction takes a transaction out of the review queue.
+func (c *DatabaseClient) ApproveTransaction(id int64) error {
+	result, err := c.db.Exec("UPDATE transactions SET approved = 1 WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to approve transaction: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("transaction not found")
+	}
+	return nil
+}
+
+// transactionExcluded reports whether a transaction is kept out of totals.
+func (c *DatabaseClient) transactionExcluded(id int64) (bool, error) {
+	var excluded bool
+	err := c.db.QueryRow("SELECT excluded FROM transactions WHERE id = ?", id).Scan(&excluded)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("transaction not found")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	return excluded, nil
+}
+
+// reviewHandler serves GET /review?cycle=: the transactions still waiting
+// to be approved (default: the current cycle).
+func reviewHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[API] GET /review - Request from %s", r.RemoteAddr)
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		cycle := r.URL.Query().Get("cycle")
+		if cycle == "" {
+			cycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+		}
+		transactions, err := db.GetReviewQueue(cycle)
+		if err != nil {
+			log.Printf("[API] Failed to get review queue: %v", err)
+			http.Error(w, "Failed to retrieve review queue", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":      true,
+			"cycle":        cycle,
+			"transactions": transactions,
+		})
+	}
+}
+
+// approveHandler serves POST /transaction/:id/approve.
+func approveHandler(db *DatabaseClient, id int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[API] POST /transaction/%d/approve - Approve from %s", id, r.RemoteAddr)
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := db.ApproveTransaction(id); err != nil {
+			log.Printf("[API] Failed to approve transaction: %v", err)
+			if err.Error() == "transaction not found" {
+				http.Error(w, "Transaction not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to approve transaction", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+		})
+	}
+}
+
+// ruleLogHandler serves GET /transaction/:id/rule-log.
+func ruleLogHandler(db *DatabaseClient, id int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[API] GET /transaction/%d/rule-log - Request from %s", id, r.RemoteAddr)
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		entries, err := db.GetRuleLog(id)
+		if err != nil {
+			log.Printf("[API] Failed to get rule log: %v", err)
+			if err.Error() == "transaction not found" {
+				http.Error(w, "Transaction not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to retrieve rule log", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"log":     entries,
+		})
+	}
+}
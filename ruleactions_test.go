@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRuleActions_Retroactive(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	insertTestTransaction(t, db, Transaction{Description: "POS 4471 AMZN MKTP AE*2K4", Amount: 120, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"})
+	insertTestTransaction(t, db, Transaction{Description: "TRANSFER TO OWN ACCOUNT 0021", Amount: 3000, Date: "2026-01-26", BillingCycle: "Jan 2026", Category: "Shopping", Timestamp: "2026-01-26T10:00:00Z", Source: "openai"})
+	before, _ := db.GetStats("Jan 2026")
+
+	amazon, _, _, err := db.CreateRule("amzn mktp", MatchContains, "Shopping & Gifts", 10)
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if err := db.SetRuleActions(amazon.ID, []RuleAction{
+		{Type: "rename", Value: "Amazon"},
+		{Type: "add_tags", Tags: []string{"online"}},
+		{Type: "set_note", Value: "check the order"},
+		{Type: "approve"},
+	}); err != nil {
+		t.Fatalf("SetRuleActions failed: %v", err)
+	}
+	transfer, _, _, _ := db.CreateRule("transfer to own account", MatchPrefix, "Transport", 5)
+	db.SetRuleActions(transfer.ID, []RuleAction{{Type: "exclude"}})
+
+	if updated, _, err := db.ApplyAllRules(); err != nil || updated != 2 {
+		t.Fatalf("expected both transactions updated, got %d (%v)", updated, err)
+	}
+
+	stats, _ := db.GetStats("Jan 2026")
+	var renamed *Transaction
+	for i, tx := range stats.AllTransactions {
+		if tx.Description == "Amazon" {
+			renamed = &stats.AllTransactions[i]
+		}
+	}
+	if renamed == nil || renamed.OriginalDescription != "POS 4471 AMZN MKTP AE*2K4" || renamed.Note != "check the order" || !renamed.Approved || !hasTag(renamed.Tags, "online") {
+		t.Fatalf("expected the Amazon transaction renamed, tagged, noted and approved, got %+v", renamed)
+	}
+	if stats.Total != before.Total-3000 {
+		t.Errorf("expected the excluded transfer left out of the total, got %v → %v", before.Total, stats.Total)
+	}
+	if row := statsRow(t, stats.Categories, "Shopping & Gifts"); len(row.Transactions) != 1 || row.Transactions[0].Note != "check the order" {
+		t.Errorf("expected the note in the category breakdown, got %+v", row.Transactions)
+	}
+
+	// Re-applying changes nothing, so nothing is written or logged.
+	for i := 0; i < 2; i++ {
+		if updated, _, err := db.ApplyRuleSingle(amazon.ID); err != nil || updated != 0 {
+			t.Errorf("expected re-applying to change nothing, got %d (%v)", updated, err)
+		}
+		if updated, _, err := db.ApplyAllRules(); err != nil || updated != 0 {
+			t.Errorf("expected re-applying all rules to change nothing, got %d (%v)", updated, err)
+		}
+	}
+	logged := func() string {
+		t.Helper()
+		entries, err := db.GetRuleLog(renamed.ID)
+		if err != nil {
+			t.Fatalf("GetRuleLog failed: %v", err)
+		}
+		var actions []string
+		for _, e := range entries {
+			actions = append(actions, e.Action)
+		}
+		return strings.Join(actions, ",")
+	}
+	want := "category,rename,add_tags,set_note,approve"
+	if got := logged(); got != want {
+		t.Errorf("expected the actions logged once, in order, got %v", got)
+	}
+	if rule, _ := db.getRule(amazon.ID); rule.HitCount != 1 {
+		t.Errorf("expected one hit, got %d", rule.HitCount)
+	}
+
+	// Once something drifts, re-applying still matches on the original text
+	// but only puts back what changed, leaving the user's note alone.
+	db.db.Exec("UPDATE transactions SET category = 'Groceries', note = 'returned one item' WHERE id = ?", renamed.ID)
+	if updated, _, err := db.ApplyAllRules(); err != nil || updated != 1 {
+		t.Errorf("expected the drifted transaction updated, got %d (%v)", updated, err)
+	}
+	if got := logged(); got != want+",category" {
+		t.Errorf("expected only the category logged again, got %v", got)
+	}
+	var note string
+	db.db.QueryRow("SELECT note FROM transactions WHERE id = ?", renamed.ID).Scan(&note)
+	if note != "returned one item" {
+		t.Errorf("expected the user's note kept, got %q", note)
+	}
+
+	queue, _ := db.GetReviewQueue("Jan 2026")
+	if len(queue) != 1 || queue[0].Category != "Transport" {
+		t.Errorf("expected only the transfer left to review, got %+v", queue)
+	}
+	if err := db.ApproveTransaction(queue[0].ID); err != nil {
+		t.Fatalf("ApproveTransaction failed: %v", err)
+	}
+	if queue, _ := db.GetReviewQueue("Jan 2026"); len(queue) != 0 {
+		t.Errorf("expected an empty review queue, got %+v", queue)
+	}
+}
+
+func TestRuleActions_AtIngest(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	rule, _, _, _ := db.CreateRule("tabby", MatchWord, "Shopping & Gifts", 10)
+	db.SetRuleTags(rule.ID, []string{"instalments"})
+	db.SetRuleActions(rule.ID, []RuleAction{{Type: "rename", Value: "Tabby instalment"}})
+
+	csv := "Date,Description,Amount (AED),Category\n" +
+		"2026-02-01,TABBY FZ LLC 0042,250.00,\n"
+	body, contentType := createMultipartCSV(t, csv)
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", contentType)
+	importHandler(db).ServeHTTP(httptest.NewRecorder(), req)
+
+	stats, _ := db.GetStats("Jan 2026")
+	if len(stats.AllTransactions) != 1 {
+		t.Fatalf("expected the row imported, got %+v", stats.AllTransactions)
+	}
+	tx := stats.AllTransactions[0]
+	if tx.Description != "Tabby instalment" || tx.Category != "Shopping & Gifts" || !hasTag(tx.Tags, "instalments") || tx.Approved {
+		t.Errorf("expected the row renamed, tagged and waiting for review, got %+v", tx)
+	}
+
+	w := httptest.NewRecorder()
+	transactionDetailHandler(db)(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/transaction/%d/rule-log", tx.ID), nil))
+	if !strings.Contains(w.Body.String(), `"action":"rename"`) || !strings.Contains(w.Body.String(), `"action":"add_tags"`) {
+		t.Errorf("expected the ingest changes logged, got %s", w.Body.String())
+	}
+}
+
+func TestRuleActions_Validation(t *testing.T) {
+	db := setupTestDB(t)
+	rule, _, _, _ := db.CreateRule("careem", MatchContains, "Transport", 0)
+
+	for name, actions := range map[string][]RuleAction{
+		"unknown":       {{Type: "delete"}},
+		"empty rename":  {{Type: "rename", Value: "  "}},
+		"no tags":       {{Type: "add_tags"}},
+		"empty note":    {{Type: "set_note"}},
+		"second broken": {{Type: "approve"}, {Type: "rename"}},
+	} {
+		if err := db.SetRuleActions(rule.ID, actions); err == nil || !strings.HasPrefix(err.Error(), "invalid actions") {
+			t.Errorf("%s: expected invalid actions, got %v", name, err)
+		}
+	}
+	if err := db.SetRuleActions(9999, nil); err == nil || err.Error() != "rule not found" {
+		t.Errorf("expected rule not found, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	rulesHandler(db)(w, httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(`{"keyword":"uber","category":"Transport","actions":[{"type":"explode"}]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown action, got %d", w.Code)
+	}
+}
+
+func TestRuleActions_ExcludedCanBeUndone(t *testing.T) {
+	db := setupTestDB(t)
+	id, err := db.SaveTransaction(Transaction{Description: "TRANSFER TO OWN ACCOUNT", Amount: 900, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Transport", Timestamp: "2026-01-25T10:00:00Z", Source: "openai", Excluded: true})
+	if err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	exportHandler(db)(w, httptest.NewRequest(http.MethodGet, "/export", nil))
+	if !strings.Contains(w.Body.String(), "Grand Total,0.00") {
+		t.Errorf("expected the excluded row left out of the export totals, got\n%s", w.Body.String())
+	}
+
+	// An edit that doesn't mention it leaves it excluded.
+	put := func(body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		transactionDetailHandler(db)(w, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/transaction/%d", id), strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+	put(`{"description": "Transfer", "amount": 900, "date": "2026-01-25", "category": "Transport"}`)
+	if excluded, _ := db.transactionExcluded(id); !excluded {
+		t.Error("expected an edit without excluded to keep it")
+	}
+	put(`{"description": "Transfer", "amount": 900, "date": "2026-01-25", "category": "Transport", "excluded": false}`)
+	if excluded, _ := db.transactionExcluded(id); excluded {
+		t.Error("expected the edit to bring it back into totals")
+	}
+	if stats, _ := db.GetStats("Jan 2026"); stats.Total != 900 {
+		t.Errorf("expected the transfer counted again, got %v", stats.Total)
+	}
+}
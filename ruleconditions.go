@@ -0,0 +1,188 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// RuleConditions narrow a merchant rule beyond its description pattern.
+// Every condition that is set must hold (they are ANDed); unset ones match
+// anything. Amount bounds compare the absolute amount, so a 9,000 transfer
+// matches MinAmount 5000 whichever way it went; AmountSign picks the
+// direction. A day window may wrap the month end (DayFrom 25, DayTo 5).
+type RuleConditions struct {
+	MinAmount  *float64 `json:"minAmount,omitempty"`
+	MaxAmount  *float64 `json:"maxAmount,omitempty"`
+	AmountSign string   `json:"amountSign,omitempty"` // "debit" (spend, amount > 0) or "credit" (amount < 0)
+	DayFrom    *int     `json:"dayFrom,omitempty"`
+	DayTo      *int     `json:"dayTo,omitempty"`
+	Account    string   `json:"account,omitempty"`  // matched as a substring of the transaction's account or card
+	Currency   string   `json:"currency,omitempty"` // original currency code; transactions without one are AED
+}
+
+const (
+	AmountDebit  = "debit"
+	AmountCredit = "credit"
+)
+
+// ruleConditionColumns lists merchant_rules' condition columns in the order
+// ruleConditionRow scans them.
+const ruleConditionColumns = "min_amount, max_amount, amount_sign, day_from, day_to, account, currency"
+
+func (c *DatabaseClient) migrateRuleConditions() error {
+	columns := []struct{ table, def string }{
+		{"merchant_rules", "min_amount REAL"},
+		{"merchant_rules", "max_amount REAL"},
+		{"merchant_rules", "amount_sign TEXT NOT NULL DEFAULT ''"},
+		{"merchant_rules", "day_from INTEGER"},
+		{"merchant_rules", "day_to INTEGER"},
+		{"merchant_rules", "account TEXT NOT NULL DEFAULT ''"},
+		{"merchant_rules", "currency TEXT NOT NULL DEFAULT ''"},
+		// What the conditions are checked against on the transaction side.
+		{"transactions", "account TEXT NOT NULL DEFAULT ''"},
+		{"transactions", "currency TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, col := range columns {
+		if err := c.addColumnIfNotExists(col.table, col.def); err != nil {
+			return fmt.Errorf("failed to add %s column to %s: %w", col.def, col.table, err)
+		}
+	}
+	return nil
+}
+
+// validateRuleConditions normalizes conditions in place and checks they can
+// be met.
+func validateRuleConditions(conds *RuleConditions) error {
+	conds.AmountSign = strings.ToLower(strings.TrimSpace(conds.AmountSign))
+	conds.Account = strings.TrimSpace(conds.Account)
+	conds.Currency = strings.ToUpper(strings.TrimSpace(conds.Currency))
+
+	if conds.AmountSign != "" && conds.AmountSign != AmountDebit && conds.AmountSign != AmountCredit {
+		return fmt.Errorf("invalid conditions: amountSign must be %s or %s", AmountDebit, AmountCredit)
+	}
+	if (conds.MinAmount != nil && *conds.MinAmount < 0) || (conds.MaxAmount != nil && *conds.MaxAmount < 0) {
+		return fmt.Errorf("invalid conditions: amount bounds must not be negative")
+	}
+	if conds.MinAmount != nil && conds.MaxAmount != nil && *conds.MinAmount > *conds.MaxAmount {
+		return fmt.Errorf("invalid conditions: minAmount is greater than maxAmount")
+	}
+	if (conds.DayFrom == nil) != (conds.DayTo == nil) {
+		return fmt.Errorf("invalid conditions: dayFrom and dayTo must be set together")
+	}
+	if conds.DayFrom != nil && (*conds.DayFrom < 1 || *conds.DayFrom > 31 || *conds.DayTo < 1 || *conds.DayTo > 31) {
+		return fmt.Errorf("invalid conditions: days must be between 1 and 31")
+	}
+	if conds.Currency != "" && len(conds.Currency) != 3 {
+		return fmt.Errorf("invalid conditions: currency must be a 3-letter code")
+	}
+	return nil
+}
+
+// conditionsMet reports whether a transaction meets every condition.
+func (conds RuleConditions) conditionsMet(tx Transaction) bool {
+	amount := math.Abs(tx.Amount)
+	if conds.MinAmount != nil && amount < *conds.MinAmount {
+		return false
+	}
+	if conds.MaxAmount != nil && amount > *conds.MaxAmount {
+		return false
+	}
+	if conds.AmountSign == AmountDebit && tx.Amount <= 0 || conds.AmountSign == AmountCredit && tx.Amount >= 0 {
+		return false
+	}
+	if conds.DayFrom != nil && conds.DayTo != nil {
+		day, ok := dayOfMonth(tx.Date)
+		if !ok {
+			return false
+		}
+		if *conds.DayFrom <= *conds.DayTo {
+			if day < *conds.DayFrom || day > *conds.DayTo {
+				return false
+			}
+		} else if day < *conds.DayFrom && day > *conds.DayTo {
+			return false
+		}
+	}
+	if conds.Account != "" && !strings.Contains(strings.ToLower(tx.Account), strings.ToLower(conds.Account)) {
+		return false
+	}
+	if conds.Currency != "" {
+		currency := strings.ToUpper(tx.Currency)
+		if currency == "" {
+			currency = "AED"
+		}
+		if currency != conds.Currency {
+			return false
+		}
+	}
+	return true
+}
+
+// dayOfMonth reads the day from a "2006-01-02" date, with or without a time.
+func dayOfMonth(date string) (int, bool) {
+	if len(date) < 10 {
+		return 0, false
+	}
+	t, err := time.Parse("2006-01-02", date[:10])
+	if err != nil {
+		return 0, false
+	}
+	return t.Day(), true
+}
+
+// ruleConditionRow receives ruleConditionColumns from a scan.
+type ruleConditionRow struct {
+	minAmount, maxAmount sql.NullFloat64
+	sign                 string
+	dayFrom, dayTo       sql.NullInt64
+	account, currency    string
+}
+
+func (r *ruleConditionRow) dest() []interface{} {
+	return []interface{}{&r.minAmount, &r.maxAmount, &r.sign, &r.dayFrom, &r.dayTo, &r.account, &r.currency}
+}
+
+func (r *ruleConditionRow) conditions() RuleConditions {
+	conds := RuleConditions{
+		MinAmount:  nullFloatPtr(r.minAmount),
+		MaxAmount:  nullFloatPtr(r.maxAmount),
+		AmountSign: r.sign,
+		Account:    r.account,
+		Currency:   r.currency,
+	}
+	if r.dayFrom.Valid && r.dayTo.Valid {
+		from, to := int(r.dayFrom.Int64), int(r.dayTo.Int64)
+		conds.DayFrom, conds.DayTo = &from, &to
+	}
+	return conds
+}
+
+// ruleConditionArgs returns conditions as arguments for ruleConditionColumns.
+func ruleConditionArgs(conds RuleConditions) []interface{} {
+	return []interface{}{
+		conds.MinAmount, conds.MaxAmount, conds.AmountSign,
+		conds.DayFrom, conds.DayTo, conds.Account, conds.Currency,
+	}
+}
+
+// SetRuleConditions replaces a rule's conditions.
+func (c *DatabaseClient) SetRuleConditions(id int64, conds RuleConditions) error {
+	if err := validateRuleConditions(&conds); err != nil {
+		return err
+	}
+	args := append(ruleConditionArgs(conds), id)
+	result, err := c.db.Exec(
+		"UPDATE merchant_rules SET min_amount=?, max_amount=?, amount_sign=?, day_from=?, day_to=?, account=?, currency=? WHERE id=?",
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update rule conditions: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("rule not found")
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestRuleConditions_Engine(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+
+	// The rent transfer is told apart from smaller transfers to the same
+	// person by amount and day.
+	if _, _, _, err := db.CreateRuleWithConditions("transfer to ahmed", MatchContains, "Rent", 20, RuleConditions{
+		MinAmount: floatPtr(5000), AmountSign: AmountDebit, DayFrom: intPtr(25), DayTo: intPtr(5),
+	}); err != nil {
+		t.Fatalf("CreateRuleWithConditions failed: %v", err)
+	}
+	if _, _, _, err := db.CreateRuleWithConditions("transfer to ahmed", MatchContains, "Shopping & Gifts", 10, RuleConditions{}); err != nil {
+		t.Fatalf("CreateRuleWithConditions failed: %v", err)
+	}
+	if _, _, _, err := db.CreateRuleWithConditions("amazon", MatchContains, "Subscriptions", 10, RuleConditions{
+		Account: "1234", Currency: "usd", MaxAmount: floatPtr(100),
+	}); err != nil {
+		t.Fatalf("CreateRuleWithConditions failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		tx   Transaction
+		want string
+	}{
+		{Transaction{Description: "Transfer to Ahmed", Amount: 9000, Date: "2026-02-01"}, "Rent"},
+		{Transaction{Description: "Transfer to Ahmed", Amount: 9000, Date: "2026-01-27 09:00:00"}, "Rent"},
+		{Transaction{Description: "Transfer to Ahmed", Amount: 90, Date: "2026-02-01"}, "Shopping & Gifts"},
+		{Transaction{Description: "Transfer to Ahmed", Amount: 9000, Date: "2026-02-14"}, "Shopping & Gifts"},
+		{Transaction{Description: "Transfer to Ahmed", Amount: -9000, Date: "2026-02-01"}, "Shopping & Gifts"},
+		{Transaction{Description: "AMAZON PRIME", Amount: 36.7, Account: "Visa ending 1234", Currency: "USD"}, "Subscriptions"},
+		{Transaction{Description: "AMAZON PRIME", Amount: 36.7, Account: "Visa ending 9876", Currency: "USD"}, ""},
+		{Transaction{Description: "AMAZON PRIME", Amount: 36.7, Account: "Visa ending 1234"}, ""},
+		{Transaction{Description: "AMAZON PRIME", Amount: 367, Account: "Visa ending 1234", Currency: "USD"}, ""},
+	} {
+		rule, err := db.MatchTransaction(tc.tx)
+		if err != nil {
+			t.Fatalf("MatchTransaction failed: %v", err)
+		}
+		got := ""
+		if rule != nil {
+			got = rule.Category
+		}
+		if got != tc.want {
+			t.Errorf("%+v: expected %q, got %q", tc.tx, tc.want, got)
+		}
+	}
+
+	rules, _ := db.GetAllRules()
+	if c := rules[0].Conditions; rules[0].Category != "Rent" || c.MinAmount == nil || *c.MinAmount != 5000 || c.DayFrom == nil || *c.DayTo != 5 {
+		t.Errorf("expected the rent rule listed first with its conditions, got %+v", rules[0])
+	}
+	if c := rules[2].Conditions; c.Currency != "USD" || c.Account != "1234" {
+		t.Errorf("expected the currency normalized, got %+v", c)
+	}
+}
+
+func TestRuleConditions_Retroactive(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	for _, tx := range []Transaction{
+		{Description: "Transfer to Ahmed", Amount: 9000, Date: "2026-01-28", BillingCycle: "Jan 2026", Category: "Shopping", Source: "openai"},
+		{Description: "Transfer to Ahmed", Amount: 90, Date: "2026-01-29", BillingCycle: "Jan 2026", Category: "Shopping", Source: "openai"},
+		{Description: "Transfer to Ahmed", Amount: 8500, Date: "2026-01-30", BillingCycle: "Jan 2026", Category: "Shopping", Source: "manual"},
+	} {
+		insertTestTransaction(t, db, tx)
+	}
+
+	rule, matchCount, protectedCount, err := db.CreateRuleWithConditions("ahmed", MatchWord, "Rent", 10, RuleConditions{MinAmount: floatPtr(5000)})
+	if err != nil {
+		t.Fatalf("CreateRuleWithConditions failed: %v", err)
+	}
+	if matchCount != 1 || protectedCount != 1 {
+		t.Errorf("expected 1 match and 1 protected, got %d / %d", matchCount, protectedCount)
+	}
+	if updated, protected, err := db.ApplyRuleSingle(rule.ID); err != nil || updated != 1 || protected != 1 {
+		t.Errorf("expected ApplyRuleSingle to move only the large transfer, got %d / %d (%v)", updated, protected, err)
+	}
+
+	// Dropping the amount condition lets ApplyAllRules pick up the small one.
+	if err := db.SetRuleConditions(rule.ID, RuleConditions{}); err != nil {
+		t.Fatalf("SetRuleConditions failed: %v", err)
+	}
+	if updated, _, err := db.ApplyAllRules(); err != nil || updated != 1 {
+		t.Errorf("expected ApplyAllRules to move the small transfer too, got %d (%v)", updated, err)
+	}
+	if err := db.SetRuleConditions(9999, RuleConditions{}); err == nil || err.Error() != "rule not found" {
+		t.Errorf("expected rule not found, got %v", err)
+	}
+}
+
+func TestRuleConditions_Validation(t *testing.T) {
+	db := setupTestDB(t)
+
+	for name, conds := range map[string]RuleConditions{
+		"bad sign":      {AmountSign: "sideways"},
+		"min over max":  {MinAmount: floatPtr(500), MaxAmount: floatPtr(100)},
+		"negative":      {MinAmount: floatPtr(-1)},
+		"half a window": {DayFrom: intPtr(1)},
+		"day 32":        {DayFrom: intPtr(1), DayTo: intPtr(32)},
+		"currency":      {Currency: "dirham"},
+	} {
+		if _, _, _, err := db.CreateRuleWithConditions("ahmed", "", "Rent", 0, conds); err == nil || !strings.HasPrefix(err.Error(), "invalid conditions") {
+			t.Errorf("%s: expected invalid conditions, got %v", name, err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	body := `{"keyword":"ahmed","category":"Rent","conditions":{"minAmount":500,"maxAmount":100}}`
+	rulesHandler(db)(w, httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unmeetable conditions, got %d", w.Code)
+	}
+}
+
+func TestImportHandler_RulesFileUncategorizedRows(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	db.CreateRuleWithConditions("transfer to ahmed", MatchContains, "Rent", 10, RuleConditions{MinAmount: floatPtr(5000)})
+
+	csv := "Date,Description,Amount (AED),Category\n" +
+		"2026-02-01,Transfer to Ahmed,9000.00,\n" +
+		"2026-02-02,Transfer to Ahmed,90.00,\n" +
+		"2026-02-03,Transfer to Ahmed,75.00,Shopping & Gifts\n"
+	body, contentType := createMultipartCSV(t, csv)
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	importHandler(db).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rows, _ := db.db.Query("SELECT amount, category, source FROM transactions ORDER BY transaction_date")
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var amount float64
+		var category, source string
+		rows.Scan(&amount, &category, &source)
+		got = append(got, category+"/"+source)
+	}
+	if strings.Join(got, ",") != "Rent/rule,Shopping & Gifts/" {
+		t.Errorf("expected the rent row filed by the rule and the unmatched row skipped, got %v", got)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A merchant rule's match type says how its keyword is compared with a
+// transaction description. Every type is case-insensitive and compiles to a
+// regexp, so the ingest-time matcher, retroactive application and the match
+// counter all agree on what a rule catches.
+const (
+	MatchContains = "contains"
+	MatchPrefix   = "prefix"
+	MatchExact    = "exact"
+	MatchRegex    = "regex"
+	MatchWord     = "word-boundary"
+)
+
+var matchTypes = []string{MatchContains, MatchPrefix, MatchExact, MatchRegex, MatchWord}
+
+func (c *DatabaseClient) migrateRuleMatchTypes() error {
+	if err := c.addColumnIfNotExists("merchant_rules", "match_type TEXT NOT NULL DEFAULT 'contains'"); err != nil {
+		return fmt.Errorf("failed to add match_type column: %w", err)
+	}
+	return nil
+}
+
+// normalizeMatchType defaults an empty match type to contains and rejects
+// unknown ones.
+func normalizeMatchType(matchType string) (string, error) {
+	matchType = strings.ToLower(strings.TrimSpace(matchType))
+	if matchType == "" {
+		return MatchContains, nil
+	}
+	for _, t := range matchTypes {
+		if matchType == t {
+			return matchType, nil
+		}
+	}
+	return "", fmt.Errorf("invalid match type %q: must be one of %s", matchType, strings.Join(matchTypes, ", "))
+}
+
+// compileRuleMatcher builds the regexp for a keyword under a match type.
+// Word matches treat any character that isn't a letter or digit as a
+// boundary, so "apple" matches "APPLE.COM/BILL" but not "Applebee's".
+func compileRuleMatcher(keyword, matchType string) (*regexp.Regexp, error) {
+	quoted := regexp.QuoteMeta(keyword)
+	var pattern string
+	switch matchType {
+	case MatchContains, "":
+		pattern = quoted
+	case MatchPrefix:
+		pattern = `^\s*` + quoted
+	case MatchExact:
+		pattern = `^\s*` + quoted + `\s*$`
+	case MatchWord:
+		pattern = `(?:^|[^\pL\pN])` + quoted + `(?:[^\pL\pN]|$)`
+	case MatchRegex:
+		pattern = keyword
+	default:
+		return nil, fmt.Errorf("invalid match type %q", matchType)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %v", err)
+	}
+	return re, nil
+}
+
+// validateRuleMatch normalizes a rule's match type and checks its keyword
+// compiles under it.
+func validateRuleMatch(keyword, matchType string) (string, error) {
+	if strings.TrimSpace(keyword) == "" {
+		return "", fmt.Errorf("invalid keyword: must not be empty")
+	}
+	matchType, err := normalizeMatchType(matchType)
+	if err != nil {
+		return "", err
+	}
+	if _, err := compileRuleMatcher(keyword, matchType); err != nil {
+		return "", err
+	}
+	return matchType, nil
+}
+
+// ruleMatcher is a rule with its compiled keyword.
+type ruleMatcher struct {
+	rule MerchantRule
+	re   *regexp.Regexp
+}
+
+// matchedDescription is the description rules see: the bank's text, before
+// any rule renamed it.
+func matchedDescription(tx Transaction) string {
+	if tx.OriginalDescription != "" {
+		return tx.OriginalDescription
+	}
+	return tx.Description
+}
+
+func (m ruleMatcher) matches(tx Transaction) bool {
+	return m.re.MatchString(matchedDescription(tx)) && m.rule.Conditions.conditionsMet(tx)
+}
+
+// ruleEngine evaluates merchant rules in priority order; the first rule
+// whose pattern and conditions all match wins. It is the one place rules
+// are evaluated, whether at ingest (new and imported transactions) or
+// retroactively.
+type ruleEngine []ruleMatcher
+
+// newRuleEngine compiles rules, keeping their order. A rule whose keyword
+// no longer compiles is skipped rather than failing every match.
+func newRuleEngine(rules []MerchantRule) ruleEngine {
+	engine := make(ruleEngine, 0, len(rules))
+	for _, rule := range rules {
+		re, err := compileRuleMatcher(rule.Keyword, rule.MatchType)
+		if err != nil {
+			continue
+		}
+		engine = append(engine, ruleMatcher{rule, re})
+	}
+	return engine
+}
+
+func (c *DatabaseClient) loadRuleEngine() (ruleEngine, error) {
+	rules, err := c.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	return newRuleEngine(rules), nil
+}
+
+// Match returns the first rule matching a transaction, or nil.
+func (e ruleEngine) Match(tx Transaction) *MerchantRule {
+	for i := range e {
+		if e[i].matches(tx) {
+			return &e[i].rule
+		}
+	}
+	return nil
+}
+
+// ruleCandidate is a transaction as rules see it, with whether its cycle
+// is open.
+type ruleCandidate struct {
+	Transaction
+	open bool
+}
+
+// ruleCandidates loads every transaction for rule evaluation.
+func (c *DatabaseClient) ruleCandidates() ([]ruleCandidate, error) {
+	rows, err := c.db.Query(`
+		SELECT id, description, original_description, amount, transaction_date, category, account, currency, source,
+			note, excluded, approved, ` + openCyclesClause + `
+		FROM transactions`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []ruleCandidate
+	for rows.Next() {
+		var tx ruleCandidate
+		if err := rows.Scan(&tx.ID, &tx.Description, &tx.OriginalDescription, &tx.Amount, &tx.Date, &tx.Category, &tx.Account, &tx.Currency, &tx.Source,
+			&tx.Note, &tx.Excluded, &tx.Approved, &tx.open); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		candidates = append(candidates, tx)
+	}
+	return candidates, rows.Err()
+}
+
+// ruleMatches lists the transactions a rule matches that it may
+// recategorize, and counts the manually categorized ones it leaves alone.
+// With openOnly, transactions in closed cycles are left out of the list.
+func (c *DatabaseClient) ruleMatches(rule MerchantRule, openOnly bool) ([]Transaction, int, error) {
+	re, err := compileRuleMatcher(rule.Keyword, rule.MatchType)
+	if err != nil {
+		return nil, 0, err
+	}
+	m := ruleMatcher{rule, re}
+	candidates, err := c.ruleCandidates()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matched []Transaction
+	var protected int
+	for _, tx := range candidates {
+		if !m.matches(tx.Transaction) {
+			continue
+		}
+		if tx.Source == "manual" {
+			protected++
+		} else if !openOnly || tx.open {
+			matched = append(matched, tx.Transaction)
+		}
+	}
+	return matched, protected, nil
+}
+
+// getRule returns one rule with its tags, conditions and actions.
+func (c *DatabaseClient) getRule(id int64) (*MerchantRule, error) {
+	rules, err := c.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i], nil
+		}
+	}
+	return nil, fmt.Errorf("rule not found")
+}
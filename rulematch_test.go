@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRuleMatchTypes(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+
+	for _, r := range []struct{ keyword, matchType, category string }{
+		{"uber eats", MatchContains, "Dining Out"},
+		{"uber", MatchWord, "Transport"},
+		{"apple.com/bill", MatchPrefix, "Shopping & Gifts"},
+		{"apple store", MatchExact, "Shopping & Gifts"},
+		{`^talabat\s+#\d+`, MatchRegex, "Dining Out"},
+	} {
+		if _, _, _, err := db.CreateRule(r.keyword, r.matchType, r.category, 50); err != nil {
+			t.Fatalf("CreateRule(%q) failed: %v", r.keyword, err)
+		}
+	}
+
+	for desc, want := range map[string]string{
+		"UBER EATS DUBAI":       "Dining Out",
+		"UBER *TRIP HELP.UBER":  "Transport",
+		"Ubereats voucher":      "",
+		"APPLE.COM/BILL CA":     "Shopping & Gifts",
+		"ITUNES APPLE.COM/BILL": "",
+		"  Apple Store ":        "Shopping & Gifts",
+		"Apple Store Dubai":     "",
+		"TALABAT #4471":         "Dining Out",
+		"Order from talabat":    "",
+	} {
+		rule, err := db.FindMatchingRule(desc)
+		if err != nil {
+			t.Fatalf("FindMatchingRule failed: %v", err)
+		}
+		if got := ""; rule != nil {
+			got = rule.Category
+			if got != want {
+				t.Errorf("%q: expected %q, got %q (%s %q)", desc, want, got, rule.MatchType, rule.Keyword)
+			}
+		} else if want != "" {
+			t.Errorf("%q: expected %q, got no match", desc, want)
+		}
+	}
+}
+
+func TestRuleMatchTypes_Retroactive(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	for _, tx := range []Transaction{
+		{Description: "UBER *TRIP", Amount: 30, Date: "2026-01-25", Category: "Shopping", Source: "openai"},
+		{Description: "UBER EATS", Amount: 60, Date: "2026-01-25", Category: "Shopping", Source: "openai"},
+		{Description: "Uber airport", Amount: 90, Date: "2026-01-26", Category: "Shopping", Source: "manual"},
+	} {
+		insertTestTransaction(t, db, tx)
+	}
+
+	rule, matchCount, protectedCount, err := db.CreateRule("uber", MatchPrefix, "Transport", 10)
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if matchCount != 2 || protectedCount != 1 {
+		t.Errorf("expected 2 matches and 1 protected, got %d / %d", matchCount, protectedCount)
+	}
+
+	if err := db.UpdateRule(rule.ID, "uber *trip", MatchExact, "Transport", 10); err != nil {
+		t.Fatalf("UpdateRule failed: %v", err)
+	}
+	updated, protected, err := db.ApplyRuleSingle(rule.ID)
+	if err != nil {
+		t.Fatalf("ApplyRuleSingle failed: %v", err)
+	}
+	if updated != 1 || protected != 0 {
+		t.Errorf("expected only the exact description updated, got %d updated / %d protected", updated, protected)
+	}
+
+	if err := db.UpdateRule(rule.ID, "eats", MatchWord, "Dining Out", 10); err != nil {
+		t.Fatalf("UpdateRule failed: %v", err)
+	}
+	if updated, _, err := db.ApplyAllRules(); err != nil || updated != 1 {
+		t.Errorf("expected ApplyAllRules to move UBER EATS only, got %d (%v)", updated, err)
+	}
+	var category string
+	db.db.QueryRow("SELECT category FROM transactions WHERE description = 'UBER EATS'").Scan(&category)
+	if category != "Dining Out" {
+		t.Errorf("expected UBER EATS in Dining Out, got %s", category)
+	}
+}
+
+func TestRuleMatchTypes_Validation(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, _, _, err := db.CreateRule("uber", "fuzzy", "Transport", 0); err == nil || !strings.HasPrefix(err.Error(), "invalid match type") {
+		t.Errorf("expected an unknown match type to be refused, got %v", err)
+	}
+	if _, _, _, err := db.CreateRule("uber(", MatchRegex, "Transport", 0); err == nil || !strings.HasPrefix(err.Error(), "invalid regex") {
+		t.Errorf("expected a bad regex to be refused, got %v", err)
+	}
+	// The same keyword is fine as a literal.
+	rule, _, _, err := db.CreateRule("uber(", "", "Transport", 0)
+	if err != nil || rule.MatchType != MatchContains {
+		t.Fatalf("expected a literal keyword defaulting to contains, got %+v (%v)", rule, err)
+	}
+	if err := db.UpdateRule(rule.ID, "uber(", MatchRegex, "Transport", 0); err == nil || !strings.HasPrefix(err.Error(), "invalid regex") {
+		t.Errorf("expected UpdateRule to refuse a bad regex, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	rulesHandler(db)(w, httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(`{"keyword":"[","matchType":"regex","category":"Transport"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad regex, got %d", w.Code)
+	}
+}
+
+func TestRuleMatchTypes_UpdateKeepsOmittedMatchType(t *testing.T) {
+	db := setupTestDB(t)
+	rule, _, _, err := db.CreateRule(`^uber\b`, MatchRegex, "Transport", 0)
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	path := fmt.Sprintf("/rules/%d", rule.ID)
+
+	w := httptest.NewRecorder()
+	ruleDetailHandler(db)(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"keyword":"^careem\\b","category":"Transport"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got, _ := db.getRule(rule.ID); got.MatchType != MatchRegex || got.Keyword != `^careem\b` {
+		t.Errorf("expected the regex kept with the new keyword, got %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	ruleDetailHandler(db)(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"keyword":"careem","matchType":"","category":"Transport"}`)))
+	if got, _ := db.getRule(rule.ID); w.Code != http.StatusOK || got.MatchType != MatchContains {
+		t.Errorf("expected an empty matchType to mean contains, got %d %+v", w.Code, got)
+	}
+
+	w = httptest.NewRecorder()
+	ruleDetailHandler(db)(w, httptest.NewRequest(http.MethodPut, "/rules/9999", strings.NewReader(`{"keyword":"x","category":"Transport"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown rule, got %d", w.Code)
+	}
+}
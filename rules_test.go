@@ -7,8 +7,8 @@ import (
 func TestFindMatchingRule(t *testing.T) {
 	db := setupTestDB(t)
 
-	db.CreateRule("keywordA", "Groceries", 10)
-	db.CreateRule("keywordB", "Transport", 5)
+	db.CreateRule("keywordA", MatchContains, "Groceries", 10)
+	db.CreateRule("keywordB", MatchContains, "Transport", 5)
 
 	tests := []struct {
 		name     string
@@ -91,7 +91,7 @@ func TestApplyRuleSingle(t *testing.T) {
 		insertTestTransaction(t, db, tx)
 	}
 
-	rule, _, _, err := db.CreateRule("carrefour", "Groceries", 0)
+	rule, _, _, err := db.CreateRule("carrefour", MatchContains, "Groceries", 0)
 	if err != nil {
 		t.Fatalf("CreateRule failed: %v", err)
 	}
@@ -148,8 +148,8 @@ func TestApplyAllRules(t *testing.T) {
 	db := setupTestDB(t)
 	db.db.Exec("DELETE FROM merchant_rules")
 
-	db.CreateRule("noon", "Shopping", 10)
-	db.CreateRule("carrefour", "Groceries", 5)
+	db.CreateRule("noon", MatchContains, "Shopping", 10)
+	db.CreateRule("carrefour", MatchContains, "Groceries", 5)
 
 	for _, tx := range []Transaction{
 		{
@@ -212,8 +212,8 @@ func TestApplyAllRulesFirstMatchWins(t *testing.T) {
 	db := setupTestDB(t)
 	db.db.Exec("DELETE FROM merchant_rules")
 
-	db.CreateRule("carrefour market", "Groceries", 10)
-	db.CreateRule("carrefour", "Shopping", 5)
+	db.CreateRule("carrefour market", MatchContains, "Groceries", 10)
+	db.CreateRule("carrefour", MatchContains, "Shopping", 5)
 
 	insertTestTransaction(t, db, Transaction{
 		Description: "Carrefour Market Dubai",
@@ -246,9 +246,9 @@ func TestRulePriorityMove(t *testing.T) {
 
 	db.db.Exec("DELETE FROM merchant_rules")
 
-	r1, _, _, _ := db.CreateRule("rule1", "Groceries", 100)
-	r2, _, _, _ := db.CreateRule("rule2", "Dining Out", 50)
-	r3, _, _, _ := db.CreateRule("rule3", "Transport", 0)
+	r1, _, _, _ := db.CreateRule("rule1", MatchContains, "Groceries", 100)
+	r2, _, _, _ := db.CreateRule("rule2", MatchContains, "Dining Out", 50)
+	r3, _, _, _ := db.CreateRule("rule3", MatchContains, "Transport", 0)
 
 	db.MoveRulePriority(r2.ID, "up")
 
@@ -288,7 +288,7 @@ func TestCreateRuleReturnsMatchCounts(t *testing.T) {
 		insertTestTransaction(t, db, tx)
 	}
 
-	_, matchCount, protectedCount, err := db.CreateRule("carrefour", "Groceries", 0)
+	_, matchCount, protectedCount, err := db.CreateRule("carrefour", MatchContains, "Groceries", 0)
 	if err != nil {
 		t.Fatalf("CreateRule failed: %v", err)
 	}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A rule simulation shows what applying rules would do to existing
+// transactions without writing anything. With a draft rule it lists the
+// transactions the draft matches: those it would recategorize, manual rows
+// it would leave alone, and rows a higher-priority rule would claim first.
+// Without one it previews /rules/apply-all for the current rule set,
+// optionally with some rules moved to new priorities.
+// Transactions in closed cycles can't change and are left out.
+
+// Simulation statuses.
+const (
+	SimRecategorized = "recategorized" // the category would change
+	SimUnchanged     = "unchanged"     // the rule fires but the category stays (its actions may still apply)
+	SimProtected     = "protected"     // a manual row; rules never touch it
+	SimShadowed      = "shadowed"      // the draft matches but a higher-priority rule wins
+)
+
+// SimulatedChange is one transaction in a rule simulation.
+type SimulatedChange struct {
+	TransactionID int64   `json:"transactionId"`
+	Description   string  `json:"description"`
+	Amount        float64 `json:"amount"`
+	Date          string  `json:"date"`
+	Before        string  `json:"before"`
+	After         string  `json:"after"`
+	Status        string  `json:"status"`
+	RuleID        int64   `json:"ruleId"` // the rule that would fire; 0 for the draft
+	Keyword       string  `json:"keyword"`
+	// Changes are what the firing rule would write, as its rule log would
+	// record them; empty for protected and shadowed rows.
+	Changes []RuleLogEntry `json:"changes,omitempty"`
+}
+
+// RuleSimulation is the result of SimulateRules.
+type RuleSimulation struct {
+	Draft   bool              `json:"draft"`
+	Changes []SimulatedChange `json:"changes"`
+	Counts  map[string]int    `json:"counts"`
+}
+
+// SimulateRules previews rule application. priorities, keyed by rule ID,
+// override existing rules' priorities. draft, when not nil, is slotted in
+// by priority (after existing rules of the same priority, as a new rule
+// would be) and only its matches are reported.
+func (c *DatabaseClient) SimulateRules(draft *MerchantRule, priorities map[int64]int) (*RuleSimulation, error) {
+	rules, err := c.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	for id, priority := range priorities {
+		found := false
+		for i := range rules {
+			if rules[i].ID == id {
+				rules[i].Priority = priority
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("rule not found")
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+
+	var draftMatcher ruleMatcher
+	if draft != nil {
+		draft.ID = 0
+		draft.MatchType, err = validateRuleMatch(draft.Keyword, draft.MatchType)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateRuleConditions(&draft.Conditions); err != nil {
+			return nil, err
+		}
+		if err := validateRuleActions(draft.Actions); err != nil {
+			return nil, err
+		}
+		draft.Tags = cleanTagNames(draft.Tags)
+		re, _ := compileRuleMatcher(draft.Keyword, draft.MatchType)
+		draftMatcher = ruleMatcher{*draft, re}
+
+		at := len(rules)
+		for i, rule := range rules {
+			if rule.Priority < draft.Priority {
+				at = i
+				break
+			}
+		}
+		rules = append(rules[:at], append([]MerchantRule{*draft}, rules[at:]...)...)
+	}
+	engine := newRuleEngine(rules)
+
+	candidates, err := c.ruleCandidates()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := c.transactionTags("")
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &RuleSimulation{
+		Draft:   draft != nil,
+		Changes: []SimulatedChange{},
+		Counts:  map[string]int{SimRecategorized: 0, SimUnchanged: 0, SimProtected: 0, SimShadowed: 0},
+	}
+	for _, tx := range candidates {
+		if !tx.open {
+			continue
+		}
+		if draft != nil && !draftMatcher.matches(tx.Transaction) {
+			continue
+		}
+		rule := engine.Match(tx.Transaction)
+		if rule == nil {
+			continue
+		}
+
+		change := SimulatedChange{
+			TransactionID: tx.ID,
+			Description:   tx.Description,
+			Amount:        tx.Amount,
+			Date:          tx.Date,
+			Before:        tx.Category,
+			After:         tx.Category,
+			RuleID:        rule.ID,
+			Keyword:       rule.Keyword,
+		}
+		switch {
+		case tx.Source == "manual":
+			change.Status = SimProtected
+		case draft != nil && rule.ID != 0:
+			change.Status = SimShadowed
+			change.After = rule.Category
+		default:
+			after := tx.Transaction
+			after.Tags = tags[tx.ID]
+			change.Changes = rule.apply(&after)
+			change.After = after.Category
+			change.Status = SimUnchanged
+			if change.After != change.Before {
+				change.Status = SimRecategorized
+			}
+		}
+		sim.Changes = append(sim.Changes, change)
+		sim.Counts[change.Status]++
+	}
+	return sim, nil
+}
+
+// simulateRulesHandler serves POST /rules/simulate. The body is
+// {"rule": {keyword, matchType, category, priority, conditions, actions,
+// tags}} for a draft, or empty for the current rule set; either may carry
+// "priorities": {"<rule id>": priority}.
+func simulateRulesHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		log.Printf("[API] POST /rules/simulate - Simulate rules from %s", r.RemoteAddr)
+
+		var req struct {
+			Rule       *MerchantRule `json:"rule"`
+			Priorities map[int64]int `json:"priorities"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			log.Printf("[API] Invalid request body: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		sim, err := db.SimulateRules(req.Rule, req.Priorities)
+		if err != nil {
+			log.Printf("[API] Failed to simulate rules: %v", err)
+			if err.Error() == "rule not found" {
+				http.Error(w, "Rule not found", http.StatusNotFound)
+			} else if strings.HasPrefix(err.Error(), "invalid ") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to simulate rules", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"simulation": sim,
+		})
+	}
+}
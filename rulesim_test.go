@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func simulatedChange(t *testing.T, sim *RuleSimulation, description string) SimulatedChange {
+	t.Helper()
+	for _, change := range sim.Changes {
+		if change.Description == description {
+			return change
+		}
+	}
+	t.Fatalf("no simulated change for %q in %+v", description, sim.Changes)
+	return SimulatedChange{}
+}
+
+func TestSimulateRules_Draft(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	insertTestTransaction(t, db, Transaction{Description: "UBER EATS 4471", Amount: 60, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"})
+	insertTestTransaction(t, db, Transaction{Description: "UBER EATS 5520", Amount: 45, Date: "2026-01-26", BillingCycle: "Jan 2026", Category: "Groceries", Timestamp: "2026-01-26T10:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "UBER TRIP", Amount: 30, Date: "2026-01-27", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-27T10:00:00Z", Source: "openai"})
+	uber, _, _, err := db.CreateRule("uber", MatchContains, "Transport", 10)
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+
+	// Below the broad rule, the draft is shadowed.
+	draft := MerchantRule{Keyword: "uber eats", Category: "Dining Out", Priority: 5}
+	sim, err := db.SimulateRules(&draft, nil)
+	if err != nil {
+		t.Fatalf("SimulateRules failed: %v", err)
+	}
+	if len(sim.Changes) != 2 || !sim.Draft {
+		t.Fatalf("expected only the draft's two matches, got %+v", sim.Changes)
+	}
+	if c := simulatedChange(t, sim, "UBER EATS 4471"); c.Status != SimShadowed || c.RuleID != uber.ID || c.After != "Transport" {
+		t.Errorf("expected the uber rule to shadow the draft, got %+v", c)
+	}
+	if c := simulatedChange(t, sim, "UBER EATS 5520"); c.Status != SimProtected || c.After != "Groceries" {
+		t.Errorf("expected the manual row protected, got %+v", c)
+	}
+
+	// Above it, the draft wins and its actions are previewed.
+	draft = MerchantRule{Keyword: "uber eats", Category: "Dining Out", Priority: 20, Actions: []RuleAction{{Type: "rename", Value: "Uber Eats"}}}
+	sim, err = db.SimulateRules(&draft, nil)
+	if err != nil {
+		t.Fatalf("SimulateRules failed: %v", err)
+	}
+	c := simulatedChange(t, sim, "UBER EATS 4471")
+	if c.Status != SimRecategorized || c.RuleID != 0 || c.Before != "Shopping & Gifts" || c.After != "Dining Out" || len(c.Changes) != 2 || c.Changes[1].Action != "rename" {
+		t.Errorf("expected the draft to recategorize and rename, got %+v", c)
+	}
+	if sim.Counts[SimRecategorized] != 1 || sim.Counts[SimProtected] != 1 || sim.Counts[SimShadowed] != 0 {
+		t.Errorf("unexpected counts %v", sim.Counts)
+	}
+
+	// Nothing was written.
+	stats, _ := db.GetStats("Jan 2026")
+	for _, tx := range stats.AllTransactions {
+		if tx.Description == "Uber Eats" || tx.Category == "Dining Out" || tx.Category == "Transport" {
+			t.Errorf("expected the simulation not to write, got %+v", tx)
+		}
+	}
+	if rules, _ := db.GetAllRules(); len(rules) != 1 {
+		t.Errorf("expected the draft not saved, got %+v", rules)
+	}
+
+	if _, err := db.SimulateRules(&MerchantRule{Keyword: "(", MatchType: MatchRegex, Category: "Dining Out"}, nil); err == nil || !strings.HasPrefix(err.Error(), "invalid regex") {
+		t.Errorf("expected an invalid draft refused, got %v", err)
+	}
+}
+
+func TestSimulateRules_KeepsExistingTags(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	insertTestTransaction(t, db, Transaction{Description: "NARITA EXPRESS", Amount: 300, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Transport", Timestamp: "2026-01-25T10:00:00Z", Source: "openai", Tags: []string{"japan trip"}})
+
+	draft := MerchantRule{Keyword: "narita", Category: "Transport", Priority: 10, Tags: []string{"japan trip", "rail"}}
+	sim, err := db.SimulateRules(&draft, nil)
+	if err != nil {
+		t.Fatalf("SimulateRules failed: %v", err)
+	}
+	c := simulatedChange(t, sim, "NARITA EXPRESS")
+	if len(c.Changes) != 2 || c.Changes[1].Action != ActionAddTags || c.Changes[1].Detail != "rail" {
+		t.Errorf("expected only the missing tag previewed, got %+v", c.Changes)
+	}
+}
+
+func TestSimulateRules_Reprioritize(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	insertTestTransaction(t, db, Transaction{Description: "UBER EATS 4471", Amount: 60, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"})
+	insertTestTransaction(t, db, Transaction{Description: "UBER TRIP", Amount: 30, Date: "2026-01-27", BillingCycle: "Jan 2026", Category: "Transport", Timestamp: "2026-01-27T10:00:00Z", Source: "openai"})
+	insertTestTransaction(t, db, Transaction{Description: "CAREEM", Amount: 25, Date: "2026-01-27", BillingCycle: "Jan 2026", Category: "Transport", Timestamp: "2026-01-27T11:00:00Z", Source: "openai"})
+	db.CreateRule("uber", MatchContains, "Transport", 10)
+	eats, _, _, _ := db.CreateRule("uber eats", MatchContains, "Dining Out", 5)
+
+	sim, err := db.SimulateRules(nil, nil)
+	if err != nil {
+		t.Fatalf("SimulateRules failed: %v", err)
+	}
+	if len(sim.Changes) != 2 || sim.Draft {
+		t.Fatalf("expected the two uber transactions, got %+v", sim.Changes)
+	}
+	if c := simulatedChange(t, sim, "UBER EATS 4471"); c.Status != SimRecategorized || c.After != "Transport" {
+		t.Errorf("expected the broad rule to win as things stand, got %+v", c)
+	}
+	if c := simulatedChange(t, sim, "UBER TRIP"); c.Status != SimUnchanged {
+		t.Errorf("expected the trip unchanged, got %+v", c)
+	}
+
+	sim, err = db.SimulateRules(nil, map[int64]int{eats.ID: 20})
+	if err != nil {
+		t.Fatalf("SimulateRules failed: %v", err)
+	}
+	if c := simulatedChange(t, sim, "UBER EATS 4471"); c.Status != SimRecategorized || c.RuleID != eats.ID || c.After != "Dining Out" {
+		t.Errorf("expected the moved rule to win, got %+v", c)
+	}
+	if rule, _ := db.getRule(eats.ID); rule.Priority != 5 {
+		t.Errorf("expected the priority left alone, got %d", rule.Priority)
+	}
+
+	if _, err := db.SimulateRules(nil, map[int64]int{9999: 1}); err == nil || err.Error() != "rule not found" {
+		t.Errorf("expected rule not found, got %v", err)
+	}
+}
+
+func TestSimulateRulesHandler(t *testing.T) {
+	db := setupTestDB(t)
+	insertTestTransaction(t, db, Transaction{Description: "UBER EATS 4471", Amount: 60, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"})
+
+	for body, want := range map[string]int{
+		"": http.StatusOK,
+		`{"rule": {"keyword": "uber eats", "category": "Dining Out", "priority": 100}}`: http.StatusOK,
+		`{"rule": {"keyword": "uber", "matchType": "fuzzy", "category": "Transport"}}`:  http.StatusBadRequest,
+		`{"priorities": {"9999": 1}}`: http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		ruleDetailHandler(db)(w, httptest.NewRequest(http.MethodPost, "/rules/simulate", strings.NewReader(body)))
+		if w.Code != want {
+			t.Errorf("%q: expected %d, got %d: %s", body, want, w.Code, w.Body.String())
+			continue
+		}
+		if want == http.StatusOK {
+			var resp struct {
+				Simulation RuleSimulation `json:"simulation"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if body != "" && (len(resp.Simulation.Changes) != 1 || resp.Simulation.Changes[0].After != "Dining Out") {
+				t.Errorf("expected the draft to claim the transaction, got %+v", resp.Simulation)
+			}
+		}
+	}
+}
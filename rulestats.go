@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Each merchant rule counts its hits: every time it fires on a transaction,
+// at ingest or when applied retroactively, its hit_count goes up and
+// last_matched_at is set. The rule report uses them to find dead rules, and
+// separately finds rules that can never fire because a higher-priority rule
+// catches everything they would.
+
+func (c *DatabaseClient) migrateRuleStats() error {
+	columns := []string{
+		"hit_count INTEGER NOT NULL DEFAULT 0",
+		"last_matched_at TEXT NOT NULL DEFAULT ''",
+	}
+	for _, def := range columns {
+		if err := c.addColumnIfNotExists("merchant_rules", def); err != nil {
+			return fmt.Errorf("failed to add %s column to merchant_rules: %w", def, err)
+		}
+	}
+
+	// Backfill from the rule log, which predates the counters. A rule still
+	// at zero has not fired since they were added, so this is safe to rerun.
+	if _, err := c.db.Exec(`
+		UPDATE merchant_rules SET
+			hit_count = (SELECT COUNT(*) FROM rule_log l WHERE l.rule_id = merchant_rules.id AND l.action = 'category'),
+			last_matched_at = COALESCE((SELECT MAX(applied_at) FROM rule_log l WHERE l.rule_id = merchant_rules.id), '')
+		WHERE hit_count = 0`); err != nil {
+		return fmt.Errorf("failed to backfill rule stats: %w", err)
+	}
+	return nil
+}
+
+// recordRuleHit counts a rule firing.
+func recordRuleHit(ex execer, ruleID int64, at string) error {
+	if _, err := ex.Exec(
+		"UPDATE merchant_rules SET hit_count = hit_count + 1, last_matched_at = ? WHERE id = ?", at, ruleID,
+	); err != nil {
+		return fmt.Errorf("failed to record rule hit: %w", err)
+	}
+	return nil
+}
+
+// ShadowedRule is a rule that can never fire, with the higher-priority rule
+// that catches everything it would.
+type ShadowedRule struct {
+	MerchantRule
+	ShadowedByID      int64  `json:"shadowedById"`
+	ShadowedByKeyword string `json:"shadowedByKeyword"`
+}
+
+// RuleReport lists rules worth pruning.
+type RuleReport struct {
+	Cycles   int            `json:"cycles"`
+	Since    string         `json:"since"`    // the first day of the oldest cycle looked at
+	Dead     []MerchantRule `json:"dead"`     // no hits since then
+	Shadowed []ShadowedRule `json:"shadowed"` // fully covered by a higher-priority rule
+}
+
+// GetRuleReport finds rules with no hits in the last `cycles` billing cycles
+// (counting the current one) and rules shadowed by a higher-priority rule.
+// Rules created inside the window are too new to call dead.
+func (c *DatabaseClient) GetRuleReport(cycles int, now time.Time) (*RuleReport, error) {
+	if cycles < 1 {
+		return nil, fmt.Errorf("invalid cycles: must be at least 1")
+	}
+	oldest := shiftCycle(calculateBillingCycle(now.Format("2006-01-02")), -(cycles - 1))
+	since, _, err := cycleBounds(oldest)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := c.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RuleReport{
+		Cycles:   cycles,
+		Since:    since.Format("2006-01-02"),
+		Dead:     []MerchantRule{},
+		Shadowed: []ShadowedRule{},
+	}
+	for i, rule := range rules {
+		if timestampBefore(rule.CreatedAt, since) && (rule.LastMatchedAt == "" || timestampBefore(rule.LastMatchedAt, since)) {
+			report.Dead = append(report.Dead, rule)
+		}
+		for _, higher := range rules[:i] {
+			if shadows(higher, rule) {
+				report.Shadowed = append(report.Shadowed, ShadowedRule{rule, higher.ID, higher.Keyword})
+				break
+			}
+		}
+	}
+	return report, nil
+}
+
+// timestampBefore reports whether an RFC 3339 timestamp is before t. One
+// that doesn't parse counts as before, so it never hides a rule.
+func timestampBefore(ts string, t time.Time) bool {
+	parsed, err := time.Parse(time.RFC3339, ts)
+	return err != nil || parsed.Before(t)
+}
+
+// shadows reports whether rule a matches every transaction rule b does,
+// judged from the rules alone. It is conservative: a regex only shadows
+// the identical regex, so some shadowed rules go unreported, but none is
+// reported wrongly.
+func shadows(a, b MerchantRule) bool {
+	return patternCovers(a, b) && conditionsCover(a.Conditions, b.Conditions)
+}
+
+// patternCovers reports whether every description b's keyword matches is
+// also matched by a's.
+func patternCovers(a, b MerchantRule) bool {
+	aType, bType := a.MatchType, b.MatchType
+	if aType == "" {
+		aType = MatchContains
+	}
+	if bType == "" {
+		bType = MatchContains
+	}
+	if aType == MatchRegex || bType == MatchRegex {
+		return aType == bType && a.Keyword == b.Keyword
+	}
+
+	aKey, bKey := strings.ToLower(a.Keyword), strings.ToLower(b.Keyword)
+	switch aType {
+	case MatchContains:
+		// Every non-regex match contains the keyword.
+		return strings.Contains(bKey, aKey)
+	case MatchPrefix:
+		return (bType == MatchPrefix || bType == MatchExact) && strings.HasPrefix(bKey, aKey)
+	case MatchExact:
+		return bType == MatchExact && aKey == bKey
+	case MatchWord:
+		return (bType == MatchWord || bType == MatchExact) && aKey == bKey
+	}
+	return false
+}
+
+// conditionsCover reports whether every transaction meeting b also meets a:
+// each condition a sets, b sets at least as tightly.
+func conditionsCover(a, b RuleConditions) bool {
+	if a.MinAmount != nil && (b.MinAmount == nil || *b.MinAmount < *a.MinAmount) {
+		return false
+	}
+	if a.MaxAmount != nil && (b.MaxAmount == nil || *b.MaxAmount > *a.MaxAmount) {
+		return false
+	}
+	if a.AmountSign != "" && a.AmountSign != b.AmountSign {
+		return false
+	}
+	if a.DayFrom != nil && (b.DayFrom == nil || *b.DayFrom != *a.DayFrom || *b.DayTo != *a.DayTo) {
+		return false
+	}
+	if a.Account != "" && !strings.Contains(strings.ToLower(b.Account), strings.ToLower(a.Account)) {
+		return false
+	}
+	if a.Currency != "" && a.Currency != b.Currency {
+		return false
+	}
+	return true
+}
+
+// ruleReportHandler serves GET /rules/report?cycles=N (default 3).
+func ruleReportHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		log.Printf("[API] GET /rules/report - Rule report from %s", r.RemoteAddr)
+
+		cycles := 3
+		if s := r.URL.Query().Get("cycles"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				http.Error(w, "Invalid cycles", http.StatusBadRequest)
+				return
+			}
+			cycles = n
+		}
+
+		report, err := db.GetRuleReport(cycles, time.Now())
+		if err != nil {
+			log.Printf("[API] Failed to build rule report: %v", err)
+			if strings.HasPrefix(err.Error(), "invalid ") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to build rule report", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"report":  report,
+		})
+	}
+}
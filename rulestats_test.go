@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRuleStats_CountedAtIngestAndRetroactively(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	rule, _, _, err := db.CreateRule("careem", MatchContains, "Transport", 10)
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	insertTestTransaction(t, db, Transaction{Description: "CAREEM RIDE", Amount: 25, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"})
+
+	if _, _, err := db.ApplyRuleSingle(rule.ID); err != nil {
+		t.Fatalf("ApplyRuleSingle failed: %v", err)
+	}
+	if _, _, err := db.ApplyAllRules(); err != nil {
+		t.Fatalf("ApplyAllRules failed: %v", err)
+	}
+
+	body, contentType := createMultipartCSV(t, "Date,Description,Amount (AED),Category\n2026-01-26,Careem Hala,18.00,\n")
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", contentType)
+	importHandler(db).ServeHTTP(httptest.NewRecorder(), req)
+
+	got, err := db.getRule(rule.ID)
+	if err != nil {
+		t.Fatalf("getRule failed: %v", err)
+	}
+	// The apply-all found nothing left to change, so it isn't a hit.
+	if got.HitCount != 2 || got.LastMatchedAt == "" {
+		t.Errorf("expected 2 hits with a last match, got %d / %q", got.HitCount, got.LastMatchedAt)
+	}
+}
+
+func TestRuleReport(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) // in the Sep 2026 cycle
+
+	uber, _, _, _ := db.CreateRule("uber", MatchContains, "Transport", 50)
+	eats, _, _, _ := db.CreateRule("Uber Eats", MatchWord, "Dining Out", 40)
+	db.CreateRuleWithConditions("uber", MatchContains, "Transport", 30, RuleConditions{MinAmount: floatPtr(100)})
+	db.CreateRuleWithConditions("amzn", MatchPrefix, "Shopping & Gifts", 20, RuleConditions{MinAmount: floatPtr(500)})
+	amazon, _, _, _ := db.CreateRule("amzn mktp", MatchPrefix, "Shopping & Gifts", 10)
+	re, _, _, _ := db.CreateRule(`uber\s+\d+`, MatchRegex, "Transport", 5)
+
+	// Backdate every rule; only the regex has fired recently, and
+	// Uber Eats fired before the window.
+	db.db.Exec("UPDATE merchant_rules SET created_at = '2025-01-01T00:00:00Z'")
+	db.db.Exec("UPDATE merchant_rules SET hit_count = 4, last_matched_at = '2026-08-01T09:00:00Z' WHERE id = ?", re.ID)
+	db.db.Exec("UPDATE merchant_rules SET hit_count = 9, last_matched_at = '2026-06-30T09:00:00Z' WHERE id = ?", eats.ID)
+	fresh, _, _, _ := db.CreateRule("talabat", MatchContains, "Dining Out", 1)
+
+	report, err := db.GetRuleReport(3, now)
+	if err != nil {
+		t.Fatalf("GetRuleReport failed: %v", err)
+	}
+	if report.Since != "2026-07-23" {
+		t.Errorf("expected the window to start with the Jul 2026 cycle, got %s", report.Since)
+	}
+	dead := map[int64]bool{}
+	for _, rule := range report.Dead {
+		dead[rule.ID] = true
+	}
+	if !dead[uber.ID] || !dead[eats.ID] || !dead[amazon.ID] || dead[re.ID] || dead[fresh.ID] {
+		t.Errorf("unexpected dead rules %+v", report.Dead)
+	}
+
+	shadowedBy := map[string]int64{}
+	for _, s := range report.Shadowed {
+		shadowedBy[s.Keyword+"/"+s.MatchType] = s.ShadowedByID
+	}
+	// The conditioned uber rule is covered by the bare one; amzn mktp is
+	// not, since the higher amzn rule only catches large amounts; the regex
+	// is never judged covered.
+	want := map[string]int64{"Uber Eats/word-boundary": uber.ID, "uber/contains": uber.ID}
+	if len(shadowedBy) != len(want) || shadowedBy["Uber Eats/word-boundary"] != uber.ID || shadowedBy["uber/contains"] != uber.ID {
+		t.Errorf("expected %v shadowed, got %v", want, shadowedBy)
+	}
+
+	if _, err := db.GetRuleReport(0, now); err == nil {
+		t.Error("expected cycles < 1 refused")
+	}
+}
+
+func TestRuleReportHandler(t *testing.T) {
+	db := setupTestDB(t)
+	for query, want := range map[string]int{
+		"":           http.StatusOK,
+		"?cycles=6":  http.StatusOK,
+		"?cycles=0":  http.StatusBadRequest,
+		"?cycles=ab": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		ruleDetailHandler(db)(w, httptest.NewRequest(http.MethodGet, "/rules/report"+query, nil))
+		if w.Code != want {
+			t.Errorf("%q: expected %d, got %d: %s", query, want, w.Code, w.Body.String())
+		}
+	}
+}
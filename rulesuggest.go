@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Rule suggestions are mined from category corrections. Editing a
+// transaction into a different category records the category it had before
+// (corrected_from); a word that turns up in several corrected transactions,
+// all moved to the same category, is proposed as a word-boundary rule for
+// that category. Words an existing rule already files there are left out.
+
+// minSuggestionSupport is how many corrected transactions a word needs
+// before it is suggested.
+const minSuggestionSupport = 2
+
+// suggestionStopWords are words bank descriptions share across merchants.
+var suggestionStopWords = map[string]bool{
+	"pos": true, "purchase": true, "card": true, "debit": true, "credit": true, "payment": true,
+	"the": true, "and": true, "llc": true, "ltd": true, "fze": true, "com": true, "www": true,
+	"dubai": true, "abu": true, "dhabi": true, "sharjah": true, "uae": true, "are": true, "aed": true,
+}
+
+// SuggestionSupport is a corrected transaction behind a suggested rule.
+type SuggestionSupport struct {
+	TransactionID int64   `json:"transactionId"`
+	Description   string  `json:"description"`
+	Amount        float64 `json:"amount"`
+	Date          string  `json:"date"`
+	CorrectedFrom string  `json:"correctedFrom"`
+}
+
+// RuleSuggestion is a rule the corrections point to.
+type RuleSuggestion struct {
+	Keyword   string `json:"keyword"`
+	MatchType string `json:"matchType"`
+	Category  string `json:"category"`
+	// Priority is what the rule is created at: above every rule that files
+	// its transactions today, so accepting it takes effect.
+	Priority int `json:"priority"`
+	// Confidence (0-100) grows with the number of corrections and shrinks
+	// when other transactions with the word sit in other categories.
+	Confidence   int                 `json:"confidence"`
+	Transactions []SuggestionSupport `json:"transactions"`
+}
+
+func (c *DatabaseClient) migrateRuleSuggestions() error {
+	if err := c.addColumnIfNotExists("transactions", "corrected_from TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("failed to add corrected_from column: %w", err)
+	}
+	// Corrections made before the column existed can be recovered where a
+	// rule had filed the transaction: its last logged category is what the
+	// edit replaced.
+	lastRuleCategory := `(SELECT l.detail FROM rule_log l
+		WHERE l.transaction_id = transactions.id AND l.action = 'category' ORDER BY l.id DESC LIMIT 1)`
+	if _, err := c.db.Exec(`
+		UPDATE transactions SET corrected_from = ` + lastRuleCategory + `
+		WHERE source = 'manual' AND corrected_from = '' AND ` + lastRuleCategory + ` <> category`); err != nil {
+		return fmt.Errorf("failed to backfill corrected_from: %w", err)
+	}
+	return nil
+}
+
+// suggestionTokens splits a description into lower-case words, dropping
+// short words, numbers and stop words. Each word is returned once.
+func suggestionTokens(description string) []string {
+	seen := map[string]bool{}
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < 3 || suggestionStopWords[word] || seen[word] {
+			continue
+		}
+		if strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// GetRuleSuggestions proposes rules from category corrections, most
+// confident first.
+func (c *DatabaseClient) GetRuleSuggestions() ([]RuleSuggestion, error) {
+	rows, err := c.db.Query(`
+		SELECT id, description, original_description, amount, transaction_date, category, corrected_from
+		FROM transactions
+		WHERE source = 'manual' AND corrected_from <> ''
+		ORDER BY transaction_date ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corrections: %w", err)
+	}
+	type correction struct {
+		tx   Transaction
+		from string
+	}
+	var corrections []correction
+	for rows.Next() {
+		var cr correction
+		if err := rows.Scan(&cr.tx.ID, &cr.tx.Description, &cr.tx.OriginalDescription, &cr.tx.Amount, &cr.tx.Date, &cr.tx.Category, &cr.from); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan correction: %w", err)
+		}
+		corrections = append(corrections, cr)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Group the corrections by word, noting whether they agree on a category.
+	type candidate struct {
+		category string
+		agreed   bool
+		support  []correction
+	}
+	byToken := map[string]*candidate{}
+	var order []string
+	for _, cr := range corrections {
+		for _, token := range suggestionTokens(matchedDescription(cr.tx)) {
+			cand, ok := byToken[token]
+			if !ok {
+				cand = &candidate{category: cr.tx.Category, agreed: true}
+				byToken[token] = cand
+				order = append(order, token)
+			}
+			if cand.category != cr.tx.Category {
+				cand.agreed = false
+			}
+			cand.support = append(cand.support, cr)
+		}
+	}
+
+	// Every transaction's words and category, for how consistently a word
+	// goes with the suggested category.
+	all, err := c.ruleCandidates()
+	if err != nil {
+		return nil, err
+	}
+	withToken := map[string]int{}
+	inCategory := map[string]int{}
+	for _, tx := range all {
+		for _, token := range suggestionTokens(matchedDescription(tx.Transaction)) {
+			cand, ok := byToken[token]
+			if !ok {
+				continue
+			}
+			withToken[token]++
+			if tx.Category == cand.category {
+				inCategory[token]++
+			}
+		}
+	}
+
+	engine, err := c.loadRuleEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []RuleSuggestion{}
+	bySupport := map[string]int{} // support set + category → index into suggestions
+	for _, token := range order {
+		cand := byToken[token]
+		if !cand.agreed || len(cand.support) < minSuggestionSupport {
+			continue
+		}
+		covered := true
+		priority := 0
+		for _, cr := range cand.support {
+			rule := engine.Match(cr.tx)
+			if rule == nil || rule.Category != cand.category {
+				covered = false
+			}
+			if rule != nil && rule.Priority >= priority {
+				priority = rule.Priority + 1
+			}
+		}
+		if covered {
+			continue
+		}
+
+		n := float64(len(cand.support))
+		consistency := float64(inCategory[token]) / float64(withToken[token])
+		s := RuleSuggestion{
+			Keyword:    token,
+			MatchType:  MatchWord,
+			Category:   cand.category,
+			Priority:   priority,
+			Confidence: int(100*consistency*n/(n+1) + 0.5),
+		}
+		ids := make([]string, 0, len(cand.support))
+		for _, cr := range cand.support {
+			s.Transactions = append(s.Transactions, SuggestionSupport{cr.tx.ID, cr.tx.Description, cr.tx.Amount, cr.tx.Date, cr.from})
+			ids = append(ids, fmt.Sprint(cr.tx.ID))
+		}
+
+		// Words that always appear together ("careem", "hala") are one
+		// merchant; keep the more telling one, or the longer.
+		key := cand.category + "|" + strings.Join(ids, ",")
+		if i, ok := bySupport[key]; ok {
+			prev := suggestions[i]
+			if s.Confidence > prev.Confidence || s.Confidence == prev.Confidence && len(s.Keyword) > len(prev.Keyword) {
+				suggestions[i] = s
+			}
+			continue
+		}
+		bySupport[key] = len(suggestions)
+		suggestions = append(suggestions, s)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		return len(suggestions[i].Transactions) > len(suggestions[j].Transactions)
+	})
+	return suggestions, nil
+}
+
+// AcceptRuleSuggestion creates the suggested rule for a keyword and
+// category, which must still be suggested, at the suggested priority.
+func (c *DatabaseClient) AcceptRuleSuggestion(keyword, category string) (*MerchantRule, int, int, error) {
+	suggestions, err := c.GetRuleSuggestions()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	for _, s := range suggestions {
+		if s.Keyword == strings.ToLower(strings.TrimSpace(keyword)) && s.Category == category {
+			return c.CreateRule(s.Keyword, s.MatchType, s.Category, s.Priority)
+		}
+	}
+	return nil, 0, 0, fmt.Errorf("suggestion not found")
+}
+
+// ruleSuggestionsHandler serves GET /rules/suggestions and POST
+// /rules/suggestions to accept one ({"keyword", "category"}).
+func ruleSuggestionsHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			log.Printf("[API] GET /rules/suggestions - Rule suggestions from %s", r.RemoteAddr)
+			suggestions, err := db.GetRuleSuggestions()
+			if err != nil {
+				log.Printf("[API] Failed to get rule suggestions: %v", err)
+				http.Error(w, "Failed to get rule suggestions", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":     true,
+				"suggestions": suggestions,
+			})
+
+		case http.MethodPost:
+			log.Printf("[API] POST /rules/suggestions - Accept rule suggestion from %s", r.RemoteAddr)
+			var req struct {
+				Keyword  string `json:"keyword"`
+				Category string `json:"category"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				log.Printf("[API] Invalid request body: %v", err)
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			rule, matchCount, protectedCount, err := db.AcceptRuleSuggestion(req.Keyword, req.Category)
+			if err != nil {
+				log.Printf("[API] Failed to accept rule suggestion: %v", err)
+				if err.Error() == "suggestion not found" {
+					http.Error(w, "Suggestion not found", http.StatusNotFound)
+				} else {
+					http.Error(w, "Failed to create rule", http.StatusInternalServerError)
+				}
+				return
+			}
+			rule.Actions = []RuleAction{}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":         true,
+				"rule":            rule,
+				"match_count":     matchCount,
+				"protected_count": protectedCount,
+			})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRuleSuggestions(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	correct := func(tx Transaction, category string) {
+		t.Helper()
+		id, err := db.SaveTransaction(tx)
+		if err != nil {
+			t.Fatalf("SaveTransaction failed: %v", err)
+		}
+		tx.Category = category
+		if err := db.UpdateTransaction(id, tx); err != nil {
+			t.Fatalf("UpdateTransaction failed: %v", err)
+		}
+	}
+	correct(Transaction{Description: "POS 1123 CAREEM HALA DUBAI", Amount: 25, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"}, "Transport")
+	correct(Transaction{Description: "CAREEM HALA RIDE 88", Amount: 30, Date: "2026-01-26", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-26T10:00:00Z", Source: "openai"}, "Transport")
+	correct(Transaction{Description: "CAREEM FOOD", Amount: 60, Date: "2026-01-27", BillingCycle: "Jan 2026", Category: "Groceries", Timestamp: "2026-01-27T10:00:00Z", Source: "openai"}, "Dining Out")
+	// Uncorrected, and in another category: lowers the confidence in "hala".
+	insertTestTransaction(t, db, Transaction{Description: "HALA TAXI", Amount: 20, Date: "2026-01-28", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-28T10:00:00Z", Source: "openai"})
+	// Entered by hand, or edited without moving category: not corrections.
+	insertTestTransaction(t, db, Transaction{Description: "NOON ORDER", Amount: 90, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T11:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "NOON ORDER 2", Amount: 80, Date: "2026-01-26", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-26T11:00:00Z", Source: "manual"})
+	correct(Transaction{Description: "TALABAT 1", Amount: 40, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Dining Out", Timestamp: "2026-01-25T12:00:00Z", Source: "openai"}, "Dining Out")
+	correct(Transaction{Description: "TALABAT 2", Amount: 45, Date: "2026-01-26", BillingCycle: "Jan 2026", Category: "Dining Out", Timestamp: "2026-01-26T12:00:00Z", Source: "openai"}, "Dining Out")
+
+	suggestions, err := db.GetRuleSuggestions()
+	if err != nil {
+		t.Fatalf("GetRuleSuggestions failed: %v", err)
+	}
+	// "careem" went to two categories, and "ride" was corrected only once.
+	if len(suggestions) != 1 {
+		t.Fatalf("expected one suggestion, got %+v", suggestions)
+	}
+	s := suggestions[0]
+	if s.Keyword != "hala" || s.MatchType != MatchWord || s.Category != "Transport" || len(s.Transactions) != 2 || s.Transactions[0].CorrectedFrom != "Shopping & Gifts" {
+		t.Errorf("unexpected suggestion %+v", s)
+	}
+	if s.Confidence != 44 {
+		t.Errorf("expected 2 of 3 consistent over 2 corrections to score 44, got %d", s.Confidence)
+	}
+
+	rule, matched, _, err := db.AcceptRuleSuggestion("HALA", "Transport")
+	if err != nil {
+		t.Fatalf("AcceptRuleSuggestion failed: %v", err)
+	}
+	if rule.Keyword != "hala" || rule.MatchType != MatchWord || matched != 1 {
+		t.Errorf("expected a word rule matching the uncorrected taxi, got %+v (%d)", rule, matched)
+	}
+	if suggestions, _ := db.GetRuleSuggestions(); len(suggestions) != 0 {
+		t.Errorf("expected the accepted rule to cover the suggestion, got %+v", suggestions)
+	}
+	if _, _, _, err := db.AcceptRuleSuggestion("noon", "Shopping & Gifts"); err == nil || err.Error() != "suggestion not found" {
+		t.Errorf("expected suggestion not found, got %v", err)
+	}
+}
+
+func TestRuleSuggestions_OutranksMatchingRules(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	db.CreateRule("careem", MatchContains, "Shopping & Gifts", 5)
+	db.CreateRule("unrelated", MatchContains, "Groceries", 9)
+	for _, tx := range []Transaction{
+		{Description: "CAREEM HALA 1", Amount: 25, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"},
+		{Description: "CAREEM HALA 2", Amount: 30, Date: "2026-01-26", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-26T10:00:00Z", Source: "openai"},
+	} {
+		id, err := db.SaveTransaction(tx)
+		if err != nil {
+			t.Fatalf("SaveTransaction failed: %v", err)
+		}
+		tx.Category = "Transport"
+		if err := db.UpdateTransaction(id, tx); err != nil {
+			t.Fatalf("UpdateTransaction failed: %v", err)
+		}
+	}
+
+	suggestions, err := db.GetRuleSuggestions()
+	if err != nil || len(suggestions) != 1 {
+		t.Fatalf("expected one suggestion, got %+v (%v)", suggestions, err)
+	}
+	if suggestions[0].Priority != 6 {
+		t.Errorf("expected priority 6, above the careem rule only, got %d", suggestions[0].Priority)
+	}
+	rule, _, _, err := db.AcceptRuleSuggestion(suggestions[0].Keyword, "Transport")
+	if err != nil {
+		t.Fatalf("AcceptRuleSuggestion failed: %v", err)
+	}
+	engine, _ := db.loadRuleEngine()
+	if m := engine.Match(Transaction{Description: "CAREEM HALA 3", Amount: 25, Date: "2026-02-01"}); m == nil || m.ID != rule.ID {
+		t.Errorf("expected the accepted rule to win, got %+v", m)
+	}
+}
+
+func TestRuleSuggestions_BackfillFromRuleLog(t *testing.T) {
+	db := setupTestDB(t)
+	db.db.Exec("DELETE FROM merchant_rules")
+	id, _ := db.SaveTransaction(Transaction{Description: "ENOC 1042", Amount: 150, Date: "2026-01-25", BillingCycle: "Jan 2026", Category: "Shopping & Gifts", Timestamp: "2026-01-25T10:00:00Z", Source: "openai"})
+	rule, _, _, _ := db.CreateRule("enoc", MatchContains, "Groceries", 10)
+	db.ApplyRuleSingle(rule.ID)
+	// An edit from before corrected_from was recorded.
+	db.db.Exec("UPDATE transactions SET category = 'Transport', source = 'manual', corrected_from = '' WHERE id = ?", id)
+
+	if err := db.migrateRuleSuggestions(); err != nil {
+		t.Fatalf("migrateRuleSuggestions failed: %v", err)
+	}
+	var from string
+	db.db.QueryRow("SELECT corrected_from FROM transactions WHERE id = ?", id).Scan(&from)
+	if from != "Groceries" {
+		t.Errorf("expected the rule's category recovered, got %q", from)
+	}
+}
+
+func TestRuleSuggestionsHandler(t *testing.T) {
+	db := setupTestDB(t)
+
+	w := httptest.NewRecorder()
+	ruleDetailHandler(db)(w, httptest.NewRequest(http.MethodGet, "/rules/suggestions", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"suggestions":[]`) {
+		t.Errorf("expected an empty list, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ruleDetailHandler(db)(w, httptest.NewRequest(http.MethodPost, "/rules/suggestions", strings.NewReader(`{"keyword": "hala", "category": "Transport"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown suggestion, got %d", w.Code)
+	}
+}
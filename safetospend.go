@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SafeToSpend is today's spending allowance: what is left of the wants
+// budget after spend so far and whatever fixed set-asides are still unfunded, spread
+// evenly over the days left in the cycle (today included).
+type SafeToSpend struct {
+	Cycle         string  `json:"cycle"`
+	Date          string  `json:"date"`
+	Daily         float64 `json:"daily"`
+	Remaining     float64 `json:"remaining"`
+	WantsBudget   float64 `json:"wantsBudget"`
+	WantsSpent    float64 `json:"wantsSpent"`
+	UnfundedFixed float64 `json:"unfundedFixed"`
+	DaysLeft      int     `json:"daysLeft"`
+}
+
+// GetSafeToSpend computes the allowance for a cycle as of now from the
+// bucket totals GetStats reports. Daily is never negative; Remaining is, so
+// an overspend stays visible.
+func (c *DatabaseClient) GetSafeToSpend(cycle string, now time.Time) (*SafeToSpend, error) {
+	if cycle == "" {
+		cycle = calculateBillingCycle(now.Format("2006-01-02"))
+	}
+	start, end, err := cycleBounds(cycle)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := c.GetStats(cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	allocated := make(map[int64]FundingStatus, len(stats.Funding))
+	for _, f := range stats.Funding {
+		allocated[f.CategoryID] = f
+	}
+	var unfunded float64
+	for _, cat := range stats.CategoryDefinitions {
+		if cat.Type == "fixed" && cat.Tracking == "allocated" && !cat.ExcludeFromTotals {
+			unfunded += allocated[cat.ID].Remaining
+		}
+	}
+
+	s := &SafeToSpend{
+		Cycle:         cycle,
+		Date:          now.Format("2006-01-02"),
+		WantsBudget:   stats.WantsBudget,
+		WantsSpent:    stats.WantsTotal,
+		UnfundedFixed: unfunded,
+	}
+	s.Remaining = s.WantsBudget - s.WantsSpent - s.UnfundedFixed
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case today.After(end):
+		s.DaysLeft = 0
+	case today.Before(start):
+		s.DaysLeft = int(end.Sub(start).Hours()/24) + 1
+	default:
+		s.DaysLeft = int(end.Sub(today).Hours()/24) + 1
+	}
+	if s.DaysLeft > 0 && s.Remaining > 0 {
+		s.Daily = s.Remaining / float64(s.DaysLeft)
+	}
+	return s, nil
+}
+
+// safeToSpendHandler serves GET /safe-to-spend for home-screen widgets.
+// JSON by default; ?format=text (or Accept: text/plain) returns just the
+// daily amount, e.g. "245 AED".
+func safeToSpendHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[API] GET /safe-to-spend - Request from %s", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s, err := db.GetSafeToSpend(r.URL.Query().Get("cycle"), time.Now())
+		if err != nil {
+			log.Printf("[API] Failed to compute safe-to-spend: %v", err)
+			if err.Error() == "invalid cycle" {
+				http.Error(w, "Invalid cycle", http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to compute safe-to-spend", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if r.URL.Query().Get("format") == "text" || strings.HasPrefix(r.Header.Get("Accept"), "text/plain") {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintf(w, "%.0f AED\n", s.Daily)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":     true,
+			"safeToSpend": s,
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafeToSpend_SpreadsRemainingOverDaysLeft(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, time.April, 13, 8, 0, 0, 0, time.UTC) // 10 days left in Mar 2026
+
+	stats, err := db.GetStats("Mar 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	var unfunded float64
+	for _, cat := range stats.CategoryDefinitions {
+		if cat.Type == "fixed" && cat.Tracking == "allocated" && cat.BudgetAmount != nil {
+			unfunded += *cat.BudgetAmount
+		}
+	}
+	rent := categoryByName(t, db, "Rent")
+	if err := db.SetFunding("Mar 2026", rent.ID, 9000); err != nil {
+		t.Fatalf("SetFunding failed: %v", err)
+	}
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour", Amount: 1200, Date: "2026-03-30", Category: "Groceries", BillingCycle: "Mar 2026", Timestamp: "2026-03-30T10:00:00Z", Source: "openai"})
+
+	s, err := db.GetSafeToSpend("Mar 2026", now)
+	if err != nil {
+		t.Fatalf("GetSafeToSpend failed: %v", err)
+	}
+	if s.DaysLeft != 10 {
+		t.Errorf("expected 10 days left, got %d", s.DaysLeft)
+	}
+	if s.UnfundedFixed != unfunded-9000 {
+		t.Errorf("expected unfunded fixed %.2f, got %.2f", unfunded-9000, s.UnfundedFixed)
+	}
+	want := stats.WantsBudget - 1200 - (unfunded - 9000)
+	if s.Remaining != want || s.Daily != want/10 {
+		t.Errorf("expected remaining %.2f / daily %.2f, got %+v", want, want/10, s)
+	}
+
+	if over, _ := db.GetSafeToSpend("Jan 2026", now); over.DaysLeft != 0 || over.Daily != 0 {
+		t.Errorf("expected finished cycle to have no allowance, got %+v", over)
+	}
+}
+
+func TestSafeToSpend_PlainText(t *testing.T) {
+	db := setupTestDB(t)
+
+	req := httptest.NewRequest("GET", "/safe-to-spend?format=text", nil)
+	rec := httptest.NewRecorder()
+	safeToSpendHandler(db)(rec, req)
+
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain, got %s", ct)
+	}
+	if body := rec.Body.String(); !strings.HasSuffix(body, " AED\n") {
+		t.Errorf("unexpected body %q", body)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	simulateDefaultCycles = 3
+	simulateMaxCycles     = 24
+)
+
+// BudgetChange is one hypothetical edit. It targets an existing category by
+// name, or creates one when no category has that name (Type is then
+// required). Remove drops the category and its transactions from the
+// scenario; otherwise any non-empty Type/Tracking and a non-nil Budget
+// replace the category's own.
+type BudgetChange struct {
+	Category string   `json:"category"`
+	Remove   bool     `json:"remove"`
+	Type     string   `json:"type"`
+	Tracking string   `json:"tracking"`
+	Budget   *float64 `json:"budget"`
+}
+
+// SimulationRequest is the body of POST /budget/simulate. Cycles is how many
+// completed cycles to replay (default 3). Salary, when set, replaces each
+// cycle's real income.
+type SimulationRequest struct {
+	Cycles  int            `json:"cycles"`
+	Salary  *float64       `json:"salary"`
+	Changes []BudgetChange `json:"changes"`
+}
+
+// SimBucket is one bucket in a simulated cycle. Share is the budget as a
+// percentage of salary.
+type SimBucket struct {
+	Budget float64 `json:"budget"`
+	Actual float64 `json:"actual"`
+	Share  float64 `json:"share"`
+}
+
+// SimCycle is a cycle replayed under a scenario. SalarySpent follows
+// GetStats (fixed + wants); Left is salary minus everything committed,
+// goals included. Overspent means Left went negative; OverBudget lists
+// buckets whose actual beat their budget.
+type SimCycle struct {
+	Cycle          string    `json:"cycle"`
+	Salary         float64   `json:"salary"`
+	Fixed          SimBucket `json:"fixed"`
+	Wants          SimBucket `json:"wants"`
+	Goals          SimBucket `json:"goals"`
+	SalarySpent    float64   `json:"salarySpent"`
+	SalarySpentPct float64   `json:"salarySpentPct"`
+	Left           float64   `json:"left"`
+	Overspent      bool      `json:"overspent"`
+	OverBudget     []string  `json:"overBudget,omitempty"`
+}
+
+type SimResult struct {
+	Cycles          []SimCycle `json:"cycles"`
+	Average         SimCycle   `json:"average"`
+	OverspentCycles []string   `json:"overspentCycles"`
+}
+
+// Simulation compares the real setup (Baseline) with the hypothetical one
+// (Scenario) over the same cycles.
+type Simulation struct {
+	Cycles   []string  `json:"cycles"`
+	Baseline SimResult `json:"baseline"`
+	Scenario SimResult `json:"scenario"`
+}
+
+// simCategory is the slice of a category the simulator needs.
+type simCategory struct {
+	Name     string
+	Type     string
+	Tracking string
+	Budget   *float64
+}
+
+// validateSimulation checks a request against the current categories and
+// fills in defaults.
+func validateSimulation(req *SimulationRequest, cats []Category) error {
+	if req.Cycles == 0 {
+		req.Cycles = simulateDefaultCycles
+	}
+	if req.Cycles < 1 || req.Cycles > simulateMaxCycles {
+		return fmt.Errorf("invalid simulation: cycles must be between 1 and %d", simulateMaxCycles)
+	}
+	if req.Salary != nil && *req.Salary <= 0 {
+		return fmt.Errorf("invalid simulation: salary must be greater than 0")
+	}
+	existing := make(map[string]bool, len(cats))
+	for _, cat := range cats {
+		existing[cat.Name] = true
+	}
+	for i := range req.Changes {
+		ch := &req.Changes[i]
+		ch.Category = strings.TrimSpace(ch.Category)
+		if ch.Category == "" {
+			return fmt.Errorf("invalid simulation: change %d has no category", i+1)
+		}
+		if !existing[ch.Category] {
+			if ch.Remove {
+				return fmt.Errorf("invalid simulation: unknown category %q", ch.Category)
+			}
+			if ch.Type == "" {
+				return fmt.Errorf("invalid simulation: new category %q needs a type", ch.Category)
+			}
+		}
+		switch ch.Type {
+		case "", "fixed", "wants", "goal", "other":
+		default:
+			return fmt.Errorf("invalid simulation: unknown type %q", ch.Type)
+		}
+		switch ch.Tracking {
+		case "", "actual", "allocated":
+		default:
+			return fmt.Errorf("invalid simulation: unknown tracking %q", ch.Tracking)
+		}
+		if ch.Budget != nil && *ch.Budget < 0 {
+			return fmt.Errorf("invalid simulation: negative budget for %q", ch.Category)
+		}
+	}
+	return nil
+}
+
+// applyBudgetChanges returns cats with the changes applied, leaving cats
+// untouched.
+func applyBudgetChanges(cats []simCategory, changes []BudgetChange) []simCategory {
+	out := make([]simCategory, 0, len(cats)+len(changes))
+	index := make(map[string]int, len(cats))
+	for _, cat := range cats {
+		index[cat.Name] = len(out)
+		out = append(out, cat)
+	}
+	removed := make(map[string]bool)
+	for _, ch := range changes {
+		if ch.Remove {
+			removed[ch.Category] = true
+			continue
+		}
+		i, ok := index[ch.Category]
+		if !ok {
+			index[ch.Category] = len(out)
+			out = append(out, simCategory{Name: ch.Category, Tracking: "actual"})
+			i = len(out) - 1
+		}
+		if ch.Type != "" {
+			out[i].Type = ch.Type
+		}
+		if ch.Tracking != "" {
+			out[i].Tracking = ch.Tracking
+		}
+		if ch.Budget != nil {
+			out[i].Budget = floatPtr(*ch.Budget)
+		}
+	}
+	kept := out[:0]
+	for _, cat := range out {
+		if !removed[cat.Name] {
+			kept = append(kept, cat)
+		}
+	}
+	return kept
+}
+
+// simulateCycle totals one cycle's buckets the way GetStats does, except
+// that allocated lines count at their budget (as if ticked off) so that
+// budget changes on them show up.
+func simulateCycle(cycle string, cats []simCategory, spent map[string]float64, salary float64) SimCycle {
+	s := SimCycle{Cycle: cycle, Salary: salary}
+	for _, cat := range cats {
+		var bucket *SimBucket
+		switch cat.Type {
+		case "fixed":
+			bucket = &s.Fixed
+		case "wants":
+			bucket = &s.Wants
+		case "goal":
+			bucket = &s.Goals
+		default:
+			continue
+		}
+		budget := 0.0
+		if cat.Budget != nil {
+			budget = *cat.Budget
+		}
+		bucket.Budget += budget
+		if cat.Tracking == "allocated" {
+			bucket.Actual += budget
+		} else if cat.Type != "goal" {
+			bucket.Actual += spent[cat.Name]
+		}
+	}
+	finishSimCycle(&s)
+	return s
+}
+
+// finishSimCycle fills in the figures derived from the bucket totals.
+func finishSimCycle(s *SimCycle) {
+	s.SalarySpent = s.Fixed.Actual + s.Wants.Actual
+	s.Left = s.Salary - s.SalarySpent - s.Goals.Actual
+	s.Overspent = s.Left < 0
+	if s.Salary > 0 {
+		s.SalarySpentPct = s.SalarySpent / s.Salary * 100
+		for _, b := range []*SimBucket{&s.Fixed, &s.Wants, &s.Goals} {
+			b.Share = b.Budget / s.Salary * 100
+		}
+	}
+	s.OverBudget = nil
+	for _, b := range []struct {
+		name   string
+		bucket SimBucket
+	}{{"fixed", s.Fixed}, {"wants", s.Wants}, {"goals", s.Goals}} {
+		if b.bucket.Actual > b.bucket.Budget {
+			s.OverBudget = append(s.OverBudget, b.name)
+		}
+	}
+}
+
+// summarizeSimulation averages a scenario's cycles and lists the overspent ones.
+func summarizeSimulation(cycles []SimCycle) SimResult {
+	r := SimResult{Cycles: cycles, Average: SimCycle{Cycle: "average"}, OverspentCycles: []string{}}
+	for _, s := range cycles {
+		if s.Overspent {
+			r.OverspentCycles = append(r.OverspentCycles, s.Cycle)
+		}
+		r.Average.Salary += s.Salary
+		for _, pair := range [][2]*SimBucket{{&r.Average.Fixed, &s.Fixed}, {&r.Average.Wants, &s.Wants}, {&r.Average.Goals, &s.Goals}} {
+			pair[0].Budget += pair[1].Budget
+			pair[0].Actual += pair[1].Actual
+		}
+	}
+	if n := float64(len(cycles)); n > 0 {
+		r.Average.Salary /= n
+		for _, b := range []*SimBucket{&r.Average.Fixed, &r.Average.Wants, &r.Average.Goals} {
+			b.Budget /= n
+			b.Actual /= n
+		}
+	}
+	finishSimCycle(&r.Average)
+	return r
+}
+
+// SimulateBudget replays the last req.Cycles completed cycles (ending the
+// cycle before now's) against the current setup and against the setup with
+// req's changes applied. Nothing is written.
+func (c *DatabaseClient) SimulateBudget(req SimulationRequest, now time.Time) (*Simulation, error) {
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSimulation(&req, cats); err != nil {
+		return nil, err
+	}
+
+	last := shiftCycle(calculateBillingCycle(now.Format("2006-01-02")), -1)
+	cycles := cyclesBetween(shiftCycle(last, -(req.Cycles-1)), last)
+	sim := &Simulation{Cycles: cycles}
+	var baseline, scenario []SimCycle
+	for _, cycle := range cycles {
+		budgets, err := c.BudgetsForCycle(cycle)
+		if err != nil {
+			return nil, err
+		}
+		spent, _, err := c.categorySpend(cycle, "")
+		if err != nil {
+			return nil, err
+		}
+		income, err := c.IncomeForCycle(cycle)
+		if err != nil {
+			return nil, err
+		}
+
+		// A sub-category under a budgeted parent adds nothing of its own.
+		shadowed := shadowedBudgets(cats, budgets)
+		base := make([]simCategory, 0, len(cats))
+		for _, cat := range cats {
+			if cat.ExcludeFromTotals {
+				continue
+			}
+			budget := budgets[cat.ID]
+			if shadowed[cat.ID] {
+				budget = nil
+			}
+			base = append(base, simCategory{Name: cat.Name, Type: cat.Type, Tracking: cat.Tracking, Budget: budget})
+		}
+		salary := income.Total
+		baseline = append(baseline, simulateCycle(cycle, base, spent, salary))
+
+		if req.Salary != nil {
+			salary = *req.Salary
+		}
+		scenario = append(scenario, simulateCycle(cycle, applyBudgetChanges(base, req.Changes), spent, salary))
+	}
+	sim.Baseline = summarizeSimulation(baseline)
+	sim.Scenario = summarizeSimulation(scenario)
+	return sim, nil
+}
+
+// budgetSimulateHandler serves POST /budget/simulate: a what-if run of
+// hypothetical salary and budget changes over recent cycles.
+func budgetSimulateHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req SimulationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		log.Printf("[API] POST /budget/simulate - %d changes over %d cycles from %s", len(req.Changes), req.Cycles, r.RemoteAddr)
+
+		sim, err := db.SimulateBudget(req, time.Now())
+		if err != nil {
+			log.Printf("[API] Failed to simulate budget: %v", err)
+			if strings.HasPrefix(err.Error(), "invalid simulation") {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, "Failed to simulate budget", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"simulation": sim,
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulateBudget_BaselineAndScenario(t *testing.T) {
+	db := setupTestDB(t)
+	insertTestTransaction(t, db, Transaction{Description: "Carrefour", Amount: 1500, Date: "2026-01-25", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "Spinneys", Amount: 2500, Date: "2026-02-25", Category: "Groceries", BillingCycle: "Feb 2026", Timestamp: "2026-02-25T10:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "DEWA", Amount: 700, Date: "2026-02-01", Category: "DEWA", BillingCycle: "Jan 2026", Timestamp: "2026-02-01T10:00:00Z", Source: "manual"})
+
+	// Replays Jan and Feb 2026, the two cycles before Mar 2026.
+	now := time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)
+	sim, err := db.SimulateBudget(SimulationRequest{
+		Cycles: 2,
+		Salary: floatPtr(30000),
+		Changes: []BudgetChange{
+			{Category: "Rent", Budget: floatPtr(20000)},
+			{Category: "Car loan 2", Type: "fixed", Tracking: "allocated", Budget: floatPtr(1800)},
+			{Category: "Therapy", Remove: true},
+			{Category: "Groceries", Type: "fixed"},
+		},
+	}, now)
+	if err != nil {
+		t.Fatalf("SimulateBudget failed: %v", err)
+	}
+	if len(sim.Cycles) != 2 || sim.Cycles[0] != "Jan 2026" || sim.Cycles[1] != "Feb 2026" {
+		t.Fatalf("unexpected cycles: %v", sim.Cycles)
+	}
+
+	// Baseline: allocated fixed lines 15683 at budget, plus 700 DEWA.
+	jan := sim.Baseline.Cycles[0]
+	if jan.Salary != 32500 || jan.Fixed.Actual != 16383 || jan.Wants.Actual != 1500 || jan.Goals.Actual != 5250 {
+		t.Errorf("unexpected baseline Jan: %+v", jan)
+	}
+	if jan.SalarySpent != 17883 || jan.Left != 32500-17883-5250 || jan.Overspent {
+		t.Errorf("unexpected baseline Jan totals: %+v", jan)
+	}
+	if len(sim.Baseline.OverspentCycles) != 0 {
+		t.Errorf("expected no overspent baseline cycles, got %v", sim.Baseline.OverspentCycles)
+	}
+
+	// Scenario: +11000 rent, +1800 loan, −1762 therapy, groceries moved to fixed.
+	jan = sim.Scenario.Cycles[0]
+	if jan.Fixed.Actual != 16383+11000+1800-1762+1500 || jan.Wants.Actual != 0 {
+		t.Errorf("unexpected scenario Jan buckets: %+v", jan)
+	}
+	if jan.Salary != 30000 || !jan.Overspent {
+		t.Errorf("expected scenario Jan overspent on 30000, got %+v", jan)
+	}
+	if len(sim.Scenario.OverspentCycles) != 2 {
+		t.Errorf("expected both scenario cycles overspent, got %v", sim.Scenario.OverspentCycles)
+	}
+	if avg := sim.Scenario.Average; avg.Salary != 30000 || !avg.Overspent {
+		t.Errorf("unexpected scenario average: %+v", avg)
+	}
+
+	// Nothing was persisted.
+	if rent := categoryByName(t, db, "Rent"); *rent.BudgetAmount != 9000 {
+		t.Errorf("expected Rent budget untouched, got %v", *rent.BudgetAmount)
+	}
+	cats, _ := db.GetAllCategories()
+	for _, cat := range cats {
+		if cat.Name == "Car loan 2" {
+			t.Error("expected the hypothetical category not to be created")
+		}
+	}
+	if db.GetSalary() != 32500 {
+		t.Errorf("expected salary untouched, got %v", db.GetSalary())
+	}
+}
+
+func TestSimulateBudget_Validation(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)
+	for name, req := range map[string]SimulationRequest{
+		"new category without type": {Changes: []BudgetChange{{Category: "Pets", Budget: floatPtr(200)}}},
+		"remove unknown":            {Changes: []BudgetChange{{Category: "Pets", Remove: true}}},
+		"bad tracking":              {Changes: []BudgetChange{{Category: "Rent", Tracking: "monthly"}}},
+		"too many cycles":           {Cycles: 100},
+	} {
+		if _, err := db.SimulateBudget(req, now); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
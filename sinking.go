@@ -0,0 +1,278 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SinkingFund turns an allocated category into a running balance for a bill
+// that arrives once in a while (car registration, insurance). Each cycle's
+// funding tick adds to the balance and the category's actual transactions
+// draw it down, from StartCycle onward.
+type SinkingFund struct {
+	CategoryID     int64   `json:"categoryId"`
+	DueDate        string  `json:"dueDate"`
+	ExpectedBill   float64 `json:"expectedBill"`
+	OpeningBalance float64 `json:"openingBalance"`
+	StartCycle     string  `json:"startCycle"`
+	CreatedAt      string  `json:"createdAt"`
+}
+
+// SinkingFundStatus is a fund's balance and whether it will cover the next
+// bill. NextDue is DueDate rolled forward a year at a time until it is not
+// in the past. ProjectedAtDue adds the category's current budget for every
+// cycle still to be funded up to and including the due date's cycle.
+type SinkingFundStatus struct {
+	SinkingFund
+	Category       string  `json:"category"`
+	Funded         float64 `json:"funded"`
+	Spent          float64 `json:"spent"`
+	Balance        float64 `json:"balance"`
+	MonthlyFunding float64 `json:"monthlyFunding"`
+	NextDue        string  `json:"nextDue"`
+	CyclesToDue    int     `json:"cyclesToDue"`
+	ProjectedAtDue float64 `json:"projectedAtDue"`
+	Shortfall      float64 `json:"shortfall"`
+	Covered        bool    `json:"covered"`
+	Warning        string  `json:"warning,omitempty"`
+}
+
+func (c *DatabaseClient) migrateSinkingFunds() error {
+	_, err := c.db.Exec(`CREATE TABLE IF NOT EXISTS sinking_funds (
+		category_id INTEGER PRIMARY KEY,
+		due_date TEXT NOT NULL,
+		expected_bill REAL NOT NULL,
+		opening_balance REAL NOT NULL DEFAULT 0,
+		start_cycle TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("sinking_funds migration failed: %w", err)
+	}
+	return nil
+}
+
+// SetSinkingFund makes an allocated category a sinking fund, or updates its
+// settings. ExpectedBill defaults to twelve cycles of the category's budget;
+// StartCycle defaults to the current cycle for a new fund.
+func (c *DatabaseClient) SetSinkingFund(f SinkingFund) (*SinkingFund, error) {
+	cat, err := c.GetCategory(f.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if cat.Tracking != "allocated" {
+		return nil, fmt.Errorf("sinking fund requires allocated tracking")
+	}
+	if _, err := time.Parse("2006-01-02", f.DueDate); err != nil {
+		return nil, fmt.Errorf("invalid due date")
+	}
+	if f.StartCycle != "" && cycleSortKey(f.StartCycle) == "" {
+		return nil, fmt.Errorf("invalid cycle")
+	}
+	if f.ExpectedBill <= 0 {
+		if cat.BudgetAmount == nil {
+			return nil, fmt.Errorf("expected bill is required")
+		}
+		f.ExpectedBill = *cat.BudgetAmount * 12
+	}
+
+	existing, err := c.GetSinkingFund(f.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if f.StartCycle == "" {
+		if existing != nil {
+			f.StartCycle = existing.StartCycle
+		} else {
+			f.StartCycle = calculateBillingCycle(time.Now().Format("2006-01-02"))
+		}
+	}
+	f.CreatedAt = time.Now().Format(time.RFC3339)
+	if existing != nil {
+		f.CreatedAt = existing.CreatedAt
+	}
+
+	_, err = c.db.Exec(
+		`INSERT INTO sinking_funds (category_id, due_date, expected_bill, opening_balance, start_cycle, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(category_id) DO UPDATE SET due_date = excluded.due_date, expected_bill = excluded.expected_bill,
+		 opening_balance = excluded.opening_balance, start_cycle = excluded.start_cycle`,
+		f.CategoryID, f.DueDate, f.ExpectedBill, f.OpeningBalance, f.StartCycle, f.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set sinking fund: %w", err)
+	}
+	return &f, nil
+}
+
+// GetSinkingFund returns a category's sinking fund settings, or nil.
+func (c *DatabaseClient) GetSinkingFund(categoryID int64) (*SinkingFund, error) {
+	var f SinkingFund
+	err := c.db.QueryRow(
+		"SELECT category_id, due_date, expected_bill, opening_balance, start_cycle, created_at FROM sinking_funds WHERE category_id = ?",
+		categoryID,
+	).Scan(&f.CategoryID, &f.DueDate, &f.ExpectedBill, &f.OpeningBalance, &f.StartCycle, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sinking fund: %w", err)
+	}
+	return &f, nil
+}
+
+// DeleteSinkingFund turns a sinking fund back into a plain allocated
+// category. Funding and transactions are untouched.
+func (c *DatabaseClient) DeleteSinkingFund(categoryID int64) error {
+	result, err := c.db.Exec("DELETE FROM sinking_funds WHERE category_id = ?", categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sinking fund: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("sinking fund not found")
+	}
+	return nil
+}
+
+// categorySpendByCycle sums a category's spend lines per billing cycle.
+func (c *DatabaseClient) categorySpendByCycle(name string) (map[string]float64, error) {
+	rows, err := c.db.Query("SELECT billing_cycle, SUM(amount) FROM spend_lines WHERE category = ? GROUP BY billing_cycle", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category spend: %w", err)
+	}
+	defer rows.Close()
+
+	spend := make(map[string]float64)
+	for rows.Next() {
+		var cycle string
+		var sum float64
+		if err := rows.Scan(&cycle, &sum); err != nil {
+			return nil, fmt.Errorf("failed to scan category spend: %w", err)
+		}
+		spend[cycle] = sum
+	}
+	return spend, rows.Err()
+}
+
+// sinkingFundStatus computes a fund's balance and next-bill coverage as of now.
+func (c *DatabaseClient) sinkingFundStatus(f SinkingFund, now time.Time) (*SinkingFundStatus, error) {
+	cat, err := c.GetCategory(f.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	cycles, amounts, err := c.fundingHistory(f.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	spend, err := c.categorySpendByCycle(cat.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	startKey := cycleSortKey(f.StartCycle)
+	s := &SinkingFundStatus{SinkingFund: f, Category: cat.Name}
+	for i, cycle := range cycles {
+		if cycleSortKey(cycle) >= startKey {
+			s.Funded += amounts[i]
+		}
+	}
+	for cycle, sum := range spend {
+		if cycleSortKey(cycle) >= startKey {
+			s.Spent += sum
+		}
+	}
+	s.Balance = f.OpeningBalance + s.Funded - s.Spent
+
+	today := now.Format("2006-01-02")
+	due, _ := time.Parse("2006-01-02", f.DueDate)
+	for due.Format("2006-01-02") < today {
+		due = due.AddDate(1, 0, 0)
+	}
+	s.NextDue = due.Format("2006-01-02")
+
+	current := calculateBillingCycle(today)
+	if budget, err := c.BudgetFor(cat.ID, current); err != nil {
+		return nil, err
+	} else if budget != nil {
+		s.MonthlyFunding = *budget
+	}
+	funded := make(map[string]bool, len(cycles))
+	for _, cycle := range cycles {
+		funded[cycle] = true
+	}
+	for _, cycle := range cyclesBetween(current, calculateBillingCycle(s.NextDue)) {
+		if !funded[cycle] {
+			s.CyclesToDue++
+		}
+	}
+	s.ProjectedAtDue = s.Balance + s.MonthlyFunding*float64(s.CyclesToDue)
+
+	s.Covered = s.ProjectedAtDue >= f.ExpectedBill
+	if !s.Covered {
+		s.Shortfall = f.ExpectedBill - s.ProjectedAtDue
+		s.Warning = fmt.Sprintf("%s will be %.2f AED short of the %.2f AED bill due %s",
+			cat.Name, s.Shortfall, f.ExpectedBill, s.NextDue)
+	}
+	return s, nil
+}
+
+// GetSinkingFundStatuses lists every sinking fund with its balance as of now.
+func (c *DatabaseClient) GetSinkingFundStatuses(now time.Time) ([]SinkingFundStatus, error) {
+	rows, err := c.db.Query("SELECT category_id, due_date, expected_bill, opening_balance, start_cycle, created_at FROM sinking_funds ORDER BY due_date ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sinking funds: %w", err)
+	}
+	var funds []SinkingFund
+	for rows.Next() {
+		var f SinkingFund
+		if err := rows.Scan(&f.CategoryID, &f.DueDate, &f.ExpectedBill, &f.OpeningBalance, &f.StartCycle, &f.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan sinking fund: %w", err)
+		}
+		funds = append(funds, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := []SinkingFundStatus{}
+	for _, f := range funds {
+		s, err := c.sinkingFundStatus(f, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *s)
+	}
+	return statuses, nil
+}
+
+// sinkingFundsHandler serves GET /sinking-funds: every fund with its balance
+// and coverage warning.
+func sinkingFundsHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[API] GET /sinking-funds - Request from %s", r.RemoteAddr)
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		funds, err := db.GetSinkingFundStatuses(time.Now())
+		if err != nil {
+			log.Printf("[API] Failed to get sinking funds: %v", err)
+			http.Error(w, "Failed to retrieve sinking funds", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"funds":   funds,
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSinkingFund_BalanceAndCoverage(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, time.April, 7, 9, 0, 0, 0, time.UTC) // Mar 2026 cycle
+
+	reg := categoryByName(t, db, "Car registration") // allocated, 33/month
+	fund, err := db.SetSinkingFund(SinkingFund{CategoryID: reg.ID, DueDate: "2026-06-10", OpeningBalance: 200, StartCycle: "Jan 2026"})
+	if err != nil {
+		t.Fatalf("SetSinkingFund failed: %v", err)
+	}
+	if fund.ExpectedBill != 396 {
+		t.Errorf("expected bill to default to 12 × 33 = 396, got %.2f", fund.ExpectedBill)
+	}
+	for _, cycle := range []string{"Jan 2026", "Feb 2026", "Mar 2026"} {
+		if err := db.SetFunding(cycle, reg.ID, 33); err != nil {
+			t.Fatalf("SetFunding failed: %v", err)
+		}
+	}
+
+	s, err := db.sinkingFundStatus(*fund, now)
+	if err != nil {
+		t.Fatalf("sinkingFundStatus failed: %v", err)
+	}
+	// 200 + 3 × 33 = 299; Apr and May 2026 still to fund → 365 < 396.
+	if s.Balance != 299 || s.CyclesToDue != 2 || s.ProjectedAtDue != 365 {
+		t.Errorf("expected balance 299, 2 cycles, projected 365, got %+v", s)
+	}
+	if s.Covered || s.Shortfall != 31 || s.Warning == "" {
+		t.Errorf("expected a 31 AED shortfall warning, got %+v", s)
+	}
+
+	// The bill draws the balance down; the due date then rolls to next year.
+	insertTestTransaction(t, db, Transaction{Description: "RTA registration", Amount: 280, Date: "2026-04-05", Category: "Car registration", BillingCycle: "Mar 2026", Timestamp: "2026-04-05T10:00:00Z", Source: "openai"})
+	s, _ = db.sinkingFundStatus(*fund, time.Date(2026, time.June, 20, 0, 0, 0, 0, time.UTC))
+	if s.Balance != 19 || s.NextDue != "2027-06-10" {
+		t.Errorf("expected balance 19 and next due 2027-06-10, got %.2f / %s", s.Balance, s.NextDue)
+	}
+}
+
+func TestSinkingFund_RequiresAllocated(t *testing.T) {
+	db := setupTestDB(t)
+
+	groc := categoryByName(t, db, "Groceries")
+	if _, err := db.SetSinkingFund(SinkingFund{CategoryID: groc.ID, DueDate: "2026-12-01"}); err == nil {
+		t.Error("expected actual-tracked category to be rejected")
+	}
+	ins := categoryByName(t, db, "Car insurance")
+	if _, err := db.SetSinkingFund(SinkingFund{CategoryID: ins.ID, DueDate: "December"}); err == nil {
+		t.Error("expected invalid due date to be rejected")
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// A split transaction keeps its parent row (description, date, the full
+// amount as charged) but its spend is counted through its split lines, each
+// with its own category and amount. The lines must add up to the parent's
+// amount. Spend queries read the spend_lines view: one row per split line,
+// or the transaction itself when it isn't split. A split line carries its
+// parent's id, so tag filters and per-transaction lookups work on either.
+// Transactions a rule excluded from totals (see ruleactions.go) are left
+// out of the view.
+
+// TransactionSplit is one line of a split transaction.
+type TransactionSplit struct {
+	ID       int64   `json:"id"`
+	Amount   float64 `json:"amount"`
+	Category string  `json:"category"`
+	Note     string  `json:"note,omitempty"`
+}
+
+func (c *DatabaseClient) migrateSplits() error {
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS transaction_splits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id INTEGER NOT NULL,
+			category TEXT NOT NULL,
+			amount REAL NOT NULL,
+			note TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_transaction_splits_tx ON transaction_splits(transaction_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_transaction_splits_category ON transaction_splits(category)`,
+		// Recreated on every start so the view follows the tables.
+		`DROP VIEW IF EXISTS spend_lines`,
+		`CREATE VIEW spend_lines AS
+			SELECT t.id, t.description, COALESCE(s.amount, t.amount) AS amount, t.transaction_date,
+				COALESCE(s.category, t.category) AS category, t.confidence, t.billing_cycle,
+				t.created_at, t.source, s.id AS split_id, CASE WHEN s.id IS NULL THEN t.note ELSE s.note END AS note
+			FROM transactions t LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+			WHERE t.excluded = 0`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("transaction_splits migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetTransactionSplits replaces a transaction's split lines. There must be
+// at least two, each with the transaction's sign and in an active category,
+// adding up to the transaction's amount. An empty list un-splits the transaction.
+func (c *DatabaseClient) SetTransactionSplits(id int64, splits []TransactionSplit) error {
+	var amount float64
+	var cycle string
+	err := c.db.QueryRow("SELECT amount, billing_cycle FROM transactions WHERE id = ?", id).Scan(&amount, &cycle)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("transaction not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if err := c.ensureCycleOpen(cycle); err != nil {
+		return err
+	}
+
+	if len(splits) == 1 {
+		return fmt.Errorf("invalid splits: a split needs at least two lines")
+	}
+	active := make(map[string]bool)
+	if len(splits) > 0 {
+		cats, err := c.GetAllCategories()
+		if err != nil {
+			return err
+		}
+		for _, cat := range cats {
+			active[cat.Name] = !cat.Archived
+		}
+	}
+	var sum float64
+	for i := range splits {
+		splits[i].Category = strings.TrimSpace(splits[i].Category)
+		// A refund or credit splits into negative lines.
+		if splits[i].Amount == 0 || (splits[i].Amount > 0) != (amount > 0) {
+			return fmt.Errorf("invalid splits: amounts must be non-zero with the transaction's sign")
+		}
+		if !active[splits[i].Category] {
+			return fmt.Errorf("invalid splits: unknown category %q", splits[i].Category)
+		}
+		sum += splits[i].Amount
+	}
+	if len(splits) > 0 && math.Abs(sum-amount) > 0.005 {
+		return fmt.Errorf("invalid splits: lines add up to %.2f but the transaction is %.2f", sum, amount)
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM transaction_splits WHERE transaction_id = ?", id); err != nil {
+		return fmt.Errorf("failed to clear splits: %w", err)
+	}
+	for _, s := range splits {
+		if _, err := tx.Exec(
+			"INSERT INTO transaction_splits (transaction_id, category, amount, note) VALUES (?, ?, ?, ?)",
+			id, s.Category, s.Amount, strings.TrimSpace(s.Note),
+		); err != nil {
+			return fmt.Errorf("failed to add split: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit splits: %w", err)
+	}
+
+	c.checkAlertsAfterWrite(cycle, cycle)
+	return nil
+}
+
+// GetTransactionSplits returns a transaction's split lines, or an empty list
+// when it isn't split.
+func (c *DatabaseClient) GetTransactionSplits(id int64) ([]TransactionSplit, error) {
+	var exists bool
+	if err := c.db.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions WHERE id = ?)", id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("transaction not found")
+	}
+	splits, err := c.transactionSplits("transaction_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if splits[id] == nil {
+		return []TransactionSplit{}, nil
+	}
+	return splits[id], nil
+}
+
+// transactionSplits maps transaction IDs to their split lines, filtered by
+// where (on transaction_splits) when it is not "".
+func (c *DatabaseClient) transactionSplits(where string, args ...interface{}) (map[int64][]TransactionSplit, error) {
+	query := "SELECT id, transaction_id, category, amount, note FROM transaction_splits"
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := c.db.Query(query+" ORDER BY id ASC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query splits: %w", err)
+	}
+	defer rows.Close()
+
+	splits := make(map[int64][]TransactionSplit)
+	for rows.Next() {
+		var s TransactionSplit
+		var txID int64
+		if err := rows.Scan(&s.ID, &txID, &s.Category, &s.Amount, &s.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan split: %w", err)
+		}
+		splits[txID] = append(splits[txID], s)
+	}
+	return splits, rows.Err()
+}
+
+// cycleSplits maps transaction IDs to their split lines for one billing
+// cycle or, with cycle "", for every transaction.
+func (c *DatabaseClient) cycleSplits(cycle string) (map[int64][]TransactionSplit, error) {
+	if cycle == "" {
+		return c.transactionSplits("")
+	}
+	return c.transactionSplits("transaction_id IN (SELECT id FROM transactions WHERE billing_cycle = ?)", cycle)
+}
+
+func attachSplits(txs []Transaction, splits map[int64][]TransactionSplit) {
+	for i := range txs {
+		txs[i].Splits = splits[txs[i].ID]
+	}
+}
+
+// hasSplits reports whether a transaction is split.
+func (c *DatabaseClient) hasSplits(id int64) (bool, error) {
+	var split bool
+	if err := c.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM transaction_splits WHERE transaction_id = ?)", id,
+	).Scan(&split); err != nil {
+		return false, fmt.Errorf("failed to check splits: %w", err)
+	}
+	return split, nil
+}
+
+// splitsHandler serves GET/PUT /transaction/:id/splits. PUT takes
+// {"splits": [{amount, category, note}]}; an empty list un-splits.
+func splitsHandler(db *DatabaseClient, id int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			log.Printf("[API] GET /transaction/%d/splits - Request from %s", id, r.RemoteAddr)
+
+		case http.MethodPut:
+			log.Printf("[API] PUT /transaction/%d/splits - Set splits from %s", id, r.RemoteAddr)
+			var req struct {
+				Splits []TransactionSplit `json:"splits"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := db.SetTransactionSplits(id, req.Splits); err != nil {
+				log.Printf("[API] Failed to set splits: %v", err)
+				switch {
+				case err.Error() == "transaction not found":
+					http.Error(w, "Transaction not found", http.StatusNotFound)
+				case strings.HasPrefix(err.Error(), "invalid splits"):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				case strings.HasPrefix(err.Error(), "cycle closed:"):
+					writeCycleClosed(w, err)
+				default:
+					http.Error(w, "Failed to set splits", http.StatusInternalServerError)
+				}
+				return
+			}
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		splits, err := db.GetTransactionSplits(id)
+		if err != nil {
+			log.Printf("[API] Failed to get splits: %v", err)
+			if err.Error() == "transaction not found" {
+				http.Error(w, "Transaction not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to retrieve splits", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"splits":  splits,
+		})
+	}
+}
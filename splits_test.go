@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSplits_CountedInsteadOfParent(t *testing.T) {
+	db := setupTestDB(t)
+	id, err := db.SaveTransaction(Transaction{Description: "Carrefour", Amount: 500, Date: "2026-01-25", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual", Tags: []string{"weekly shop"}})
+	if err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+	insertTestTransaction(t, db, Transaction{Description: "Spinneys", Amount: 100, Date: "2026-01-26", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-01-26T10:00:00Z", Source: "manual"})
+	before, _ := db.GetStats("Jan 2026")
+
+	err = db.SetTransactionSplits(id, []TransactionSplit{
+		{Amount: 300, Category: "Groceries"},
+		{Amount: 120, Category: "Shopping & Gifts", Note: "birthday card"},
+		{Amount: 80, Category: "Rent", Note: "cleaning supplies"},
+	})
+	if err != nil {
+		t.Fatalf("SetTransactionSplits failed: %v", err)
+	}
+
+	stats, err := db.GetStats("Jan 2026")
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.Total != 600 || stats.Count != 2 {
+		t.Errorf("expected the total and count unchanged, got %v / %d", stats.Total, stats.Count)
+	}
+	if row := statsRow(t, stats.Categories, "Groceries"); row.Total != 400 || row.Count != 2 {
+		t.Errorf("expected Groceries to hold 300 of the split plus Spinneys, got %+v", row)
+	}
+	gifts := statsRow(t, stats.Categories, "Shopping & Gifts")
+	if gifts.Total != 120 || len(gifts.Transactions) != 1 || gifts.Transactions[0].ID != id || gifts.Transactions[0].Note != "birthday card" || gifts.Transactions[0].SplitID == 0 {
+		t.Errorf("expected the gift line listed under Shopping & Gifts, got %+v", gifts)
+	}
+	if stats.WantsTotal != before.WantsTotal-80 {
+		t.Errorf("expected the Rent line to leave wants spend, got %v → %v", before.WantsTotal, stats.WantsTotal)
+	}
+	for _, tx := range stats.AllTransactions {
+		if tx.ID == id && (tx.Amount != 500 || len(tx.Splits) != 3) {
+			t.Errorf("expected the flat list to keep the parent with its splits, got %+v", tx)
+		}
+	}
+	if tagged, _ := db.GetStatsForTag("Jan 2026", "weekly shop"); tagged.Total != 500 {
+		t.Errorf("expected the tag filter to cover all split lines, got %v", tagged.Total)
+	}
+
+	w := httptest.NewRecorder()
+	exportHandler(db)(w, httptest.NewRequest(http.MethodGet, "/export", nil))
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	var lines []string
+	for _, record := range records {
+		if strings.HasPrefix(record[1], "Carrefour") {
+			lines = append(lines, record[1]+"|"+record[2]+"|"+record[3])
+		}
+	}
+	want := []string{"Carrefour|300.00|Groceries", "Carrefour (birthday card)|120.00|Shopping & Gifts", "Carrefour (cleaning supplies)|80.00|Rent"}
+	if strings.Join(lines, ",") != strings.Join(want, ",") {
+		t.Errorf("expected the export to list the split lines, got %v", lines)
+	}
+
+	// Un-splitting puts the full amount back on the parent's category.
+	if err := db.SetTransactionSplits(id, nil); err != nil {
+		t.Fatalf("SetTransactionSplits(nil) failed: %v", err)
+	}
+	stats, _ = db.GetStats("Jan 2026")
+	if row := statsRow(t, stats.Categories, "Groceries"); row.Total != 600 {
+		t.Errorf("expected Groceries back at 600, got %v", row.Total)
+	}
+}
+
+func TestSplits_Validation(t *testing.T) {
+	db := setupTestDB(t)
+	id, err := db.SaveTransaction(Transaction{Description: "Carrefour", Amount: 200, Date: "2026-01-25", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual"})
+	if err != nil {
+		t.Fatalf("SaveTransaction failed: %v", err)
+	}
+
+	for name, splits := range map[string][]TransactionSplit{
+		"one line":         {{Amount: 200, Category: "Groceries"}},
+		"doesn't add up":   {{Amount: 150, Category: "Groceries"}, {Amount: 40, Category: "Transport"}},
+		"unknown category": {{Amount: 150, Category: "Groceries"}, {Amount: 50, Category: "Pets"}},
+		"zero amount":      {{Amount: 200, Category: "Groceries"}, {Amount: 0, Category: "Transport"}},
+		"wrong sign":       {{Amount: 250, Category: "Groceries"}, {Amount: -50, Category: "Transport"}},
+	} {
+		if err := db.SetTransactionSplits(id, splits); err == nil || !strings.HasPrefix(err.Error(), "invalid splits") {
+			t.Errorf("%s: expected invalid splits, got %v", name, err)
+		}
+	}
+	if err := db.SetTransactionSplits(9999, nil); err == nil || err.Error() != "transaction not found" {
+		t.Errorf("expected transaction not found, got %v", err)
+	}
+
+	// A refund splits into negative lines.
+	refund, _ := db.SaveTransaction(Transaction{Description: "Carrefour refund", Amount: -80, Date: "2026-01-26", Category: "Groceries", BillingCycle: "Jan 2026", Timestamp: "2026-01-26T10:00:00Z", Source: "manual"})
+	if err := db.SetTransactionSplits(refund, []TransactionSplit{{Amount: -60, Category: "Groceries"}, {Amount: -20, Category: "Transport"}}); err != nil {
+		t.Errorf("expected a refund to split into negative lines, got %v", err)
+	}
+	if err := db.SetTransactionSplits(refund, []TransactionSplit{{Amount: -100, Category: "Groceries"}, {Amount: 20, Category: "Transport"}}); err == nil || !strings.HasPrefix(err.Error(), "invalid splits") {
+		t.Errorf("expected a positive line on a refund to be refused, got %v", err)
+	}
+	db.DeleteTransaction(refund)
+
+	if err := db.SetTransactionSplits(id, []TransactionSplit{{Amount: 150, Category: "Groceries"}, {Amount: 50, Category: "Transport"}}); err != nil {
+		t.Fatalf("SetTransactionSplits failed: %v", err)
+	}
+	err = db.UpdateTransaction(id, Transaction{Description: "Carrefour", Amount: 250, Date: "2026-01-25", Category: "Groceries", BillingCycle: "Jan 2026"})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid amount") {
+		t.Errorf("expected an amount change on a split transaction to be refused, got %v", err)
+	}
+
+	// Split lines follow category renames and block deleting their category.
+	transport := categoryByName(t, db, "Transport")
+	if err := db.UpdateCategory(transport.ID, "Getting around", transport.Emoji, false, transport.Type, transport.BudgetAmount, transport.Tracking); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+	splits, _ := db.GetTransactionSplits(id)
+	if len(splits) != 2 || splits[1].Category != "Getting around" {
+		t.Errorf("expected the split line renamed, got %+v", splits)
+	}
+	if err := db.DeleteCategory(transport.ID); err == nil || !strings.Contains(err.Error(), "1 split line") {
+		t.Errorf("expected the split line to block the delete, got %v", err)
+	}
+
+	if err := db.DeleteTransaction(id); err != nil {
+		t.Fatalf("DeleteTransaction failed: %v", err)
+	}
+	if left, _ := db.transactionSplits(""); len(left) != 0 {
+		t.Errorf("expected the splits deleted with the transaction, got %v", left)
+	}
+}
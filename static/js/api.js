@@ -87,6 +87,22 @@ export async function applyRuleSingle(id) {
   return res.json();
 }
 
+export async function fetchRuleSuggestions() {
+  const res = await fetch('/rules/suggestions');
+  if (!res.ok) throw new Error('Failed to fetch rule suggestions');
+  return res.json();
+}
+
+export async function acceptRuleSuggestion(keyword, category) {
+  const res = await fetch('/rules/suggestions', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ keyword, category }),
+  });
+  if (!res.ok) throw new Error('Failed to accept suggestion');
+  return res.json();
+}
+
 export async function applyAllRules() {
   const res = await fetch('/rules/apply-all', { method: 'POST' });
   if (!res.ok) throw new Error('Failed to apply all rules');
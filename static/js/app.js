@@ -8,7 +8,7 @@ import {
   nowLocalInput,
 } from './utils.js';
 
-import { fetchDashboard, createTransaction, updateTransaction, removeTransaction, parseTransaction, fetchRules, createRule, updateRule, deleteRule, applyRuleSingle, applyAllRules, moveRulePriority, createCategory, updateCategory, deleteCategory, setCategoryTarget, removeCategoryTarget, setFunding, setSalary } from './api.js';
+import { fetchDashboard, createTransaction, updateTransaction, removeTransaction, parseTransaction, fetchRules, createRule, updateRule, deleteRule, applyRuleSingle, applyAllRules, fetchRuleSuggestions, acceptRuleSuggestion, moveRulePriority, createCategory, updateCategory, deleteCategory, setCategoryTarget, removeCategoryTarget, setFunding, setSalary } from './api.js';
 import { computeTodaySpend, computeBiggestExpense, computeDailyAverage, computeTopCategory } from './tabs/dashboard.js';
 import { computeSearchedAndSorted, computeGroupedByDate } from './tabs/transactions.js';
 
@@ -47,7 +47,7 @@ export default function app() {
     editOpen: false,
     editId: null,
     editOld: null,
-    editForm: { description: '', amount: 0, date: '', category: 'Groceries' },
+    editForm: { description: '', amount: 0, date: '', category: 'Groceries', excluded: false },
 
     // Delete modal
     deleteOpen: false,
@@ -67,6 +67,7 @@ export default function app() {
     // Rules tab
     rulesLoading: false,
     rules: [],
+    ruleSuggestions: [],
 
     // Edit modal multi-step
     editStep: 'edit', // 'edit' | 'confirm_rule' | 'confirm_retroactive'
@@ -77,12 +78,12 @@ export default function app() {
 
     // Rule add modal
     ruleAddOpen: false,
-    ruleAddForm: { keyword: '', category: 'Groceries', priority: 0 },
+    ruleAddForm: { keyword: '', matchType: 'contains', category: 'Groceries', priority: 0 },
 
     // Rule edit modal
     ruleEditOpen: false,
     ruleEditId: null,
-    ruleEditForm: { keyword: '', category: 'Groceries', priority: 0 },
+    ruleEditForm: { keyword: '', matchType: 'contains', category: 'Groceries', priority: 0 },
 
     // Rule delete confirm
     ruleDeleteOpen: false,
@@ -149,7 +150,7 @@ export default function app() {
         this.categories = data.categories || [];
         this.allTransactions = data.allTransactions || [];
         this.categoryDefinitions = data.categoryDefinitions || [];
-        this.categoryOptions = this.categoryDefinitions.map(c => c.name);
+        this.categoryOptions = this.categoryDefinitions.filter(c => !c.archived).map(c => c.name);
         this.availableCycles = data.availableCycles || [];
         // Default the picker to the current period on first load.
         if (!this.selectedCycle) this.selectedCycle = data.cycle;
@@ -212,7 +213,7 @@ export default function app() {
       const fundedIds = this.stats?.fundedCategoryIds || [];
       const order = { fixed: 0, goal: 1 };
       return this.categoryDefinitions
-        .filter(def => def.tracking === 'allocated')
+        .filter(def => def.tracking === 'allocated' && (!def.archived || fundedIds.includes(def.id)))
         .map(def => ({ ...def, emoji: def.emoji || '📌', funded: fundedIds.includes(def.id) }))
         .sort((a, b) => (order[a.type] ?? 9) - (order[b.type] ?? 9));
     },
@@ -251,13 +252,15 @@ export default function app() {
     get fixedCategories() {
       return this.categoryDefinitions
         .filter(def => def.type === 'fixed')
-        .map(def => this._mergeWithSpending(def));
+        .map(def => this._mergeWithSpending(def))
+        .filter(cat => !cat.archived || cat.count > 0);
     },
 
     get wantsCategories() {
       return this.categoryDefinitions
         .filter(def => def.type === 'wants')
-        .map(def => this._mergeWithSpending(def));
+        .map(def => this._mergeWithSpending(def))
+        .filter(cat => !cat.archived || cat.count > 0);
     },
 
     get otherCategories() {
@@ -456,6 +459,7 @@ export default function app() {
         amount: tx.amount,
         date: dateToInputValue(tx.date),
         category: tx.category,
+        excluded: !!tx.excluded,
       };
       this.editOpen = true;
       this.editStep = 'edit';
@@ -481,6 +485,7 @@ export default function app() {
         amount: parseFloat(this.editForm.amount),
         date: inputValueToDate(this.editForm.date),
         category: this.editForm.category,
+        excluded: this.editForm.excluded,
       };
       try {
         await updateTransaction(this.editId, payload);
@@ -624,11 +629,28 @@ export default function app() {
       } finally {
         this.rulesLoading = false;
       }
+      try {
+        const data = await fetchRuleSuggestions();
+        this.ruleSuggestions = data.suggestions || [];
+      } catch (e) {
+        this.ruleSuggestions = [];
+      }
+    },
+
+    async acceptSuggestion(suggestion) {
+      try {
+        await acceptRuleSuggestion(suggestion.keyword, suggestion.category);
+        hapticFeedback('success');
+        this.showToast('Rule created');
+        await this.loadRules();
+      } catch (e) {
+        this.showToast('Error: ' + e.message);
+      }
     },
 
     // Rule add modal
     openRuleAdd() {
-      this.ruleAddForm = { keyword: '', category: 'Groceries', priority: 0 };
+      this.ruleAddForm = { keyword: '', matchType: 'contains', category: 'Groceries', priority: 0 };
       this.ruleAddOpen = true;
       hapticFeedback('light');
     },
@@ -643,6 +665,7 @@ export default function app() {
       try {
         const result = await createRule({
           keyword,
+          matchType: this.ruleAddForm.matchType,
           category: this.ruleAddForm.category,
           priority: parseInt(this.ruleAddForm.priority) || 0,
         });
@@ -659,7 +682,7 @@ export default function app() {
     // Rule edit modal
     openRuleEdit(rule) {
       this.ruleEditId = rule.id;
-      this.ruleEditForm = { keyword: rule.keyword, category: rule.category, priority: rule.priority };
+      this.ruleEditForm = { keyword: rule.keyword, matchType: rule.matchType || 'contains', category: rule.category, priority: rule.priority };
       this.ruleEditOpen = true;
       hapticFeedback('light');
     },
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tag is a free-form label that cuts across categories ("Japan trip",
+// "work reimbursable"). A transaction can carry any number of tags, and a
+// merchant rule can add tags to the transactions it categorizes at ingest.
+// Names are unique regardless of case.
+type Tag struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Count     int    `json:"count"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// TagCycleTotal is a tag's spend in one billing cycle.
+type TagCycleTotal struct {
+	Cycle string  `json:"cycle"`
+	Total float64 `json:"total"`
+	Count int     `json:"count"`
+}
+
+// TagTotals is a tag's spend per cycle; Cycles line up with
+// TagReport.Cycles.
+type TagTotals struct {
+	TagID  int64           `json:"tagId"`
+	Tag    string          `json:"tag"`
+	Cycles []TagCycleTotal `json:"cycles"`
+	Total  float64         `json:"total"`
+	Count  int             `json:"count"`
+}
+
+type TagReport struct {
+	Cycles []string    `json:"cycles"`
+	Tags   []TagTotals `json:"tags"`
+}
+
+func (c *DatabaseClient) migrateTags() error {
+	migrations := []string{
+		`CREATE TABLE IF NOT EXISTS tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS transaction_tags (
+			transaction_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (transaction_id, tag_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_transaction_tags_tag ON transaction_tags(tag_id)`,
+		`CREATE TABLE IF NOT EXISTS rule_tags (
+			rule_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (rule_id, tag_id)
+		)`,
+	}
+	for _, migration := range migrations {
+		if _, err := c.db.Exec(migration); err != nil {
+			return fmt.Errorf("tags migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// cleanTagNames trims names and drops blanks and case-insensitive
+// duplicates, keeping the first spelling.
+func cleanTagNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	clean := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		clean = append(clean, name)
+	}
+	return clean
+}
+
+// parseTagList splits the Tags column of an import file ("Japan trip; kid").
+func parseTagList(s string) []string {
+	return cleanTagNames(strings.Split(s, ";"))
+}
+
+func (c *DatabaseClient) GetTags() ([]Tag, error) {
+	rows, err := c.db.Query(`
+		SELECT t.id, t.name, t.created_at, COUNT(tt.transaction_id)
+		FROM tags t LEFT JOIN transaction_tags tt ON tt.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name COLLATE NOCASE`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.CreatedAt, &tag.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (c *DatabaseClient) CreateTag(name string) (*Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("tag name is required")
+	}
+	now := time.Now().Format(time.RFC3339)
+	result, err := c.db.Exec("INSERT INTO tags (name, created_at) VALUES (?, ?)", name, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			return nil, fmt.Errorf("tag already exists")
+		}
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return &Tag{ID: id, Name: name, CreatedAt: now}, nil
+}
+
+// RenameTag renames a tag everywhere it is used; transactions and rules
+// link to it by ID.
+func (c *DatabaseClient) RenameTag(id int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("tag name is required")
+	}
+	result, err := c.db.Exec("UPDATE tags SET name = ? WHERE id = ?", name, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			return fmt.Errorf("tag already exists")
+		}
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("tag not found")
+	}
+	return nil
+}
+
+// DeleteTag removes a tag from every transaction and rule.
+func (c *DatabaseClient) DeleteTag(id int64) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM tags WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("tag not found")
+	}
+	for _, table := range []string{"transaction_tags", "rule_tags"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE tag_id = ?", id); err != nil {
+			return fmt.Errorf("failed to unlink tag: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// tagIDs returns the IDs of the named tags, creating any that don't exist.
+func tagIDs(tx *sql.Tx, names []string) ([]int64, error) {
+	now := time.Now().Format(time.RFC3339)
+	ids := make([]int64, 0, len(names))
+	for _, name := range cleanTagNames(names) {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name, created_at) VALUES (?, ?)", name, now); err != nil {
+			return nil, fmt.Errorf("failed to create tag %s: %w", name, err)
+		}
+		var id int64
+		if err := tx.QueryRow("SELECT id FROM tags WHERE name = ?", name).Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to look up tag %s: %w", name, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// setLinkedTags replaces the tags linked to owner in a link table
+// (transaction_tags or rule_tags, keyed by column).
+func (c *DatabaseClient) setLinkedTags(table, column string, owner int64, names []string) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setLinkedTagsTx(tx, table, column, owner, names); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setLinkedTagsTx is setLinkedTags inside an existing SQL transaction.
+func setLinkedTagsTx(tx *sql.Tx, table, column string, owner int64, names []string) error {
+	ids, err := tagIDs(tx, names)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM "+table+" WHERE "+column+" = ?", owner); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec("INSERT INTO "+table+" ("+column+", tag_id) VALUES (?, ?)", owner, id); err != nil {
+			return fmt.Errorf("failed to add tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetTransactionTags replaces a transaction's tags, creating new tags as
+// needed. An empty list clears them.
+func (c *DatabaseClient) SetTransactionTags(id int64, names []string) error {
+	var exists bool
+	if err := c.db.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions WHERE id = ?)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("transaction not found")
+	}
+	return c.setLinkedTags("transaction_tags", "transaction_id", id, names)
+}
+
+// SetRuleTags replaces the tags a rule adds to the transactions it matches.
+func (c *DatabaseClient) SetRuleTags(id int64, names []string) error {
+	return c.setLinkedTags("rule_tags", "rule_id", id, names)
+}
+
+// linkedTags maps each owner in a link table to its tag names, sorted.
+// where filters the link rows (aliased l); args are its parameters.
+func (c *DatabaseClient) linkedTags(table, column, where string, args ...interface{}) (map[int64][]string, error) {
+	query := "SELECT l." + column + ", t.name FROM " + table + " l JOIN tags t ON t.id = l.tag_id"
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := c.db.Query(query+" ORDER BY t.name COLLATE NOCASE", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var owner int64
+		var name string
+		if err := rows.Scan(&owner, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags[owner] = append(tags[owner], name)
+	}
+	return tags, rows.Err()
+}
+
+// transactionTags maps transaction IDs to their tags, for one billing cycle
+// or, with cycle "", for every transaction.
+func (c *DatabaseClient) transactionTags(cycle string) (map[int64][]string, error) {
+	if cycle == "" {
+		return c.linkedTags("transaction_tags", "transaction_id", "")
+	}
+	return c.linkedTags("transaction_tags", "transaction_id",
+		"l.transaction_id IN (SELECT id FROM transactions WHERE billing_cycle = ?)", cycle)
+}
+
+func (c *DatabaseClient) ruleTags() (map[int64][]string, error) {
+	return c.linkedTags("rule_tags", "rule_id", "")
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func attachTags(txs []Transaction, tags map[int64][]string) {
+	for i := range txs {
+		txs[i].Tags = tags[txs[i].ID]
+	}
+}
+
+// tagFilter narrows a transactions query to rows carrying tag; it is empty
+// when tag is "". Append it to a WHERE clause and its args to the query's.
+func tagFilter(tag string) (string, []interface{}) {
+	if tag == "" {
+		return "", nil
+	}
+	return ` AND id IN (
+		SELECT tt.transaction_id FROM transaction_tags tt JOIN tags t ON t.id = tt.tag_id
+		WHERE t.name = ?)`, []interface{}{tag}
+}
+
+// GetTagTotals sums each tag's spend per billing cycle from `from` through
+// `to`, skipping categories excluded from totals. With no range it covers
+// every cycle that has tagged spend. Tags with no spend in range are left
+// out.
+func (c *DatabaseClient) GetTagTotals(from, to string) (*TagReport, error) {
+	var inRange map[string]bool
+	if from != "" || to != "" {
+		if to == "" {
+			to = calculateBillingCycle(time.Now().Format("2006-01-02"))
+		}
+		if from == "" {
+			from = to
+		}
+		cycles := cyclesBetween(from, to)
+		if len(cycles) == 0 {
+			return nil, fmt.Errorf("invalid cycle range")
+		}
+		inRange = make(map[string]bool, len(cycles))
+		for _, cycle := range cycles {
+			inRange[cycle] = true
+		}
+	}
+
+	rows, err := c.db.Query(`
+		SELECT t.id, t.name, x.billing_cycle, SUM(x.amount), COUNT(DISTINCT x.id)
+		FROM transaction_tags tt
+		JOIN tags t ON t.id = tt.tag_id
+		JOIN spend_lines x ON x.id = tt.transaction_id
+		WHERE x.category NOT IN (SELECT name FROM categories WHERE exclude_from_totals = 1)
+		GROUP BY t.id, x.billing_cycle`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag totals: %w", err)
+	}
+	defer rows.Close()
+
+	byTag := make(map[int64]*TagTotals)
+	cycleSet := make(map[string]bool)
+	for rows.Next() {
+		var id int64
+		var name string
+		var ct TagCycleTotal
+		if err := rows.Scan(&id, &name, &ct.Cycle, &ct.Total, &ct.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag total: %w", err)
+		}
+		if inRange != nil && !inRange[ct.Cycle] {
+			continue
+		}
+		if byTag[id] == nil {
+			byTag[id] = &TagTotals{TagID: id, Tag: name}
+		}
+		byTag[id].Cycles = append(byTag[id].Cycles, ct)
+		cycleSet[ct.Cycle] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag totals: %w", err)
+	}
+
+	report := &TagReport{Cycles: []string{}, Tags: []TagTotals{}}
+	for cycle := range cycleSet {
+		report.Cycles = append(report.Cycles, cycle)
+	}
+	sort.Slice(report.Cycles, func(i, j int) bool {
+		return cycleSortKey(report.Cycles[i]) < cycleSortKey(report.Cycles[j])
+	})
+	index := make(map[string]int, len(report.Cycles))
+	for i, cycle := range report.Cycles {
+		index[cycle] = i
+	}
+	for _, t := range byTag {
+		cells := make([]TagCycleTotal, len(report.Cycles))
+		for i, cycle := range report.Cycles {
+			cells[i].Cycle = cycle
+		}
+		for _, ct := range t.Cycles {
+			cells[index[ct.Cycle]] = ct
+			t.Total += ct.Total
+			t.Count += ct.Count
+		}
+		t.Cycles = cells
+		report.Tags = append(report.Tags, *t)
+	}
+	sort.Slice(report.Tags, func(i, j int) bool {
+		return report.Tags[i].Total > report.Tags[j].Total
+	})
+	return report, nil
+}
+
+// tagsHandler serves /tags and /tags/:
+//
+//	GET    /tags               — all tags with how many transactions carry each
+//	POST   /tags               — create a tag ({"name"})
+//	GET    /tags/totals        — per-tag spend per cycle (?from=&to=, default all)
+//	PUT    /tags/{id}          — rename ({"name"})
+//	DELETE /tags/{id}          — delete, untagging its transactions and rules
+func tagsHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/tags"), "/")
+
+		if rest == "totals" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+			log.Printf("[API] GET /tags/totals - %q to %q from %s", from, to, r.RemoteAddr)
+			report, err := db.GetTagTotals(from, to)
+			if err != nil {
+				log.Printf("[API] Failed to get tag totals: %v", err)
+				if err.Error() == "invalid cycle range" {
+					http.Error(w, "Invalid cycle range", http.StatusBadRequest)
+				} else {
+					http.Error(w, "Failed to retrieve tag totals", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"report":  report,
+			})
+			return
+		}
+
+		if rest == "" {
+			switch r.Method {
+			case http.MethodGet:
+				log.Printf("[API] GET /tags - Request from %s", r.RemoteAddr)
+				tags, err := db.GetTags()
+				if err != nil {
+					log.Printf("[API] Failed to get tags: %v", err)
+					http.Error(w, "Failed to retrieve tags", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"tags":    tags,
+				})
+
+			case http.MethodPost:
+				log.Printf("[API] POST /tags - Create tag from %s", r.RemoteAddr)
+				var req struct {
+					Name string `json:"name"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+				tag, err := db.CreateTag(req.Name)
+				if err != nil {
+					log.Printf("[API] Failed to create tag: %v", err)
+					switch err.Error() {
+					case "tag name is required":
+						http.Error(w, "Name is required", http.StatusBadRequest)
+					case "tag already exists":
+						http.Error(w, "Tag already exists", http.StatusConflict)
+					default:
+						http.Error(w, "Failed to create tag", http.StatusInternalServerError)
+					}
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"tag":     tag,
+				})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		var id int64
+		if _, err := fmt.Sscanf(rest, "%d", &id); err != nil {
+			http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			log.Printf("[API] PUT /tags/%d - Rename from %s", id, r.RemoteAddr)
+			var req struct {
+				Name string `json:"name"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := db.RenameTag(id, req.Name); err != nil {
+				log.Printf("[API] Failed to rename tag: %v", err)
+				switch err.Error() {
+				case "tag not found":
+					http.Error(w, "Tag not found", http.StatusNotFound)
+				case "tag name is required":
+					http.Error(w, "Name is required", http.StatusBadRequest)
+				case "tag already exists":
+					http.Error(w, "Tag already exists", http.StatusConflict)
+				default:
+					http.Error(w, "Failed to rename tag", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		case http.MethodDelete:
+			log.Printf("[API] DELETE /tags/%d - Delete from %s", id, r.RemoteAddr)
+			if err := db.DeleteTag(id); err != nil {
+				log.Printf("[API] Failed to delete tag: %v", err)
+				if err.Error() == "tag not found" {
+					http.Error(w, "Tag not found", http.StatusNotFound)
+				} else {
+					http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func seedTaggedTransactions(t *testing.T, db *DatabaseClient) {
+	t.Helper()
+	insertTestTransaction(t, db, Transaction{Description: "Narita Express", Amount: 300, Date: "2026-01-25", Category: "Transport", BillingCycle: "Jan 2026", Timestamp: "2026-01-25T10:00:00Z", Source: "manual", Tags: []string{"Japan trip"}})
+	insertTestTransaction(t, db, Transaction{Description: "Ichiran", Amount: 120, Date: "2026-01-26", Category: "Dining Out & Delivery", BillingCycle: "Jan 2026", Timestamp: "2026-01-26T10:00:00Z", Source: "manual", Tags: []string{"Japan trip", "  japan TRIP ", "food"}})
+	insertTestTransaction(t, db, Transaction{Description: "Careem", Amount: 45, Date: "2026-01-27", Category: "Transport", BillingCycle: "Jan 2026", Timestamp: "2026-01-27T10:00:00Z", Source: "manual"})
+	insertTestTransaction(t, db, Transaction{Description: "Tokyo hotel refund", Amount: 80, Date: "2026-02-24", Category: "Shopping & Gifts", BillingCycle: "Feb 2026", Timestamp: "2026-02-24T10:00:00Z", Source: "manual", Tags: []string{"Japan trip"}})
+}
+
+func TestTags_CRUD(t *testing.T) {
+	db := setupTestDB(t)
+	seedTaggedTransactions(t, db)
+
+	tags, err := db.GetTags()
+	if err != nil {
+		t.Fatalf("GetTags failed: %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "food" || tags[1].Name != "Japan trip" || tags[1].Count != 3 {
+		t.Fatalf("expected food and Japan trip (3 uses), got %+v", tags)
+	}
+
+	if _, err := db.CreateTag("japan trip"); err == nil || err.Error() != "tag already exists" {
+		t.Errorf("expected a case-insensitive duplicate to be refused, got %v", err)
+	}
+	if err := db.RenameTag(tags[1].ID, "Japan 2026"); err != nil {
+		t.Fatalf("RenameTag failed: %v", err)
+	}
+	txs, _ := db.GetAllTransactionsGroupedByCycle()
+	for _, tx := range txs {
+		if tx.Description == "Narita Express" && (len(tx.Tags) != 1 || tx.Tags[0] != "Japan 2026") {
+			t.Errorf("expected the rename to show on transactions, got %v", tx.Tags)
+		}
+	}
+
+	if err := db.DeleteTag(tags[0].ID); err != nil {
+		t.Fatalf("DeleteTag failed: %v", err)
+	}
+	if err := db.DeleteTag(tags[0].ID); err == nil || err.Error() != "tag not found" {
+		t.Errorf("expected tag not found, got %v", err)
+	}
+	txs, _ = db.GetAllTransactionsGroupedByCycle()
+	for _, tx := range txs {
+		if tx.Description == "Ichiran" && len(tx.Tags) != 1 {
+			t.Errorf("expected Ichiran to lose the deleted tag, got %v", tx.Tags)
+		}
+	}
+}
+
+func TestTags_RuleAddsTags(t *testing.T) {
+	db := setupTestDB(t)
+	rule, _, _, err := db.CreateRule("expensify", MatchContains, "Shopping & Gifts", 100)
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if err := db.SetRuleTags(rule.ID, []string{"work reimbursable"}); err != nil {
+		t.Fatalf("SetRuleTags failed: %v", err)
+	}
+	match, err := db.FindMatchingRule("EXPENSIFY*CLIENT DINNER")
+	if err != nil || match == nil {
+		t.Fatalf("expected the rule to match, got %v, %v", match, err)
+	}
+	if len(match.Tags) != 1 || match.Tags[0] != "work reimbursable" {
+		t.Errorf("expected the rule's tags on the match, got %v", match.Tags)
+	}
+	if err := db.DeleteRule(rule.ID); err != nil {
+		t.Fatalf("DeleteRule failed: %v", err)
+	}
+	rules, _ := db.ruleTags()
+	if len(rules[rule.ID]) != 0 {
+		t.Error("expected deleting the rule to drop its tags")
+	}
+}
+
+func TestTags_StatsAndExportFilter(t *testing.T) {
+	db := setupTestDB(t)
+	seedTaggedTransactions(t, db)
+
+	stats, err := db.GetStatsForTag("Jan 2026", "japan trip")
+	if err != nil {
+		t.Fatalf("GetStatsForTag failed: %v", err)
+	}
+	if stats.Total != 420 || stats.Count != 2 || len(stats.AllTransactions) != 2 {
+		t.Errorf("expected only the two tagged Jan transactions, got total %v count %d", stats.Total, stats.Count)
+	}
+	if transport := statsRow(t, stats.Categories, "Transport"); transport.Total != 300 {
+		t.Errorf("expected Careem left out of Transport, got %v", transport.Total)
+	}
+	if stats.WantsTotal != 420 {
+		t.Errorf("expected wants spend from tagged rows only, got %v", stats.WantsTotal)
+	}
+	if all, _ := db.GetStats("Jan 2026"); all.Total != 465 {
+		t.Errorf("expected the unfiltered total to be 465, got %v", all.Total)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/export?tag=Japan%20trip", nil)
+	w := httptest.NewRecorder()
+	exportHandler(db)(w, req)
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if records[0][4] != "Tags" {
+		t.Errorf("expected a Tags column, got %v", records[0])
+	}
+	var rows int
+	for _, record := range records {
+		if record[0] != "" && !strings.HasPrefix(record[0], "---") && record[0] != "Date" {
+			rows++
+			if record[1] == "Ichiran" && record[4] != "food; Japan trip" {
+				t.Errorf("expected Ichiran's tags joined, got %q", record[4])
+			}
+		}
+	}
+	if rows != 3 {
+		t.Errorf("expected 3 tagged rows in the export, got %d", rows)
+	}
+}
+
+func TestTags_ImportColumn(t *testing.T) {
+	db := setupTestDB(t)
+	body, contentType := createMultipartCSV(t, "Date,Description,Amount (AED),Category,Tags\n"+
+		"2026-02-10,Florist,400.00,Shopping & Gifts,wedding; gifts\n"+
+		"2026-02-11,Uber Ride,35.50,Transport,\n")
+	req := httptest.NewRequest(http.MethodPost, "/import", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	importHandler(db)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	txs, _ := db.GetAllTransactionsGroupedByCycle()
+	for _, tx := range txs {
+		switch tx.Description {
+		case "Florist":
+			if len(tx.Tags) != 2 || tx.Tags[0] != "gifts" || tx.Tags[1] != "wedding" {
+				t.Errorf("expected Florist tagged gifts and wedding, got %v", tx.Tags)
+			}
+		case "Uber Ride":
+			if len(tx.Tags) != 0 {
+				t.Errorf("expected no tags on Uber Ride, got %v", tx.Tags)
+			}
+		}
+	}
+}
+
+func TestTags_Totals(t *testing.T) {
+	db := setupTestDB(t)
+	seedTaggedTransactions(t, db)
+
+	report, err := db.GetTagTotals("", "")
+	if err != nil {
+		t.Fatalf("GetTagTotals failed: %v", err)
+	}
+	if len(report.Cycles) != 2 || report.Cycles[0] != "Jan 2026" || report.Cycles[1] != "Feb 2026" {
+		t.Fatalf("unexpected cycles: %v", report.Cycles)
+	}
+	japan := report.Tags[0]
+	if japan.Tag != "Japan trip" || japan.Total != 500 || japan.Count != 3 {
+		t.Errorf("unexpected Japan trip totals: %+v", japan)
+	}
+	if japan.Cycles[0].Total != 420 || japan.Cycles[1].Total != 80 {
+		t.Errorf("unexpected per-cycle totals: %+v", japan.Cycles)
+	}
+	food := report.Tags[1]
+	if food.Cycles[1].Cycle != "Feb 2026" || food.Cycles[1].Total != 0 {
+		t.Errorf("expected an empty Feb cell for food, got %+v", food.Cycles)
+	}
+
+	report, err = db.GetTagTotals("Feb 2026", "Feb 2026")
+	if err != nil {
+		t.Fatalf("GetTagTotals failed: %v", err)
+	}
+	if len(report.Tags) != 1 || report.Tags[0].Total != 80 {
+		t.Errorf("expected only Japan trip in Feb, got %+v", report.Tags)
+	}
+	if _, err := db.GetTagTotals("Mar 2026", "Jan 2026"); err == nil {
+		t.Error("expected an invalid range error")
+	}
+}
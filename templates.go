@@ -0,0 +1,592 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const budgetTemplateVersion = 1
+
+// BudgetTemplate is a whole budget setup as a portable JSON or YAML
+// document: every category with its bucket, tracking mode and budget, plus
+// the monthly salary. Applying one only adds and updates categories;
+// categories it doesn't list are left alone.
+type BudgetTemplate struct {
+	Version    int                `json:"version" yaml:"version"`
+	Salary     *float64           `json:"salary,omitempty" yaml:"salary,omitempty"`
+	Categories []TemplateCategory `json:"categories" yaml:"categories"`
+}
+
+// TemplateCategory is one budget line. Type is "fixed", "wants", "goal" or
+// "other"; Tracking defaults to "actual". An empty Emoji keeps an existing
+// category's emoji. Parent names the category's parent when it is a
+// sub-category; it must then share the parent's type and tracking.
+type TemplateCategory struct {
+	Name              string   `json:"name" yaml:"name"`
+	Parent            string   `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Emoji             string   `json:"emoji,omitempty" yaml:"emoji,omitempty"`
+	Type              string   `json:"type" yaml:"type"`
+	Tracking          string   `json:"tracking,omitempty" yaml:"tracking,omitempty"`
+	Budget            *float64 `json:"budget,omitempty" yaml:"budget,omitempty"`
+	ExcludeFromTotals bool     `json:"excludeFromTotals,omitempty" yaml:"excludeFromTotals,omitempty"`
+}
+
+// defaultBudgetTemplate seeds a fresh install: the usual buckets with no
+// amounts or salary, to be filled in (or replaced by applying a template).
+var defaultBudgetTemplate = BudgetTemplate{
+	Version: budgetTemplateVersion,
+	Categories: []TemplateCategory{
+		{Name: "Rent", Emoji: "🏠", Type: "fixed", Tracking: "allocated"},
+		{Name: "Bills & Utilities", Emoji: "💳", Type: "fixed", Tracking: "actual"},
+		{Name: "Subscriptions", Emoji: "📱", Type: "fixed", Tracking: "actual"},
+		{Name: "Groceries", Emoji: "🛒", Type: "wants", Tracking: "actual"},
+		{Name: "Dining Out & Delivery", Emoji: "🍔", Type: "wants", Tracking: "actual"},
+		{Name: "Transport", Emoji: "🚗", Type: "wants", Tracking: "actual"},
+		{Name: "Shopping & Gifts", Emoji: "🛍️", Type: "wants", Tracking: "actual"},
+		{Name: "Healthcare", Emoji: "💊", Type: "wants", Tracking: "actual"},
+		{Name: "Entertainment & Going Out", Emoji: "🎬", Type: "wants", Tracking: "actual"},
+		{Name: "Savings", Emoji: "🏦", Type: "goal", Tracking: "allocated"},
+		{Name: "Emergency fund", Emoji: "🆘", Type: "goal", Tracking: "allocated"},
+	},
+}
+
+// FieldChange is one field a template changes: From is the current value,
+// To the template's.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// TemplateChange is what applying a template does to one category. Action
+// is "create", "update", "unchanged" or "unlisted" (in the database but not
+// the template; left alone). Conflict explains why an update would break
+// something attached to the category.
+type TemplateChange struct {
+	Category string        `json:"category"`
+	Action   string        `json:"action"`
+	Fields   []FieldChange `json:"fields,omitempty"`
+	Conflict string        `json:"conflict,omitempty"`
+}
+
+// TemplatePreview is the diff between a template and the current setup.
+type TemplatePreview struct {
+	Changes   []TemplateChange `json:"changes"`
+	Salary    *FieldChange     `json:"salary,omitempty"`
+	Conflicts int              `json:"conflicts"`
+	Applied   bool             `json:"applied"`
+}
+
+// ParseBudgetTemplate decodes a template from JSON, or YAML when asYAML is
+// set, rejecting unknown fields so a typo doesn't silently drop a budget.
+func ParseBudgetTemplate(data []byte, asYAML bool) (*BudgetTemplate, error) {
+	var t BudgetTemplate
+	if asYAML {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&t); err != nil {
+			return nil, fmt.Errorf("invalid template: %v", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&t); err != nil {
+			return nil, fmt.Errorf("invalid template: %v", err)
+		}
+	}
+	if err := validateBudgetTemplate(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// loadBudgetTemplate reads a template file; .yaml/.yml files are YAML.
+func loadBudgetTemplate(path string) (*BudgetTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read budget template: %w", err)
+	}
+	return ParseBudgetTemplate(data, strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml"))
+}
+
+// validateBudgetTemplate checks a template and fills in defaults.
+func validateBudgetTemplate(t *BudgetTemplate) error {
+	if t.Version == 0 {
+		t.Version = budgetTemplateVersion
+	}
+	if t.Version != budgetTemplateVersion {
+		return fmt.Errorf("invalid template: unsupported version %d", t.Version)
+	}
+	if t.Salary != nil && *t.Salary <= 0 {
+		return fmt.Errorf("invalid template: salary must be greater than 0")
+	}
+	seen := make(map[string]bool, len(t.Categories))
+	for i := range t.Categories {
+		cat := &t.Categories[i]
+		cat.Name = strings.TrimSpace(cat.Name)
+		if cat.Name == "" {
+			return fmt.Errorf("invalid template: category %d has no name", i+1)
+		}
+		if seen[cat.Name] {
+			return fmt.Errorf("invalid template: duplicate category %q", cat.Name)
+		}
+		seen[cat.Name] = true
+		switch cat.Type {
+		case "fixed", "wants", "goal", "other":
+		default:
+			return fmt.Errorf("invalid template: %s has unknown type %q", cat.Name, cat.Type)
+		}
+		switch cat.Tracking {
+		case "":
+			cat.Tracking = "actual"
+		case "actual", "allocated":
+		default:
+			return fmt.Errorf("invalid template: %s has unknown tracking %q", cat.Name, cat.Tracking)
+		}
+		if cat.Budget != nil && *cat.Budget < 0 {
+			return fmt.Errorf("invalid template: %s has a negative budget", cat.Name)
+		}
+	}
+	// Parents listed in the template must be top-level and in the same
+	// bucket; parents that aren't listed are checked when applying.
+	byName := make(map[string]TemplateCategory, len(t.Categories))
+	for _, cat := range t.Categories {
+		byName[cat.Name] = cat
+	}
+	for _, cat := range t.Categories {
+		if cat.Parent == "" {
+			continue
+		}
+		if cat.Parent == cat.Name {
+			return fmt.Errorf("invalid template: %s is its own parent", cat.Name)
+		}
+		parent, ok := byName[cat.Parent]
+		if !ok {
+			continue
+		}
+		if parent.Parent != "" {
+			return fmt.Errorf("invalid template: %s's parent %s is itself a sub-category", cat.Name, parent.Name)
+		}
+		if cat.Type != parent.Type || cat.Tracking != parent.Tracking || cat.ExcludeFromTotals != parent.ExcludeFromTotals {
+			return fmt.Errorf("invalid template: %s must match its parent's type and tracking", cat.Name)
+		}
+	}
+	return nil
+}
+
+// categoryParentName returns the name of cat's parent, or "".
+func categoryParentName(cat Category, cats []Category) string {
+	if cat.ParentID == nil {
+		return ""
+	}
+	for _, other := range cats {
+		if other.ID == *cat.ParentID {
+			return other.Name
+		}
+	}
+	return ""
+}
+
+// ExportBudgetTemplate captures the current setup: every active category
+// with the budget in effect today, and the salary when one is set.
+func (c *DatabaseClient) ExportBudgetTemplate() (*BudgetTemplate, error) {
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	t := &BudgetTemplate{Version: budgetTemplateVersion, Categories: []TemplateCategory{}}
+	if salary := c.GetSalary(); salary > 0 {
+		t.Salary = floatPtr(salary)
+	}
+	for _, cat := range cats {
+		if cat.Archived {
+			continue
+		}
+		t.Categories = append(t.Categories, TemplateCategory{
+			Name:              cat.Name,
+			Parent:            categoryParentName(cat, cats),
+			Emoji:             cat.Emoji,
+			Type:              cat.Type,
+			Tracking:          cat.Tracking,
+			Budget:            cat.BudgetAmount,
+			ExcludeFromTotals: cat.ExcludeFromTotals,
+		})
+	}
+	return t, nil
+}
+
+func budgetValue(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// templateConflict reports why applying tc to cat would strand something
+// attached to the category, or "" when it wouldn't.
+func (c *DatabaseClient) templateConflict(cat Category, tc TemplateCategory) (string, error) {
+	if cat.Type == "goal" && tc.Type != "goal" {
+		goal, err := c.goalForCategory(cat.ID)
+		if err != nil {
+			return "", err
+		}
+		if goal != nil {
+			return "category has a savings goal", nil
+		}
+	}
+	if cat.Tracking == "allocated" && tc.Tracking != "allocated" {
+		fund, err := c.GetSinkingFund(cat.ID)
+		if err != nil {
+			return "", err
+		}
+		if fund != nil {
+			return "category is a sinking fund", nil
+		}
+		matchers, err := c.GetFundingMatchers()
+		if err != nil {
+			return "", err
+		}
+		for _, m := range matchers {
+			if m.CategoryID == cat.ID {
+				return "category has auto-fund matchers", nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// PreviewBudgetTemplate diffs a template against the current setup without
+// changing anything.
+func (c *DatabaseClient) PreviewBudgetTemplate(t *BudgetTemplate) (*TemplatePreview, error) {
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]Category, len(cats))
+	for _, cat := range cats {
+		existing[cat.Name] = cat
+	}
+
+	p := &TemplatePreview{Changes: []TemplateChange{}}
+	listed := make(map[string]bool, len(t.Categories))
+	for _, tc := range t.Categories {
+		listed[tc.Name] = true
+		cat, ok := existing[tc.Name]
+		if !ok {
+			p.Changes = append(p.Changes, TemplateChange{Category: tc.Name, Action: "create"})
+			continue
+		}
+
+		change := TemplateChange{Category: tc.Name, Action: "unchanged"}
+		if parent := categoryParentName(cat, cats); tc.Parent != parent {
+			change.Fields = append(change.Fields, FieldChange{"parent", parent, tc.Parent})
+		}
+		if tc.Emoji != "" && tc.Emoji != cat.Emoji {
+			change.Fields = append(change.Fields, FieldChange{"emoji", cat.Emoji, tc.Emoji})
+		}
+		if tc.Type != cat.Type {
+			change.Fields = append(change.Fields, FieldChange{"type", cat.Type, tc.Type})
+		}
+		if tc.Tracking != cat.Tracking {
+			change.Fields = append(change.Fields, FieldChange{"tracking", cat.Tracking, tc.Tracking})
+		}
+		if (tc.Budget == nil) != (cat.BudgetAmount == nil) ||
+			(tc.Budget != nil && *tc.Budget != *cat.BudgetAmount) {
+			change.Fields = append(change.Fields, FieldChange{"budget", budgetValue(cat.BudgetAmount), budgetValue(tc.Budget)})
+		}
+		if tc.ExcludeFromTotals != cat.ExcludeFromTotals {
+			change.Fields = append(change.Fields, FieldChange{"excludeFromTotals", cat.ExcludeFromTotals, tc.ExcludeFromTotals})
+		}
+		if len(change.Fields) > 0 {
+			change.Action = "update"
+			change.Conflict, err = c.templateConflict(cat, tc)
+			if err != nil {
+				return nil, err
+			}
+			if change.Conflict != "" {
+				p.Conflicts++
+			}
+		}
+		p.Changes = append(p.Changes, change)
+	}
+	for _, cat := range cats {
+		if !listed[cat.Name] && !cat.Archived {
+			p.Changes = append(p.Changes, TemplateChange{Category: cat.Name, Action: "unlisted"})
+		}
+	}
+
+	if t.Salary != nil {
+		if current := c.GetSalary(); current != *t.Salary {
+			p.Salary = &FieldChange{"salary", current, *t.Salary}
+		}
+	}
+	return p, nil
+}
+
+// detachForTemplate removes whatever templateConflict found attached to a
+// category, so the template's change can go through.
+func detachForTemplate(tx *sql.Tx, cat Category) error {
+	if _, err := tx.Exec("DELETE FROM savings_goals WHERE category_id = ?", cat.ID); err != nil {
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM sinking_funds WHERE category_id = ?", cat.ID); err != nil {
+		return fmt.Errorf("failed to delete sinking fund: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM funding_matchers WHERE category_id = ?", cat.ID); err != nil {
+		return fmt.Errorf("failed to delete funding matchers: %w", err)
+	}
+	return nil
+}
+
+// ApplyBudgetTemplate brings the setup in line with a template, in one SQL
+// transaction: a template that fails part-way changes nothing. Budget
+// changes apply from the current cycle forward, like any budget edit.
+// onConflict decides what happens to updates that would strand a goal,
+// sinking fund or auto-fund matcher: "" refuses the whole template, "skip"
+// leaves those categories as they are, "overwrite" removes what's attached
+// and applies the change. The returned preview is always filled in.
+func (c *DatabaseClient) ApplyBudgetTemplate(t *BudgetTemplate, onConflict string) (*TemplatePreview, error) {
+	if onConflict != "" && onConflict != "skip" && onConflict != "overwrite" {
+		return nil, fmt.Errorf("invalid conflict mode")
+	}
+	p, err := c.PreviewBudgetTemplate(t)
+	if err != nil {
+		return nil, err
+	}
+	if p.Conflicts > 0 && onConflict == "" {
+		return p, fmt.Errorf("template conflicts with %d categories", p.Conflicts)
+	}
+
+	// Everything is read up front: the transaction holds the only connection.
+	cats, err := c.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	salary, err := c.salarySource(current)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Create new categories first so they can be named as parents below.
+	for i, tc := range t.Categories {
+		if p.Changes[i].Action == "create" {
+			cat, err := createCategory(tx, tc.Name, tc.Emoji, tc.ExcludeFromTotals, tc.Type, tc.Budget, tc.Tracking)
+			if err != nil {
+				return nil, err
+			}
+			cats = append(cats, *cat)
+		}
+	}
+	existing := make(map[string]Category, len(cats))
+	for _, cat := range cats {
+		existing[cat.Name] = cat
+	}
+
+	for i, tc := range t.Categories {
+		change := p.Changes[i]
+		cat := existing[tc.Name]
+		if change.Action == "update" && change.Conflict != "" {
+			if onConflict == "skip" {
+				continue
+			}
+			if err := detachForTemplate(tx, cat); err != nil {
+				return nil, err
+			}
+		}
+		// Move the category before updating it, so that a category leaving
+		// its parent takes the template's type rather than the parent's.
+		if tc.Parent != categoryParentName(cat, cats) {
+			var parentID *int64
+			if tc.Parent != "" {
+				parent, ok := existing[tc.Parent]
+				if !ok {
+					return nil, fmt.Errorf("invalid template: unknown parent %q", tc.Parent)
+				}
+				parentID = &parent.ID
+			}
+			if err := setCategoryParent(tx, cat.ID, parentID); err != nil {
+				return nil, fmt.Errorf("invalid template: %s: %v", tc.Name, err)
+			}
+		}
+		if change.Action == "update" {
+			emoji := tc.Emoji
+			if emoji == "" {
+				emoji = cat.Emoji
+			}
+			if err := updateCategory(tx, cat.ID, cat.Name, emoji, tc.ExcludeFromTotals, tc.Type, tc.Budget, tc.Tracking); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if p.Salary != nil {
+		if err := setSalary(tx, salary, current, *t.Salary); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit template: %w", err)
+	}
+	p.Applied = true
+	return p, nil
+}
+
+// seedBudgetTemplate writes a template straight into categories and
+// settings for runBudgetingSetup. Unlike ApplyBudgetTemplate it records no
+// budget versions: seedBudgetVersions turns the amounts into base versions
+// so they hold for every cycle.
+func (c *DatabaseClient) seedBudgetTemplate(t *BudgetTemplate) error {
+	now := time.Now().Format(time.RFC3339)
+	for _, cat := range t.Categories {
+		excl := 0
+		if cat.ExcludeFromTotals {
+			excl = 1
+		}
+		if _, err := c.db.Exec(
+			"INSERT OR IGNORE INTO categories (name, emoji, exclude_from_totals, type, budget_amount, tracking, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			cat.Name, cat.Emoji, excl, cat.Type, cat.Budget, cat.Tracking, now,
+		); err != nil {
+			return fmt.Errorf("insert budgeting category %s: %w", cat.Name, err)
+		}
+		if _, err := c.db.Exec(
+			"UPDATE categories SET type=?, tracking=?, budget_amount=? WHERE name=?",
+			cat.Type, cat.Tracking, cat.Budget, cat.Name,
+		); err != nil {
+			return fmt.Errorf("update budgeting category %s: %w", cat.Name, err)
+		}
+	}
+	for _, cat := range t.Categories {
+		if cat.Parent == "" {
+			continue
+		}
+		if _, err := c.db.Exec(
+			"UPDATE categories SET parent_id = (SELECT id FROM categories WHERE name = ?) WHERE name = ?",
+			cat.Parent, cat.Name,
+		); err != nil {
+			return fmt.Errorf("set parent of budgeting category %s: %w", cat.Name, err)
+		}
+	}
+
+	if t.Salary != nil {
+		if _, err := c.db.Exec(
+			"INSERT OR IGNORE INTO settings (key, value) VALUES ('monthly_salary', ?)",
+			strconv.FormatFloat(*t.Salary, 'f', -1, 64),
+		); err != nil {
+			return fmt.Errorf("seed salary: %w", err)
+		}
+	}
+	return nil
+}
+
+// wantsYAML reports whether a request asked for YAML, via ?format=yaml or a
+// YAML Content-Type (for bodies) / Accept (for responses) header.
+func wantsYAML(r *http.Request, header string) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "yaml" || format == "yml"
+	}
+	return strings.Contains(r.Header.Get(header), "yaml")
+}
+
+// budgetTemplateHandler serves /budget/template:
+//
+//	GET  /budget/template                  — the current setup as a bare template document
+//	POST /budget/template?preview=true     — diff a template against the current setup
+//	POST /budget/template?onConflict=skip  — apply a template (onConflict: skip|overwrite)
+//
+// JSON by default; ?format=yaml or a YAML Content-Type/Accept header switches
+// to YAML. A conflicting apply without onConflict is refused with 409 and the
+// diff.
+func budgetTemplateHandler(db *DatabaseClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			log.Printf("[API] GET /budget/template - Export from %s", r.RemoteAddr)
+			t, err := db.ExportBudgetTemplate()
+			if err != nil {
+				log.Printf("[API] Failed to export budget template: %v", err)
+				http.Error(w, "Failed to export budget template", http.StatusInternalServerError)
+				return
+			}
+			if wantsYAML(r, "Accept") {
+				w.Header().Set("Content-Type", "application/yaml")
+				enc := yaml.NewEncoder(w)
+				enc.SetIndent(2)
+				enc.Encode(t)
+				enc.Close()
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(t)
+
+		case http.MethodPost:
+			preview := r.URL.Query().Get("preview") == "true"
+			onConflict := r.URL.Query().Get("onConflict")
+			log.Printf("[API] POST /budget/template - preview=%v onConflict=%q from %s", preview, onConflict, r.RemoteAddr)
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			t, err := ParseBudgetTemplate(body, wantsYAML(r, "Content-Type"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var p *TemplatePreview
+			if preview {
+				p, err = db.PreviewBudgetTemplate(t)
+			} else {
+				p, err = db.ApplyBudgetTemplate(t, onConflict)
+			}
+			if err != nil {
+				log.Printf("[API] Failed to apply budget template: %v", err)
+				switch {
+				case strings.HasPrefix(err.Error(), "template conflicts"):
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"success": false,
+						"message": err.Error(),
+						"preview": p,
+					})
+				case err.Error() == "invalid conflict mode":
+					http.Error(w, "onConflict must be skip or overwrite", http.StatusBadRequest)
+				case strings.HasPrefix(err.Error(), "invalid template"):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				default:
+					http.Error(w, "Failed to apply budget template", http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"preview": p,
+			})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBudgetTemplate_FreshInstallSeedsNeutralDefault(t *testing.T) {
+	t.Setenv("BUDGET_TEMPLATE", "")
+	db, err := NewDatabaseClient(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create test db: %v", err)
+	}
+	defer db.Close()
+
+	rent := categoryByName(t, db, "Rent")
+	if rent.Type != "fixed" || rent.Tracking != "allocated" || rent.BudgetAmount != nil {
+		t.Errorf("expected Rent seeded as fixed/allocated with no budget, got %+v", rent)
+	}
+	cats, _ := db.GetAllCategories()
+	for _, cat := range cats {
+		if cat.Name == "DEWA" || cat.Name == "Therapy" {
+			t.Errorf("expected no personal budget lines, found %s", cat.Name)
+		}
+	}
+	if salary := db.GetSalary(); salary != 0 {
+		t.Errorf("expected no salary, got %v", salary)
+	}
+}
+
+func TestBudgetTemplate_FreshInstallSeedsFromEnv(t *testing.T) {
+	t.Setenv("BUDGET_TEMPLATE", "testdata/household.yaml")
+	db, err := NewDatabaseClient(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create test db: %v", err)
+	}
+	defer db.Close()
+
+	if rent := categoryByName(t, db, "Rent"); rent.BudgetAmount == nil || *rent.BudgetAmount != 9000 {
+		t.Errorf("expected Rent seeded from the template at 9000, got %+v", rent)
+	}
+	if salary := db.GetSalary(); salary != 32500 {
+		t.Errorf("expected salary 32500, got %v", salary)
+	}
+}
+
+func TestBudgetTemplate_ExportRoundTrips(t *testing.T) {
+	db := setupTestDB(t)
+
+	exported, err := db.ExportBudgetTemplate()
+	if err != nil {
+		t.Fatalf("ExportBudgetTemplate failed: %v", err)
+	}
+	if exported.Salary == nil || *exported.Salary != 32500 {
+		t.Errorf("expected salary 32500 in export, got %v", exported.Salary)
+	}
+	data, _ := json.Marshal(exported)
+	parsed, err := ParseBudgetTemplate(data, false)
+	if err != nil {
+		t.Fatalf("ParseBudgetTemplate failed: %v", err)
+	}
+	preview, err := db.PreviewBudgetTemplate(parsed)
+	if err != nil {
+		t.Fatalf("PreviewBudgetTemplate failed: %v", err)
+	}
+	for _, c := range preview.Changes {
+		if c.Action != "unchanged" {
+			t.Errorf("expected %s unchanged after round trip, got %+v", c.Category, c)
+		}
+	}
+	if preview.Salary != nil {
+		t.Errorf("expected no salary change, got %+v", preview.Salary)
+	}
+}
+
+func TestBudgetTemplate_PreviewThenApply(t *testing.T) {
+	db := setupTestDB(t)
+
+	tmpl, err := ParseBudgetTemplate([]byte(`
+salary: 35000
+categories:
+  - {name: Groceries, type: wants, budget: 2500}
+  - {name: Pets, emoji: 🐾, type: wants, budget: 300}
+`), true)
+	if err != nil {
+		t.Fatalf("ParseBudgetTemplate failed: %v", err)
+	}
+
+	preview, err := db.PreviewBudgetTemplate(tmpl)
+	if err != nil {
+		t.Fatalf("PreviewBudgetTemplate failed: %v", err)
+	}
+	if preview.Changes[0].Action != "update" || len(preview.Changes[0].Fields) != 1 || preview.Changes[0].Fields[0].Field != "budget" {
+		t.Errorf("expected a Groceries budget update, got %+v", preview.Changes[0])
+	}
+	if preview.Changes[1].Action != "create" {
+		t.Errorf("expected Pets to be created, got %+v", preview.Changes[1])
+	}
+	if preview.Changes[len(preview.Changes)-1].Action != "unlisted" {
+		t.Errorf("expected categories missing from the template to be unlisted")
+	}
+	if preview.Salary == nil || preview.Salary.To != 35000.0 {
+		t.Errorf("expected salary change to 35000, got %+v", preview.Salary)
+	}
+	if groc := categoryByName(t, db, "Groceries"); *groc.BudgetAmount != 2000 {
+		t.Fatal("expected preview to leave the budget alone")
+	}
+
+	applied, err := db.ApplyBudgetTemplate(tmpl, "")
+	if err != nil {
+		t.Fatalf("ApplyBudgetTemplate failed: %v", err)
+	}
+	if !applied.Applied {
+		t.Error("expected the preview to be marked applied")
+	}
+
+	groc := categoryByName(t, db, "Groceries")
+	current := calculateBillingCycle(time.Now().Format("2006-01-02"))
+	if budget, _ := db.BudgetFor(groc.ID, current); budget == nil || *budget != 2500 {
+		t.Errorf("expected Groceries 2500 in the current cycle, got %v", budget)
+	}
+	if budget, _ := db.BudgetFor(groc.ID, "Jan 2026"); budget == nil || *budget != 2000 {
+		t.Errorf("expected Jan 2026 to keep 2000, got %v", budget)
+	}
+	if pets := categoryByName(t, db, "Pets"); pets.Emoji != "🐾" || *pets.BudgetAmount != 300 {
+		t.Errorf("unexpected Pets category: %+v", pets)
+	}
+	if db.GetSalary() != 35000 {
+		t.Errorf("expected salary 35000, got %v", db.GetSalary())
+	}
+	// Categories the template doesn't list are untouched.
+	if rent := categoryByName(t, db, "Rent"); *rent.BudgetAmount != 9000 {
+		t.Errorf("expected Rent untouched, got %v", *rent.BudgetAmount)
+	}
+}
+
+func TestBudgetTemplate_Conflicts(t *testing.T) {
+	db := setupTestDB(t)
+	savings := categoryByName(t, db, "Savings")
+	if _, err := db.CreateGoal(SavingsGoal{CategoryID: savings.ID, TargetAmount: 3000, TargetDate: "2027-06-30"}); err != nil {
+		t.Fatalf("CreateGoal failed: %v", err)
+	}
+	tmpl, err := ParseBudgetTemplate([]byte(`{"categories": [{"name": "Savings", "type": "wants", "budget": 2000}]}`), false)
+	if err != nil {
+		t.Fatalf("ParseBudgetTemplate failed: %v", err)
+	}
+
+	preview, err := db.ApplyBudgetTemplate(tmpl, "")
+	if err == nil || preview == nil || preview.Conflicts != 1 || preview.Changes[0].Conflict != "category has a savings goal" {
+		t.Fatalf("expected the goal to block the template, got %+v, %v", preview, err)
+	}
+
+	if _, err := db.ApplyBudgetTemplate(tmpl, "skip"); err != nil {
+		t.Fatalf("ApplyBudgetTemplate skip failed: %v", err)
+	}
+	if cat := categoryByName(t, db, "Savings"); cat.Type != "goal" {
+		t.Errorf("expected skip to leave Savings a goal, got %s", cat.Type)
+	}
+
+	if _, err := db.ApplyBudgetTemplate(tmpl, "overwrite"); err != nil {
+		t.Fatalf("ApplyBudgetTemplate overwrite failed: %v", err)
+	}
+	if cat := categoryByName(t, db, "Savings"); cat.Type != "wants" {
+		t.Errorf("expected overwrite to move Savings to wants, got %s", cat.Type)
+	}
+	if goal, _ := db.goalForCategory(savings.ID); goal != nil {
+		t.Error("expected overwrite to remove the goal")
+	}
+}
+
+func TestBudgetTemplate_FailedApplyChangesNothing(t *testing.T) {
+	db := setupTestDB(t)
+	salary := db.GetSalary()
+	tmpl, err := ParseBudgetTemplate([]byte(`
+salary: 35000
+categories:
+  - {name: Pets, type: wants, budget: 300}
+  - {name: Groceries, type: wants, budget: 2500, parent: Nowhere}
+`), true)
+	if err != nil {
+		t.Fatalf("ParseBudgetTemplate failed: %v", err)
+	}
+
+	if _, err := db.ApplyBudgetTemplate(tmpl, ""); err == nil || !strings.Contains(err.Error(), "unknown parent") {
+		t.Fatalf("expected an unknown parent error, got %v", err)
+	}
+	cats, _ := db.GetAllCategories()
+	for _, cat := range cats {
+		if cat.Name == "Pets" {
+			t.Errorf("expected Pets not created, found %+v", cat)
+		}
+	}
+	if groc := categoryByName(t, db, "Groceries"); *groc.BudgetAmount != 2000 {
+		t.Errorf("expected Groceries left at 2000, got %v", *groc.BudgetAmount)
+	}
+	if db.GetSalary() != salary {
+		t.Errorf("expected salary left at %v, got %v", salary, db.GetSalary())
+	}
+}
+
+func TestBudgetTemplate_Validation(t *testing.T) {
+	for name, doc := range map[string]string{
+		"unknown field": `{"categories": [{"name": "Rent", "type": "fixed", "budgetAmount": 10}]}`,
+		"unknown type":  `{"categories": [{"name": "Rent", "type": "needs"}]}`,
+		"duplicate":     `{"categories": [{"name": "Rent", "type": "fixed"}, {"name": "Rent", "type": "fixed"}]}`,
+		"bad version":   `{"version": 2, "categories": []}`,
+	} {
+		if _, err := ParseBudgetTemplate([]byte(doc), false); err == nil || !strings.HasPrefix(err.Error(), "invalid template") {
+			t.Errorf("%s: expected an invalid template error, got %v", name, err)
+		}
+	}
+}
+
+func TestBudgetTemplateHandler(t *testing.T) {
+	db := setupTestDB(t)
+	handler := budgetTemplateHandler(db)
+
+	req := httptest.NewRequest(http.MethodGet, "/budget/template?format=yaml", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "name: Rent") {
+		t.Fatalf("expected a YAML export, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := `{"categories": [{"name": "Groceries", "type": "wants", "budget": 1800}]}`
+	req = httptest.NewRequest(http.MethodPost, "/budget/template?preview=true", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"applied":false`) {
+		t.Fatalf("expected a preview, got %d: %s", w.Code, w.Body.String())
+	}
+	if groc := categoryByName(t, db, "Groceries"); *groc.BudgetAmount != 2000 {
+		t.Error("expected preview not to apply")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/budget/template", strings.NewReader(`{"categories": [{"name": "Rent"}]}`))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a category without type, got %d", w.Code)
+	}
+}